@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"webook/internal/job"
+	"webook/internal/service/sms/async"
+)
+
+// App 需要启动的所有组件
+type App struct {
+	server   *gin.Engine
+	purgeJob *job.PurgeUserJob
+	//异步短信的重试worker
+	asyncSMS *async.Service
+}
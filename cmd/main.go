@@ -1,7 +1,13 @@
 package main
 
+import "context"
+
 func main() {
-	server := InitWebServer()
+	app := InitApp()
+
+	//后台任务
+	go app.purgeJob.Start(context.Background())
+	go app.asyncSMS.Start(context.Background())
 
-	server.Run(":8186")
+	app.server.Run(":8186")
 }
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"webook/internal/service/sms/fake"
+)
+
+// 本地启动假的短信服务商 把SMS.Providers的Endpoint指向这里
+// 用 POST /_fake/behavior {"provider":"tencent","mode":"slow","delay_ms":3000} 切换服务商的表现
+func main() {
+	addr := flag.String("addr", ":8187", "监听地址")
+	flag.Parse()
+	log.Printf("假的短信服务商监听%s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, fake.NewServer()))
+}
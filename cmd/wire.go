@@ -3,8 +3,8 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
+	"webook/internal/job"
 	"webook/internal/repository"
 	"webook/internal/repository/cache"
 	"webook/internal/repository/dao"
@@ -15,31 +15,57 @@ import (
 	"webook/ioc"
 )
 
-func InitWebServer() *gin.Engine {
+func InitApp() *App {
 	wire.Build(
 		//基础组件
 		ioc.InitDB, ioc.InitRedis,
 		//cache dao
-		cache.NewCodeLocalCache, cache.NewUserRedisCache, dao.NewUserDao,
+		cache.NewCodeLocalCache, cache.NewUserRedisCache, cache.NewLoginLockRedisCache, cache.NewRoleRedisCache, dao.NewUserDao, dao.NewRoleDAO, dao.NewMFADAO, dao.NewAsyncSMSDAO,
 		//repo
-		wire.NewSet(repository.NewCodeCacheRepository, repository.NewUserCacheRepository,
+		wire.NewSet(repository.NewCodeCacheRepository, repository.NewUserCacheRepository, repository.NewRoleCacheRepository,
+			repository.NewMFADAORepository, repository.NewLoginLockCacheRepository, repository.NewAsyncSMSDAORepository,
 			//绑定repo接口
 			wire.Bind(new(repository.CodeRepository), new(*repository.CodeCacheRepository)),
 			wire.Bind(new(repository.UserRepository), new(*repository.UserCacheRepository)),
+			wire.Bind(new(repository.RoleRepository), new(*repository.RoleCacheRepository)),
+			wire.Bind(new(repository.MFARepository), new(*repository.MFADAORepository)),
+			wire.Bind(new(repository.LoginLockRepository), new(*repository.LoginLockCacheRepository)),
+			wire.Bind(new(repository.AsyncSMSRepository), new(*repository.AsyncSMSDAORepository)),
 		),
 		//service
-		wire.NewSet(service.NewCodeDevService, service.NewUserDevService, ioc.InitWechatService,
+		wire.NewSet(service.NewCodeDevService, service.NewUserDevService, service.NewRoleDevService, service.NewMFADevService,
+			ioc.InitWechatService,
 			//绑定service接口
 			wire.Bind(new(wechat.Service), new(*wechat.DevService)),
 			wire.Bind(new(service.CodeService), new(*service.CodeDevService)),
 			wire.Bind(new(service.UserService), new(*service.UserDevService)),
+			wire.Bind(new(service.RoleService), new(*service.RoleDevService)),
+			wire.Bind(new(ijwt.PermissionLoader), new(*service.RoleDevService)),
+			wire.Bind(new(service.MFAService), new(*service.MFADevService)),
+		),
+		ioc.InitPasswordHasher,
+		ioc.InitEmailService,
+		ioc.InitSMSTemplates, ioc.InitSMSFailoverService, ioc.InitAsyncSMSService, ioc.InitSMSService, ioc.InitSMSGatewayService,
+		ioc.InitEmailTemplates,
+		wire.NewSet(ioc.InitPasswordResetService,
+			wire.Bind(new(service.PasswordResetService), new(*service.PasswordResetDevService)),
+		),
+		wire.NewSet(ioc.InitEmailVerifyService,
+			wire.Bind(new(service.EmailVerifyService), new(*service.EmailVerifyDevService)),
+		),
+		wire.NewSet(ioc.InitLoginGuardService,
+			wire.Bind(new(service.LoginGuardService), new(*service.LoginGuardDevService)),
 		),
-		ioc.InitSMSService,
 		//web
-		ioc.InitGin, ioc.InitMiddlewares, web.NewOAuthWechatHandler,
-		wire.NewSet(web.NewUserHandler, ijwt.NewRedisJwt,
+		ioc.InitGin, ioc.InitMiddlewares, ioc.InitValidationPolicy, web.NewOAuthWechatHandler, web.NewJWKSHandler,
+		web.NewPasswordResetHandler, web.NewEmailVerifyHandler, web.NewSMSHealthHandler, web.NewSMSGatewayHandler,
+		wire.NewSet(web.NewUserHandler, ioc.InitJWTKeys, ioc.InitJWTTransport, ioc.InitDeviceBinding, ijwt.NewRedisJwt,
 			wire.Bind(new(ijwt.Handler), new(*ijwt.RedisJwt)),
+			wire.Bind(new(job.SessionRevoker), new(*ijwt.RedisJwt)),
 		),
+		//后台任务
+		ioc.InitPurgeUserJob,
+		wire.Struct(new(App), "*"),
 	)
-	return new(gin.Engine)
+	return new(App)
 }
@@ -7,7 +7,6 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
 	"webook/internal/repository"
 	"webook/internal/repository/cache"
 	"webook/internal/repository/dao"
@@ -19,22 +18,58 @@ import (
 
 // Injectors from wire.go:
 
-func InitWebServer() *gin.Engine {
+func InitApp() *App {
 	cmdable := ioc.InitRedis()
-	redisJwt := ijwt.NewRedisJwt(cmdable)
-	v := ioc.InitMiddlewares(cmdable, redisJwt)
+	keySet := ioc.InitJWTKeys()
+	transport := ioc.InitJWTTransport()
+	deviceBinding := ioc.InitDeviceBinding()
 	db := ioc.InitDB()
+	roleDAO := dao.NewRoleDAO(db)
+	roleCache := cache.NewRoleRedisCache(cmdable)
+	roleCacheRepository := repository.NewRoleCacheRepository(roleDAO, roleCache)
+	roleDevService := service.NewRoleDevService(roleCacheRepository)
+	redisJwt := ijwt.NewRedisJwt(cmdable, keySet, transport, deviceBinding, roleDevService)
+	v := ioc.InitMiddlewares(redisJwt)
 	userDAO := dao.NewUserDao(db)
 	userCache := cache.NewUserRedisCache(cmdable)
 	userCacheRepository := repository.NewUserCacheRepository(userDAO, userCache)
-	userDevService := service.NewUserDevService(userCacheRepository)
-	smsService := ioc.InitSMSService()
+	hasher := ioc.InitPasswordHasher()
+	userDevService := service.NewUserDevService(userCacheRepository, hasher)
+	registry := ioc.InitSMSTemplates()
+	failoverService := ioc.InitSMSFailoverService(registry)
+	asyncSMSDAO := dao.NewAsyncSMSDAO(db)
+	asyncSMSDAORepository := repository.NewAsyncSMSDAORepository(asyncSMSDAO)
+	asyncService := ioc.InitAsyncSMSService(failoverService, asyncSMSDAORepository)
+	smsService := ioc.InitSMSService(asyncService, registry)
 	codeCache := cache.NewCodeLocalCache()
 	codeCacheRepository := repository.NewCodeCacheRepository(codeCache)
 	codeDevService := service.NewCodeDevService(smsService, codeCacheRepository)
-	userHandler := web.NewUserHandler(userDevService, codeDevService, cmdable, redisJwt)
+	mfadao := dao.NewMFADAO(db)
+	mfadaoRepository := repository.NewMFADAORepository(mfadao)
+	mfaDevService := service.NewMFADevService(mfadaoRepository)
+	emailService := ioc.InitEmailService(cmdable)
+	templates := ioc.InitEmailTemplates()
+	emailVerifyDevService := ioc.InitEmailVerifyService(userCacheRepository, codeCacheRepository, emailService, templates, cmdable)
+	loginLockCache := cache.NewLoginLockRedisCache(cmdable)
+	loginLockCacheRepository := repository.NewLoginLockCacheRepository(loginLockCache)
+	loginGuardDevService := ioc.InitLoginGuardService(userCacheRepository, loginLockCacheRepository, codeDevService, codeCacheRepository, emailService, templates, cmdable)
+	policy := ioc.InitValidationPolicy()
+	userHandler := web.NewUserHandler(userDevService, codeDevService, redisJwt, mfaDevService, emailVerifyDevService, loginGuardDevService, policy, roleDevService)
 	devService := ioc.InitWechatService()
-	oAuthWechatHandler := web.NewOAuthWechatHandler(devService, userDevService)
-	engine := ioc.InitGin(v, userHandler, oAuthWechatHandler)
-	return engine
+	oAuthWechatHandler := web.NewOAuthWechatHandler(devService, userDevService, redisJwt)
+	jwksHandler := web.NewJWKSHandler(keySet)
+	passwordResetDevService := ioc.InitPasswordResetService(userCacheRepository, userDevService, codeDevService, emailService, templates, cmdable)
+	passwordResetHandler := web.NewPasswordResetHandler(passwordResetDevService, redisJwt, policy)
+	emailVerifyHandler := web.NewEmailVerifyHandler(emailVerifyDevService)
+	smsHealthHandler := web.NewSMSHealthHandler(failoverService)
+	authSMSService := ioc.InitSMSGatewayService(smsService, cmdable, registry)
+	smsGatewayHandler := web.NewSMSGatewayHandler(authSMSService)
+	engine := ioc.InitGin(v, userHandler, oAuthWechatHandler, jwksHandler, passwordResetHandler, emailVerifyHandler, smsHealthHandler, smsGatewayHandler)
+	purgeUserJob := ioc.InitPurgeUserJob(userDevService, redisJwt)
+	app := &App{
+		server:   engine,
+		purgeJob: purgeUserJob,
+		asyncSMS: asyncService,
+	}
+	return app
 }
@@ -1,6 +0,0 @@
-package config
-
-var (
-	JwtTokenKey     = "tbkykLFqpai8IwdLt9N20HfAsFZoK1uA"
-	RefreshTokenKey = "tbkykLFqpai8IwdLt9N20HfAsFZoK1uB"
-)
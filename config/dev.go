@@ -9,4 +9,16 @@ var Config = config{
 	Redis: RedisConfig{
 		Addr: "localhost:6379",
 	},
+	//不配置key时启动会临时生成一个
+	JWT: JWTConfig{
+		Transport:     "both",
+		DeviceBinding: "ua_family",
+	},
+	PasswordReset: PasswordResetConfig{
+		LinkURL: "http://localhost:3000/reset_password?token=",
+	},
+	EmailVerify: EmailVerifyConfig{
+		LinkURL:              "http://localhost:3000/verify_email?token=",
+		AllowUnverifiedLogin: true,
+	},
 }
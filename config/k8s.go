@@ -9,4 +9,57 @@ var Config = config{
 	Redis: RedisConfig{
 		Addr: "webook-redis:6380",
 	},
+	JWT: JWTConfig{
+		SigningKid: "webook-1",
+		Keys: []JWTKeyConfig{
+			{
+				Kid:            "webook-1",
+				PrivateKeyFile: "/etc/webook/jwt/webook-1.pem",
+			},
+		},
+		Transport: "both",
+		Cookie: JWTCookieConfig{
+			Secure: true,
+		},
+		DeviceBinding: "ua_family",
+	},
+	PasswordReset: PasswordResetConfig{
+		LinkURL: "https://webook.com/reset_password?token=",
+	},
+	Email: EmailConfig{
+		SMTP: []SMTPConfig{
+			{
+				Host:        "smtp.exmail.qq.com",
+				Port:        465,
+				Username:    "noreply@webook.com",
+				PasswordEnv: "WEBOOK_SMTP_PASSWORD",
+				From:        "webook <noreply@webook.com>",
+			},
+		},
+		RatePerMinute: 100,
+	},
+	SMS: SMSConfig{
+		Gateway: SMSGatewayConfig{
+			KeyEnv:       "WEBOOK_SMS_GATEWAY_KEY",
+			DefaultQuota: 60,
+		},
+	},
+	EmailVerify: EmailVerifyConfig{
+		LinkURL:              "https://webook.com/verify_email?token=",
+		AllowUnverifiedLogin: false,
+	},
+	Validation: ValidationConfig{
+		Password: PasswordPolicyConfig{
+			MinLength:           8,
+			MaxLength:           64,
+			RequireLetter:       true,
+			RequireDigit:        true,
+			RequireSymbol:       true,
+			DisallowEmail:       true,
+			CommonPasswordsFile: "/etc/webook/policy/common_passwords.txt",
+		},
+		Email: EmailPolicyConfig{
+			DisposableDomainsFile: "/etc/webook/policy/disposable_domains.txt",
+		},
+	},
 }
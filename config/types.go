@@ -3,6 +3,15 @@ package config
 type config struct {
 	DB    DBConfig
 	Redis RedisConfig
+	JWT   JWTConfig
+	//找回密码
+	PasswordReset PasswordResetConfig
+	Email         EmailConfig
+	SMS           SMSConfig
+	EmailVerify   EmailVerifyConfig
+	Password      PasswordConfig
+	//注册 改密码 绑定邮箱时的校验规则
+	Validation ValidationConfig
 }
 
 type DBConfig struct {
@@ -12,3 +21,139 @@ type DBConfig struct {
 type RedisConfig struct {
 	Addr string
 }
+
+type JWTConfig struct {
+	//当前用于签名的kid
+	SigningKid string
+	//所有用于验签的key 轮换时老key保留到它签的token都过期
+	Keys []JWTKeyConfig
+	//token传递方式 header cookie both 默认header
+	Transport string
+	Cookie    JWTCookieConfig
+	//短token绑定设备的策略 ua ua_family ua_major device_id none 默认ua
+	DeviceBinding string
+}
+
+type JWTCookieConfig struct {
+	Domain string
+	//只在https下发送
+	Secure bool
+}
+
+type JWTKeyConfig struct {
+	Kid string
+	//PEM格式 RSA或Ed25519 只用于验签的key可以不配私钥
+	PrivateKeyFile string
+	PublicKeyFile  string
+}
+
+type PasswordResetConfig struct {
+	//邮件里的重置链接 后面直接拼接token
+	LinkURL string
+}
+
+type EmailVerifyConfig struct {
+	//邮件里的验证链接 后面直接拼接token
+	LinkURL string
+	//是否允许邮箱没验证的用户用邮箱密码登录
+	AllowUnverifiedLogin bool
+}
+
+type EmailConfig struct {
+	//为空时使用内存实现 只打印不发送 配置多个时轮询并自动切换
+	SMTP []SMTPConfig
+	//每分钟最多发送多少封 默认100
+	RatePerMinute int
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	//密码从这个环境变量读取
+	PasswordEnv string
+	//发件人 例如 webook <noreply@webook.com>
+	From string
+}
+
+type SMSConfig struct {
+	//按顺序使用 前面的优先 为空时使用内存实现 只打印不发送
+	Providers []SMSProviderConfig
+	//业务到模板的映射 biz -> 服务商Name -> 模板 每个服务商都要配置自己的模板
+	Templates map[string]map[string]SMSTemplateConfig
+	//给其他内部服务用的短信网关
+	Gateway SMSGatewayConfig
+}
+
+type SMSGatewayConfig struct {
+	//签发业务方token的HMAC密钥从这个环境变量读取 没有配置时临时生成一个 重启之后所有token失效
+	KeyEnv string
+	//签发的token最长有效期 默认90天 不签发永久有效的token
+	MaxTTLDays int
+	//业务方每分钟最多发送多少次 默认60
+	DefaultQuota int
+	//按业务方单独配置
+	Quotas map[string]int
+}
+
+type SMSProviderConfig struct {
+	//tencent aliyun
+	Type string
+	//展示健康状态和查找模板时用 默认和Type一样
+	Name string
+	//为空时使用服务商的正式地址 本地联调时指向假的服务商 例如 http://localhost:8187
+	Endpoint string
+	//模板没有配置签名时使用
+	SignName string
+	//密钥从这两个环境变量读取
+	AccessKeyIdEnv     string
+	AccessKeySecretEnv string
+	//腾讯云
+	AppId  string
+	Region string
+}
+
+type SMSTemplateConfig struct {
+	Id       string
+	SignName string
+	//参数名 按顺序和参数对应 阿里云按名字传参 腾讯云只校验个数
+	Params []string
+}
+
+type PasswordConfig struct {
+	//新密码使用的算法 argon2id bcrypt 默认argon2id 其他算法的老哈希在登录时自动升级
+	Algorithm  string
+	BcryptCost int
+	Argon2id   Argon2idConfig
+}
+
+type Argon2idConfig struct {
+	//KiB
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+type ValidationConfig struct {
+	Password PasswordPolicyConfig
+	Email    EmailPolicyConfig
+}
+
+// PasswordPolicyConfig MinLength为0时使用默认规则
+type PasswordPolicyConfig struct {
+	MinLength        int
+	MaxLength        int
+	RequireLetter    bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	RequireMixedCase bool
+	DisallowEmail    bool
+	//弱密码列表 一行一个 为空时使用内置的列表
+	CommonPasswordsFile string
+}
+
+type EmailPolicyConfig struct {
+	//一次性邮箱域名列表 一行一个 为空时使用内置的列表
+	DisposableDomainsFile string
+	AllowDisposable       bool
+}
@@ -0,0 +1,11 @@
+package domain
+
+// TOTP 用户绑定的验证器App
+type TOTP struct {
+	UserId int
+	Secret string
+	//验证过一次验证码之后才算开启
+	Enabled bool
+	//最后一次验证通过的时间步 防止同一个验证码被重复使用
+	LastCounter int64
+}
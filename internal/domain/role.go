@@ -0,0 +1,8 @@
+package domain
+
+// Role 角色 一个角色有多个权限
+type Role struct {
+	Id          int
+	Name        string
+	Permissions []string
+}
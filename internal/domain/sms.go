@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// AsyncSMS 等待异步发送的短信
+type AsyncSMS struct {
+	Id      int
+	Biz     string
+	Args    []string
+	Numbers []string
+	//已经尝试的次数
+	Attempts    int
+	MaxAttempts int
+	LastErr     string
+	//下一次重试的时间
+	NextRetry time.Time
+}
@@ -1,11 +1,28 @@
 package domain
 
+import "time"
+
 // User DDD中的entity
 type User struct {
 	Id       int
 	Email    string
 	Phone    string
 	Password string
+	//邮箱是否已经验证过
+	EmailVerified bool
+
+	Nickname string
+	//生日 只有日期部分有意义 UTC
+	Birthday time.Time
+	//个人简介
+	AboutMe string
+	//头像地址
+	Avatar string
 
 	WechatInfo WechatInfo
+
+	//申请注销后计划彻底删除的时间 零值表示没有申请
+	DeleteAt time.Time
+	//注册时间
+	Ctime time.Time
 }
@@ -0,0 +1,53 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/job/purge_user.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/job/purge_user.go -package=jobmocks -destination=internal/job/mocks/purge_user_gen.go
+//
+// Package jobmocks is a generated GoMock package.
+package jobmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSessionRevoker is a mock of SessionRevoker interface.
+type MockSessionRevoker struct {
+	ctrl     *gomock.Controller
+	recorder *MockSessionRevokerMockRecorder
+}
+
+// MockSessionRevokerMockRecorder is the mock recorder for MockSessionRevoker.
+type MockSessionRevokerMockRecorder struct {
+	mock *MockSessionRevoker
+}
+
+// NewMockSessionRevoker creates a new mock instance.
+func NewMockSessionRevoker(ctrl *gomock.Controller) *MockSessionRevoker {
+	mock := &MockSessionRevoker{ctrl: ctrl}
+	mock.recorder = &MockSessionRevokerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSessionRevoker) EXPECT() *MockSessionRevokerMockRecorder {
+	return m.recorder
+}
+
+// PurgeSessions mocks base method.
+func (m *MockSessionRevoker) PurgeSessions(ctx context.Context, userId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeSessions", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeSessions indicates an expected call of PurgeSessions.
+func (mr *MockSessionRevokerMockRecorder) PurgeSessions(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeSessions", reflect.TypeOf((*MockSessionRevoker)(nil).PurgeSessions), ctx, userId)
+}
@@ -0,0 +1,73 @@
+package job
+
+import (
+	"context"
+	"log"
+	"time"
+	"webook/internal/service"
+)
+
+// SessionRevoker 账号删除后清掉它的登录会话
+type SessionRevoker interface {
+	PurgeSessions(ctx context.Context, userId int) error
+}
+
+// PurgeUserJob 定时彻底删除注销冷静期已过的账号
+type PurgeUserJob struct {
+	svc      service.UserService
+	sessions SessionRevoker
+	interval time.Duration
+	//每批删除的数量
+	batch int
+}
+
+func NewPurgeUserJob(svc service.UserService, sessions SessionRevoker, interval time.Duration, batch int) *PurgeUserJob {
+	return &PurgeUserJob{
+		svc:      svc,
+		sessions: sessions,
+		interval: interval,
+		batch:    batch,
+	}
+}
+
+// Start 阻塞直到ctx被取消
+func (j *PurgeUserJob) Start(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		j.Run(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run 一直删除到没有到期的账号为止
+func (j *PurgeUserJob) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		ids, err := j.svc.PurgeDeleted(ctx, j.batch)
+		//出错前已经删掉的账号也要清会话
+		j.revoke(ctx, ids)
+		if err != nil {
+			log.Printf("清除注销账号失败,err:%v", err)
+			return
+		}
+		if len(ids) > 0 {
+			log.Printf("清除了%d个注销账号", len(ids))
+		}
+		if len(ids) < j.batch {
+			return
+		}
+	}
+}
+
+// revoke 清会话失败只记日志 会话最晚在长token过期后失效
+func (j *PurgeUserJob) revoke(ctx context.Context, ids []int) {
+	for _, id := range ids {
+		if err := j.sessions.PurgeSessions(ctx, id); err != nil {
+			log.Printf("清除注销账号的会话失败,uid:%d,err:%v", id, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	jobmocks "webook/internal/job/mocks"
+	"webook/internal/service"
+	svcmocks "webook/internal/service/mocks"
+)
+
+func TestPurgeUserJob_Run(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, SessionRevoker)
+	}{
+		{
+			name: "一批删满了 继续删下一批",
+			mock: func(ctrl *gomock.Controller) (service.UserService, SessionRevoker) {
+				svc := svcmocks.NewMockUserService(ctrl)
+				gomock.InOrder(
+					svc.EXPECT().PurgeDeleted(gomock.Any(), 2).Return([]int{1, 2}, nil),
+					svc.EXPECT().PurgeDeleted(gomock.Any(), 2).Return([]int{3, 4}, nil),
+					svc.EXPECT().PurgeDeleted(gomock.Any(), 2).Return([]int{5}, nil),
+				)
+				sessions := jobmocks.NewMockSessionRevoker(ctrl)
+				for id := 1; id <= 5; id++ {
+					sessions.EXPECT().PurgeSessions(gomock.Any(), id).Return(nil)
+				}
+				return svc, sessions
+			},
+		},
+		{
+			name: "没有到期的账号",
+			mock: func(ctrl *gomock.Controller) (service.UserService, SessionRevoker) {
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().PurgeDeleted(gomock.Any(), 2).Return([]int{}, nil)
+				return svc, jobmocks.NewMockSessionRevoker(ctrl)
+			},
+		},
+		{
+			name: "清会话失败 继续删除",
+			mock: func(ctrl *gomock.Controller) (service.UserService, SessionRevoker) {
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().PurgeDeleted(gomock.Any(), 2).Return([]int{1}, nil)
+				sessions := jobmocks.NewMockSessionRevoker(ctrl)
+				sessions.EXPECT().PurgeSessions(gomock.Any(), 1).Return(errors.New("redis出错"))
+				return svc, sessions
+			},
+		},
+		{
+			name: "出错了等下一轮 已删除的账号也清会话",
+			mock: func(ctrl *gomock.Controller) (service.UserService, SessionRevoker) {
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().PurgeDeleted(gomock.Any(), 2).Return([]int{1}, errors.New("数据库出错"))
+				sessions := jobmocks.NewMockSessionRevoker(ctrl)
+				sessions.EXPECT().PurgeSessions(gomock.Any(), 1).Return(nil)
+				return svc, sessions
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc, sessions := tc.mock(ctrl)
+			j := NewPurgeUserJob(svc, sessions, time.Minute, 2)
+			j.Run(context.Background())
+		})
+	}
+}
+
+func TestPurgeUserJob_Start(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	svc := svcmocks.NewMockUserService(ctrl)
+	//启动时马上执行一次 ctx取消后退出
+	svc.EXPECT().PurgeDeleted(gomock.Any(), 10).DoAndReturn(func(ctx context.Context, batch int) ([]int, error) {
+		cancel()
+		return nil, nil
+	})
+	done := make(chan struct{})
+	go func() {
+		NewPurgeUserJob(svc, jobmocks.NewMockSessionRevoker(ctrl), time.Hour, 10).Start(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ctx取消之后没有退出")
+	}
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// LoginLockCache 登录失败太多之后的锁定和等待 到期自动解除
+type LoginLockCache interface {
+	// Lock 已经锁定时返回false
+	Lock(ctx context.Context, account string, expiration time.Duration) (bool, error)
+	// LockTTL 剩余的锁定时间 没有锁定时返回0
+	LockTTL(ctx context.Context, account string) (time.Duration, error)
+	// SetDelay 在delay之内不允许再次尝试
+	SetDelay(ctx context.Context, account string, delay time.Duration) error
+	// DelayTTL 还需要等待的时间 不需要等待时返回0
+	DelayTTL(ctx context.Context, account string) (time.Duration, error)
+	// Unlock 同时解除锁定和等待
+	Unlock(ctx context.Context, account string) error
+}
+
+type LoginLockRedisCache struct {
+	client redis.Cmdable
+}
+
+func NewLoginLockRedisCache(client redis.Cmdable) LoginLockCache {
+	return &LoginLockRedisCache{client: client}
+}
+
+func (c *LoginLockRedisCache) Lock(ctx context.Context, account string, expiration time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, c.lockKey(account), time.Now().UnixMilli(), expiration).Result()
+}
+
+func (c *LoginLockRedisCache) LockTTL(ctx context.Context, account string) (time.Duration, error) {
+	return c.ttl(ctx, c.lockKey(account))
+}
+
+func (c *LoginLockRedisCache) SetDelay(ctx context.Context, account string, delay time.Duration) error {
+	return c.client.Set(ctx, c.delayKey(account), time.Now().UnixMilli(), delay).Err()
+}
+
+func (c *LoginLockRedisCache) DelayTTL(ctx context.Context, account string) (time.Duration, error) {
+	return c.ttl(ctx, c.delayKey(account))
+}
+
+func (c *LoginLockRedisCache) Unlock(ctx context.Context, account string) error {
+	return c.client.Del(ctx, c.lockKey(account), c.delayKey(account)).Err()
+}
+
+// ttl key不存在时redis返回-2 都当作0
+func (c *LoginLockRedisCache) ttl(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := c.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (c *LoginLockRedisCache) lockKey(account string) string {
+	return fmt.Sprintf("login:lock:%s", account)
+}
+
+func (c *LoginLockRedisCache) delayKey(account string) string {
+	return fmt.Sprintf("login:delay:%s", account)
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+	"webook/internal/domain"
+)
+
+// RoleCache 用户的角色和权限 每次登录和刷新token都要用到
+type RoleCache interface {
+	Get(ctx context.Context, userId int) ([]domain.Role, error)
+	Set(ctx context.Context, userId int, roles []domain.Role) error
+}
+
+type RoleRedisCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewRoleRedisCache(client redis.Cmdable) RoleCache {
+	return &RoleRedisCache{
+		client: client,
+		//角色是直接改库的 过期时间短一点 改完一分钟内生效
+		expiration: time.Minute,
+	}
+}
+
+func (cache *RoleRedisCache) Get(ctx context.Context, userId int) ([]domain.Role, error) {
+	bytes, err := cache.client.Get(ctx, cache.key(userId)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var roles []domain.Role
+	err = json.Unmarshal(bytes, &roles)
+	return roles, err
+}
+
+func (cache *RoleRedisCache) Set(ctx context.Context, userId int, roles []domain.Role) error {
+	val, err := json.Marshal(roles)
+	if err != nil {
+		return err
+	}
+	return cache.client.Set(ctx, cache.key(userId), val, cache.expiration).Err()
+}
+
+func (cache *RoleRedisCache) key(userId int) string {
+	return fmt.Sprintf("user:roles:%d", userId)
+}
@@ -20,6 +20,7 @@ var (
 type UserCache interface {
 	Get(ctx context.Context, id int) (domain.User, error)
 	Set(ctx context.Context, u domain.User) error
+	Delete(ctx context.Context, id int) error
 }
 
 type UserRedisCache struct {
@@ -57,6 +58,10 @@ func (cache *UserRedisCache) Set(ctx context.Context, u domain.User) error {
 	return cache.client.Set(ctx, key, val, cache.expiration).Err()
 }
 
+func (cache *UserRedisCache) Delete(ctx context.Context, id int) error {
+	return cache.client.Del(ctx, cache.key(id)).Err()
+}
+
 func (cache *UserRedisCache) key(id int) string {
 	return fmt.Sprintf("user:info:%d", id)
 }
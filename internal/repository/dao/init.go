@@ -3,5 +3,15 @@ package dao
 import "gorm.io/gorm"
 
 func InitTable(db *gorm.DB) error {
-	return db.AutoMigrate(&User{})
+	//email_verified是后加的列 加列之前注册的邮箱用户都当作已经验证过 否则全部登录不了
+	backfillEmailVerified := db.Migrator().HasTable(&User{}) && !db.Migrator().HasColumn(&User{}, "EmailVerified")
+	err := db.AutoMigrate(&User{}, &Role{}, &RolePermission{}, &UserRole{},
+		&UserTOTP{}, &RecoveryCode{}, &AsyncSMS{})
+	if err != nil {
+		return err
+	}
+	if backfillEmailVerified {
+		return db.Model(&User{}).Where("email IS NOT NULL").Update("email_verified", true).Error
+	}
+	return nil
 }
@@ -0,0 +1,114 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type MFADAO struct {
+	db *gorm.DB
+}
+
+func NewMFADAO(db *gorm.DB) *MFADAO {
+	return &MFADAO{db: db}
+}
+
+func (d *MFADAO) FindTOTP(ctx context.Context, userId int) (UserTOTP, error) {
+	var result UserTOTP
+	err := d.db.WithContext(ctx).Where("user_id=?", userId).First(&result).Error
+	return result, err
+}
+
+// UpsertTOTP 重新绑定时覆盖掉未开启的密钥
+func (d *MFADAO) UpsertTOTP(ctx context.Context, t UserTOTP) error {
+	now := time.Now().UnixMilli()
+	t.Ctime = now
+	t.Utime = now
+	return d.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"secret":       t.Secret,
+			"enabled":      t.Enabled,
+			"last_counter": t.LastCounter,
+			"utime":        now,
+		}),
+	}).Create(&t).Error
+}
+
+// EnableTOTP 开启二次验证 同时换一批恢复码
+func (d *MFADAO) EnableTOTP(ctx context.Context, userId int, counter int64, codes []RecoveryCode) error {
+	now := time.Now().UnixMilli()
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&UserTOTP{}).Where("user_id=?", userId).Updates(map[string]any{
+			"enabled":      true,
+			"last_counter": counter,
+			"utime":        now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("user_id=?", userId).Delete(&RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+		for i := range codes {
+			codes[i].UserId = userId
+			codes[i].Ctime = now
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UpdateLastCounter 只能往前推进 返回false说明验证码已经被用过了
+func (d *MFADAO) UpdateLastCounter(ctx context.Context, userId int, counter int64) (bool, error) {
+	res := d.db.WithContext(ctx).Model(&UserTOTP{}).
+		Where("user_id=? AND last_counter<?", userId, counter).
+		Updates(map[string]any{
+			"last_counter": counter,
+			"utime":        time.Now().UnixMilli(),
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+// UseRecoveryCode 恢复码只能用一次
+func (d *MFADAO) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error) {
+	res := d.db.WithContext(ctx).Model(&RecoveryCode{}).
+		Where("user_id=? AND code_hash=? AND used_at=0", userId, codeHash).
+		Update("used_at", time.Now().UnixMilli())
+	return res.RowsAffected > 0, res.Error
+}
+
+func (d *MFADAO) DeleteTOTP(ctx context.Context, userId int) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id=?", userId).Delete(&UserTOTP{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id=?", userId).Delete(&RecoveryCode{}).Error
+	})
+}
+
+// UserTOTP 用户的验证器密钥
+type UserTOTP struct {
+	Id          int    `gorm:"primaryKey,autoIncrement"`
+	UserId      int    `gorm:"unique"`
+	Secret      string `gorm:"type:varchar(64)"`
+	Enabled     bool
+	LastCounter int64
+
+	Ctime int64
+	Utime int64
+}
+
+// RecoveryCode 恢复码 只存哈希
+type RecoveryCode struct {
+	Id       int    `gorm:"primaryKey,autoIncrement"`
+	UserId   int    `gorm:"index"`
+	CodeHash string `gorm:"type:char(64)"`
+	//使用时间 ms 0表示还没用过
+	UsedAt int64
+
+	Ctime int64
+}
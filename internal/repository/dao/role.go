@@ -0,0 +1,61 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+type RoleDAO struct {
+	db *gorm.DB
+}
+
+func NewRoleDAO(db *gorm.DB) *RoleDAO {
+	return &RoleDAO{db: db}
+}
+
+// FindByUserId 查用户拥有的所有角色
+func (d *RoleDAO) FindByUserId(ctx context.Context, userId int) ([]Role, error) {
+	var roles []Role
+	err := d.db.WithContext(ctx).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userId).
+		Find(&roles).Error
+	return roles, err
+}
+
+// FindPermissions 查多个角色的权限
+func (d *RoleDAO) FindPermissions(ctx context.Context, roleIds []int) ([]RolePermission, error) {
+	var perms []RolePermission
+	if len(roleIds) == 0 {
+		return perms, nil
+	}
+	err := d.db.WithContext(ctx).Where("role_id IN ?", roleIds).Find(&perms).Error
+	return perms, err
+}
+
+// Role 角色表
+type Role struct {
+	Id   int    `gorm:"primaryKey,autoIncrement"`
+	Name string `gorm:"type:varchar(64);unique"`
+
+	Ctime int64
+	Utime int64
+}
+
+// RolePermission 角色拥有的权限 例如 user:manage
+type RolePermission struct {
+	Id         int    `gorm:"primaryKey,autoIncrement"`
+	RoleId     int    `gorm:"uniqueIndex:role_perm"`
+	Permission string `gorm:"type:varchar(128);uniqueIndex:role_perm"`
+
+	Ctime int64
+}
+
+// UserRole 用户和角色的关联
+type UserRole struct {
+	Id     int `gorm:"primaryKey,autoIncrement"`
+	UserId int `gorm:"uniqueIndex:user_role"`
+	RoleId int `gorm:"uniqueIndex:user_role"`
+
+	Ctime int64
+}
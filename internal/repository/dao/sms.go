@@ -0,0 +1,155 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"gorm.io/gorm"
+	"time"
+)
+
+// 异步短信的状态
+const (
+	AsyncSMSStatusWaiting uint8 = iota
+	//被某个worker抢占 NextRetry为租约到期时间 到期还没结束说明worker挂了 可以重新抢占
+	AsyncSMSStatusSending
+	AsyncSMSStatusSuccess
+	//重试次数用完了
+	AsyncSMSStatusFailed
+)
+
+type AsyncSMSDAO struct {
+	db *gorm.DB
+}
+
+func NewAsyncSMSDAO(db *gorm.DB) *AsyncSMSDAO {
+	return &AsyncSMSDAO{db: db}
+}
+
+func (d *AsyncSMSDAO) Insert(ctx context.Context, s AsyncSMS) error {
+	now := time.Now().UnixMilli()
+	s.Ctime = now
+	s.Utime = now
+	return d.db.WithContext(ctx).Create(&s).Error
+}
+
+// Preempt 抢占最多limit个到期的任务 抢占之后的lease内其他worker拿不到
+func (d *AsyncSMSDAO) Preempt(ctx context.Context, now int64, lease time.Duration, limit int) ([]AsyncSMS, error) {
+	var candidates []AsyncSMS
+	err := d.db.WithContext(ctx).
+		//不能传[]uint8 会被当成[]byte拼成一个参数
+		Where("status IN (?,?) AND next_retry<=?",
+			AsyncSMSStatusWaiting, AsyncSMSStatusSending, now).
+		Order("next_retry").Limit(limit).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	res := make([]AsyncSMS, 0, len(candidates))
+	for _, s := range candidates {
+		//同时被多个实例查出来时只有一个能抢到 utime是毫秒 同一毫秒内会重复抢到 所以用单独的版本号
+		result := d.db.WithContext(ctx).Model(&AsyncSMS{}).
+			Where("id=? AND version=?", s.Id, s.Version).
+			Updates(map[string]any{
+				"status":     AsyncSMSStatusSending,
+				"next_retry": now + lease.Milliseconds(),
+				"version":    gorm.Expr("version + 1"),
+				"utime":      now,
+			})
+		if result.Error != nil {
+			return res, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		s.Status = AsyncSMSStatusSending
+		s.Version++
+		s.Utime = now
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+func (d *AsyncSMSDAO) MarkSuccess(ctx context.Context, id int) error {
+	return d.db.WithContext(ctx).Model(&AsyncSMS{}).Where("id=?", id).
+		Updates(map[string]any{
+			"status":  AsyncSMSStatusSuccess,
+			"version": gorm.Expr("version + 1"),
+			"utime":   time.Now().UnixMilli(),
+		}).Error
+}
+
+// MarkRetry 发送失败 等到nextRetry再试 nextRetry为0表示不再重试
+func (d *AsyncSMSDAO) MarkRetry(ctx context.Context, id int, attempts int, lastErr string, nextRetry int64) error {
+	status := AsyncSMSStatusWaiting
+	if nextRetry == 0 {
+		status = AsyncSMSStatusFailed
+	}
+	return d.db.WithContext(ctx).Model(&AsyncSMS{}).Where("id=?", id).
+		Updates(map[string]any{
+			"status":     status,
+			"attempts":   attempts,
+			"last_err":   lastErr,
+			"next_retry": nextRetry,
+			"version":    gorm.Expr("version + 1"),
+			"utime":      time.Now().UnixMilli(),
+		}).Error
+}
+
+// removeAsyncSMSNumber 从短信任务里去掉这个手机号 只发给这一个手机号的任务直接删除
+// 在删除用户的事务里调用
+func removeAsyncSMSNumber(tx *gorm.DB, phone string) error {
+	var list []AsyncSMS
+	//numbers是json数组 手机号带着引号匹配
+	err := tx.Where("numbers LIKE ?", "%\""+phone+"\"%").Find(&list).Error
+	if err != nil {
+		return err
+	}
+	for _, s := range list {
+		var numbers []string
+		if err = json.Unmarshal([]byte(s.Numbers), &numbers); err != nil {
+			return err
+		}
+		left := make([]string, 0, len(numbers))
+		for _, n := range numbers {
+			if n != phone {
+				left = append(left, n)
+			}
+		}
+		if len(left) == 0 {
+			err = tx.Where("id=?", s.Id).Delete(&AsyncSMS{}).Error
+		} else {
+			val, _ := json.Marshal(left)
+			err = tx.Model(&AsyncSMS{}).Where("id=?", s.Id).
+				Updates(map[string]any{
+					"numbers": string(val),
+					"version": gorm.Expr("version + 1"),
+					"utime":   time.Now().UnixMilli(),
+				}).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AsyncSMS 短信发送任务
+type AsyncSMS struct {
+	Id  int    `gorm:"primaryKey,autoIncrement"`
+	Biz string `gorm:"type:varchar(128)"`
+	//json数组
+	Args    string `gorm:"type:varchar(1024)"`
+	Numbers string `gorm:"type:varchar(1024)"`
+	//和next_retry一起查询 到期的等待任务
+	Status      uint8 `gorm:"index:status_next_retry"`
+	Attempts    int
+	MaxAttempts int
+	LastErr     string `gorm:"type:varchar(512)"`
+	//ms
+	NextRetry int64 `gorm:"index:status_next_retry"`
+	//每次更新都加一 抢占时比较
+	Version int64
+
+	Ctime int64
+	Utime int64
+}
@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormMysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestAsyncSMSDAO_Preempt(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(t *testing.T) *sql.DB
+
+		wantIds []int
+		wantErr error
+	}{
+		{
+			name: "按版本号抢占 被别人抢走的跳过",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				rows := sqlmock.NewRows([]string{"id", "status", "version", "utime"}).
+					AddRow(1, AsyncSMSStatusWaiting, 3, 1000).
+					AddRow(2, AsyncSMSStatusSending, 5, 1000)
+				mock.ExpectQuery("SELECT \\* FROM `async_sms` WHERE status IN \\(\\?,\\?\\) AND next_retry<=\\?").
+					WithArgs(AsyncSMSStatusWaiting, AsyncSMSStatusSending, 1000).
+					WillReturnRows(rows)
+				mock.ExpectExec("UPDATE `async_sms` SET .*`version`=version \\+ 1 WHERE id=\\? AND version=\\?").
+					WithArgs(61000, AsyncSMSStatusSending, 1000, 1, 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				//同一毫秒内另一个实例已经抢到了
+				mock.ExpectExec("UPDATE `async_sms` SET .*`version`=version \\+ 1 WHERE id=\\? AND version=\\?").
+					WithArgs(61000, AsyncSMSStatusSending, 1000, 2, 5).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				return mockDB
+			},
+			wantIds: []int{1},
+		},
+		{
+			name: "查询失败",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectQuery("SELECT \\* FROM `async_sms`").WillReturnError(errors.New("数据库出错"))
+				return mockDB
+			},
+			wantErr: errors.New("数据库出错"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := gorm.Open(gormMysql.New(gormMysql.Config{
+				Conn:                      tc.mock(t),
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			d := NewAsyncSMSDAO(db)
+			res, err := d.Preempt(context.Background(), 1000, time.Minute, 16)
+			assert.Equal(t, tc.wantErr, err)
+			ids := make([]int, 0, len(res))
+			for _, s := range res {
+				assert.Equal(t, AsyncSMSStatusSending, s.Status)
+				ids = append(ids, s.Id)
+			}
+			if tc.wantErr == nil {
+				assert.Equal(t, tc.wantIds, ids)
+			}
+		})
+	}
+}
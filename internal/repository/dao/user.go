@@ -6,12 +6,15 @@ import (
 	"errors"
 	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"time"
 )
 
 var (
 	ErrUserDuplicateEmail = errors.New("邮箱冲突")
 	ErrUserNotFind        = gorm.ErrRecordNotFound
+	//手机号 邮箱 微信已经被其他用户占用
+	ErrUserDuplicate = errors.New("唯一索引冲突")
 )
 
 type UserDAO struct {
@@ -57,13 +60,124 @@ func (u *UserDAO) FindById(ctx context.Context, id int) (User, error) {
 	return result, err
 }
 
-func (u *UserDAO) Update(ctx context.Context, user User) error {
-	//更新Ctime Utime
-	now := time.Now().UnixMilli()
-	user.Utime = now
+// UpdatePassword 只更新密码 不能整行覆盖 否则会把同时绑定的手机号 邮箱之类的改回去
+func (u *UserDAO) UpdatePassword(ctx context.Context, id int, password string) error {
+	res := u.db.WithContext(ctx).Model(&User{}).Where("id=?", id).
+		Updates(map[string]any{
+			"password": password,
+			"utime":    time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotFind
+	}
+	return nil
+}
 
-	//更新
-	return u.db.WithContext(ctx).Where("email=?", user.Email).Save(&user).Error
+func (u *UserDAO) UpdateEmailVerified(ctx context.Context, id int, email string) error {
+	//邮箱在发出验证邮件之后被改过 不能算验证通过
+	res := u.db.WithContext(ctx).Model(&User{}).
+		Where("id=? AND email=?", id, email).
+		Updates(map[string]any{
+			"email_verified": true,
+			"utime":          time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotFind
+	}
+	return nil
+}
+
+// UpdateLoginIdentity 更新手机号 邮箱 微信这些登录方式
+func (u *UserDAO) UpdateLoginIdentity(ctx context.Context, user User) error {
+	err := u.db.WithContext(ctx).Model(&User{}).Where("id=?", user.Id).
+		Updates(map[string]any{
+			"phone":           user.Phone,
+			"email":           user.Email,
+			"email_verified":  user.EmailVerified,
+			"wechat_open_id":  user.WechatOpenID,
+			"wechat_union_id": user.WechatUnionID,
+			"utime":           time.Now().UnixMilli(),
+		}).Error
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		const uniqueConflictsErrNo uint16 = 1062
+		if mysqlErr.Number == uniqueConflictsErrNo {
+			return ErrUserDuplicate
+		}
+	}
+	return err
+}
+
+// UpdateProfile 只更新个人资料 不会碰到密码之类的字段
+func (u *UserDAO) UpdateProfile(ctx context.Context, user User) error {
+	return u.db.WithContext(ctx).Model(&User{}).Where("id=?", user.Id).
+		Updates(map[string]any{
+			"nickname": user.Nickname,
+			"birthday": user.Birthday,
+			"about_me": user.AboutMe,
+			"avatar":   user.Avatar,
+			"utime":    time.Now().UnixMilli(),
+		}).Error
+}
+
+// UpdateDeleteAt 申请注销时写入计划彻底删除的时间 0表示撤销注销
+func (u *UserDAO) UpdateDeleteAt(ctx context.Context, id int, deleteAt int64) error {
+	return u.db.WithContext(ctx).Model(&User{}).Where("id=?", id).
+		Updates(map[string]any{
+			"delete_at": deleteAt,
+			"utime":     time.Now().UnixMilli(),
+		}).Error
+}
+
+// FindDeleteDue 找出冷静期已经过了的用户id
+func (u *UserDAO) FindDeleteDue(ctx context.Context, now int64, limit int) ([]int, error) {
+	var ids []int
+	err := u.db.WithContext(ctx).Model(&User{}).
+		Where("delete_at>0 AND delete_at<=?", now).
+		Order("delete_at").Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// Purge 彻底删除用户以及关联的角色和二次验证数据 待发送短信里的手机号也一起删掉
+// 用户在冷静期内撤销了注销时不删除 返回ErrUserNotFind
+func (u *UserDAO) Purge(ctx context.Context, id int, now int64) error {
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		//锁住这一行 删除之前用户不能撤销注销
+		var user User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id=? AND delete_at>0 AND delete_at<=?", id, now).
+			First(&user).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("id=?", id).Delete(&User{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("user_id=?", id).Delete(&UserRole{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("user_id=?", id).Delete(&UserTOTP{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("user_id=?", id).Delete(&RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+		if !user.Phone.Valid {
+			return nil
+		}
+		return removeAsyncSMSNumber(tx, user.Phone.String)
+	})
 }
 
 func (u *UserDAO) FindByWechat(ctx context.Context, openId string) (User, error) {
@@ -77,6 +191,14 @@ type User struct {
 	Id       int            `gorm:"primaryKey,autoIncrement"`
 	Email    sql.NullString `gorm:"unique"`
 	Password string
+	//邮箱是否已经验证过
+	EmailVerified bool
+
+	Nickname string `gorm:"type:varchar(128)"`
+	//生日 UTC零点的毫秒数
+	Birthday sql.NullInt64
+	AboutMe  string `gorm:"type:varchar(1024)"`
+	Avatar   string `gorm:"type:varchar(1024)"`
 	//允许多个空值的唯一索引
 	Phone sql.NullString `gorm:"unique"`
 
@@ -84,6 +206,9 @@ type User struct {
 	WechatUnionID sql.NullString `gorm:"unique"`
 	WechatOpenID  sql.NullString `gorm:"unique"`
 
+	//申请注销后计划彻底删除的时间 ms 0表示没有申请
+	DeleteAt int64 `gorm:"index"`
+
 	//Create time ms
 	Ctime int64
 	//update time ms
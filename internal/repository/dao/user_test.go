@@ -3,6 +3,7 @@ package dao
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
@@ -75,3 +76,147 @@ func TestUserDAO_Insert(t *testing.T) {
 		})
 	}
 }
+
+func TestUserDAO_UpdatePassword(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(t *testing.T) *sql.DB
+
+		wantErr error
+	}{
+		{
+			name: "只更新密码",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				//不能带上手机号 邮箱这些字段
+				mock.ExpectExec("^UPDATE `users` SET `password`=\\?,`utime`=\\? WHERE id=\\?$").
+					WithArgs("hash", sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				return mockDB
+			},
+		},
+		{
+			name: "用户不存在",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectExec("UPDATE `users`").WillReturnResult(sqlmock.NewResult(0, 0))
+				return mockDB
+			},
+			wantErr: ErrUserNotFind,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := gorm.Open(gormMysql.New(gormMysql.Config{
+				Conn:                      tc.mock(t),
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			d := NewUserDao(db)
+			err = d.UpdatePassword(context.Background(), 1, "hash")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestUserDAO_Purge(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(t *testing.T) *sql.DB
+
+		wantErr error
+	}{
+		{
+			name: "删除用户和关联数据 没有手机号",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `users` WHERE id=\\? AND delete_at>0 AND delete_at<=\\? .* FOR UPDATE").
+					WithArgs(1, 1000).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectExec("DELETE FROM `users` WHERE id=\\?").
+					WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM `user_roles`").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("DELETE FROM `user_totps`").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM `recovery_codes`").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 10))
+				mock.ExpectCommit()
+				return mockDB
+			},
+		},
+		{
+			name: "删除用户 清理待发送短信里的手机号",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `users`").WithArgs(1, 1000).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "phone"}).AddRow(1, "13800000000"))
+				mock.ExpectExec("DELETE FROM `users`").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM `user_roles`").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("DELETE FROM `user_totps`").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("DELETE FROM `recovery_codes`").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT \\* FROM `async_sms` WHERE numbers LIKE \\?").
+					WithArgs(`%"13800000000"%`).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "numbers"}).
+						AddRow(1, `["13800000000"]`).
+						AddRow(2, `["13800000000","13900000000"]`))
+				mock.ExpectExec("DELETE FROM `async_sms` WHERE id=\\?").WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE `async_sms` SET `numbers`=\\?,`utime`=\\?,`version`=version \\+ 1 WHERE id=\\?").
+					WithArgs(`["13900000000"]`, sqlmock.AnyArg(), 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				return mockDB
+			},
+		},
+		{
+			name: "冷静期内撤销了注销",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `users`").WithArgs(1, 1000).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectRollback()
+				return mockDB
+			},
+			wantErr: ErrUserNotFind,
+		},
+		{
+			name: "删除关联数据失败 整体回滚",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `users`").WithArgs(1, 1000).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectExec("DELETE FROM `users`").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM `user_roles`").WithArgs(1).WillReturnError(errors.New("数据库出错"))
+				mock.ExpectRollback()
+				return mockDB
+			},
+			wantErr: errors.New("数据库出错"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := gorm.Open(gormMysql.New(gormMysql.Config{
+				Conn:                      tc.mock(t),
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			d := NewUserDao(db)
+			err = d.Purge(context.Background(), 1, 1000)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
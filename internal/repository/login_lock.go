@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"webook/internal/repository/cache"
+)
+
+type LoginLockRepository interface {
+	// Lock 已经锁定时返回false
+	Lock(ctx context.Context, account string, expiration time.Duration) (bool, error)
+	// LockTTL 没有锁定时返回0
+	LockTTL(ctx context.Context, account string) (time.Duration, error)
+	SetDelay(ctx context.Context, account string, delay time.Duration) error
+	// DelayTTL 不需要等待时返回0
+	DelayTTL(ctx context.Context, account string) (time.Duration, error)
+	Unlock(ctx context.Context, account string) error
+}
+
+type LoginLockCacheRepository struct {
+	cache cache.LoginLockCache
+}
+
+func NewLoginLockCacheRepository(cache cache.LoginLockCache) *LoginLockCacheRepository {
+	return &LoginLockCacheRepository{cache: cache}
+}
+
+func (repo *LoginLockCacheRepository) Lock(ctx context.Context, account string, expiration time.Duration) (bool, error) {
+	return repo.cache.Lock(ctx, account, expiration)
+}
+
+func (repo *LoginLockCacheRepository) LockTTL(ctx context.Context, account string) (time.Duration, error) {
+	return repo.cache.LockTTL(ctx, account)
+}
+
+func (repo *LoginLockCacheRepository) SetDelay(ctx context.Context, account string, delay time.Duration) error {
+	return repo.cache.SetDelay(ctx, account, delay)
+}
+
+func (repo *LoginLockCacheRepository) DelayTTL(ctx context.Context, account string) (time.Duration, error) {
+	return repo.cache.DelayTTL(ctx, account)
+}
+
+func (repo *LoginLockCacheRepository) Unlock(ctx context.Context, account string) error {
+	return repo.cache.Unlock(ctx, account)
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"webook/internal/domain"
+	"webook/internal/repository/dao"
+)
+
+var (
+	ErrTOTPNotFind = dao.ErrUserNotFind
+)
+
+type MFARepository interface {
+	FindTOTP(ctx context.Context, userId int) (domain.TOTP, error)
+	SaveTOTP(ctx context.Context, t domain.TOTP) error
+	// EnableTOTP codeHashes为恢复码的哈希
+	EnableTOTP(ctx context.Context, userId int, counter int64, codeHashes []string) error
+	UpdateLastCounter(ctx context.Context, userId int, counter int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error)
+	DeleteTOTP(ctx context.Context, userId int) error
+}
+
+type MFADAORepository struct {
+	dao *dao.MFADAO
+}
+
+func NewMFADAORepository(dao *dao.MFADAO) *MFADAORepository {
+	return &MFADAORepository{dao: dao}
+}
+
+func (r *MFADAORepository) FindTOTP(ctx context.Context, userId int) (domain.TOTP, error) {
+	t, err := r.dao.FindTOTP(ctx, userId)
+	if err != nil {
+		return domain.TOTP{}, err
+	}
+	return domain.TOTP{
+		UserId:      t.UserId,
+		Secret:      t.Secret,
+		Enabled:     t.Enabled,
+		LastCounter: t.LastCounter,
+	}, nil
+}
+
+func (r *MFADAORepository) SaveTOTP(ctx context.Context, t domain.TOTP) error {
+	return r.dao.UpsertTOTP(ctx, dao.UserTOTP{
+		UserId:      t.UserId,
+		Secret:      t.Secret,
+		Enabled:     t.Enabled,
+		LastCounter: t.LastCounter,
+	})
+}
+
+func (r *MFADAORepository) EnableTOTP(ctx context.Context, userId int, counter int64, codeHashes []string) error {
+	codes := make([]dao.RecoveryCode, 0, len(codeHashes))
+	for _, h := range codeHashes {
+		codes = append(codes, dao.RecoveryCode{CodeHash: h})
+	}
+	return r.dao.EnableTOTP(ctx, userId, counter, codes)
+}
+
+func (r *MFADAORepository) UpdateLastCounter(ctx context.Context, userId int, counter int64) (bool, error) {
+	return r.dao.UpdateLastCounter(ctx, userId, counter)
+}
+
+func (r *MFADAORepository) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error) {
+	return r.dao.UseRecoveryCode(ctx, userId, codeHash)
+}
+
+func (r *MFADAORepository) DeleteTOTP(ctx context.Context, userId int) error {
+	return r.dao.DeleteTOTP(ctx, userId)
+}
@@ -0,0 +1,113 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/login_lock.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/login_lock.go -package=repomocks -destination=internal/repository/mocks/login_lock_gen.go
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLoginLockRepository is a mock of LoginLockRepository interface.
+type MockLoginLockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginLockRepositoryMockRecorder
+}
+
+// MockLoginLockRepositoryMockRecorder is the mock recorder for MockLoginLockRepository.
+type MockLoginLockRepositoryMockRecorder struct {
+	mock *MockLoginLockRepository
+}
+
+// NewMockLoginLockRepository creates a new mock instance.
+func NewMockLoginLockRepository(ctrl *gomock.Controller) *MockLoginLockRepository {
+	mock := &MockLoginLockRepository{ctrl: ctrl}
+	mock.recorder = &MockLoginLockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginLockRepository) EXPECT() *MockLoginLockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DelayTTL mocks base method.
+func (m *MockLoginLockRepository) DelayTTL(ctx context.Context, account string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DelayTTL", ctx, account)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DelayTTL indicates an expected call of DelayTTL.
+func (mr *MockLoginLockRepositoryMockRecorder) DelayTTL(ctx, account any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DelayTTL", reflect.TypeOf((*MockLoginLockRepository)(nil).DelayTTL), ctx, account)
+}
+
+// Lock mocks base method.
+func (m *MockLoginLockRepository) Lock(ctx context.Context, account string, expiration time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lock", ctx, account, expiration)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Lock indicates an expected call of Lock.
+func (mr *MockLoginLockRepositoryMockRecorder) Lock(ctx, account, expiration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lock", reflect.TypeOf((*MockLoginLockRepository)(nil).Lock), ctx, account, expiration)
+}
+
+// LockTTL mocks base method.
+func (m *MockLoginLockRepository) LockTTL(ctx context.Context, account string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockTTL", ctx, account)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockTTL indicates an expected call of LockTTL.
+func (mr *MockLoginLockRepositoryMockRecorder) LockTTL(ctx, account any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockTTL", reflect.TypeOf((*MockLoginLockRepository)(nil).LockTTL), ctx, account)
+}
+
+// SetDelay mocks base method.
+func (m *MockLoginLockRepository) SetDelay(ctx context.Context, account string, delay time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDelay", ctx, account, delay)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDelay indicates an expected call of SetDelay.
+func (mr *MockLoginLockRepositoryMockRecorder) SetDelay(ctx, account, delay any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDelay", reflect.TypeOf((*MockLoginLockRepository)(nil).SetDelay), ctx, account, delay)
+}
+
+// Unlock mocks base method.
+func (m *MockLoginLockRepository) Unlock(ctx context.Context, account string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlock", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlock indicates an expected call of Unlock.
+func (mr *MockLoginLockRepositoryMockRecorder) Unlock(ctx, account any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockLoginLockRepository)(nil).Unlock), ctx, account)
+}
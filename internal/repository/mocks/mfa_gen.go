@@ -0,0 +1,127 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/mfa.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/mfa.go -package=repomocks -destination=internal/repository/mocks/mfa_gen.go
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	domain "webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockMFARepository is a mock of MFARepository interface.
+type MockMFARepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockMFARepositoryMockRecorder
+}
+
+// MockMFARepositoryMockRecorder is the mock recorder for MockMFARepository.
+type MockMFARepositoryMockRecorder struct {
+	mock *MockMFARepository
+}
+
+// NewMockMFARepository creates a new mock instance.
+func NewMockMFARepository(ctrl *gomock.Controller) *MockMFARepository {
+	mock := &MockMFARepository{ctrl: ctrl}
+	mock.recorder = &MockMFARepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMFARepository) EXPECT() *MockMFARepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteTOTP mocks base method.
+func (m *MockMFARepository) DeleteTOTP(ctx context.Context, userId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTOTP", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTOTP indicates an expected call of DeleteTOTP.
+func (mr *MockMFARepositoryMockRecorder) DeleteTOTP(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTOTP", reflect.TypeOf((*MockMFARepository)(nil).DeleteTOTP), ctx, userId)
+}
+
+// EnableTOTP mocks base method.
+func (m *MockMFARepository) EnableTOTP(ctx context.Context, userId int, counter int64, codeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTP", ctx, userId, counter, codeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTOTP indicates an expected call of EnableTOTP.
+func (mr *MockMFARepositoryMockRecorder) EnableTOTP(ctx, userId, counter, codeHashes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTP", reflect.TypeOf((*MockMFARepository)(nil).EnableTOTP), ctx, userId, counter, codeHashes)
+}
+
+// FindTOTP mocks base method.
+func (m *MockMFARepository) FindTOTP(ctx context.Context, userId int) (domain.TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindTOTP", ctx, userId)
+	ret0, _ := ret[0].(domain.TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindTOTP indicates an expected call of FindTOTP.
+func (mr *MockMFARepositoryMockRecorder) FindTOTP(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTOTP", reflect.TypeOf((*MockMFARepository)(nil).FindTOTP), ctx, userId)
+}
+
+// SaveTOTP mocks base method.
+func (m *MockMFARepository) SaveTOTP(ctx context.Context, t domain.TOTP) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTOTP", ctx, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTOTP indicates an expected call of SaveTOTP.
+func (mr *MockMFARepositoryMockRecorder) SaveTOTP(ctx, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTP", reflect.TypeOf((*MockMFARepository)(nil).SaveTOTP), ctx, t)
+}
+
+// UpdateLastCounter mocks base method.
+func (m *MockMFARepository) UpdateLastCounter(ctx context.Context, userId int, counter int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLastCounter", ctx, userId, counter)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateLastCounter indicates an expected call of UpdateLastCounter.
+func (mr *MockMFARepositoryMockRecorder) UpdateLastCounter(ctx, userId, counter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLastCounter", reflect.TypeOf((*MockMFARepository)(nil).UpdateLastCounter), ctx, userId, counter)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockMFARepository) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userId, codeHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockMFARepositoryMockRecorder) UseRecoveryCode(ctx, userId, codeHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockMFARepository)(nil).UseRecoveryCode), ctx, userId, codeHash)
+}
@@ -0,0 +1,98 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/sms.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/sms.go -package=repomocks -destination=internal/repository/mocks/sms_gen.go
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+	domain "webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAsyncSMSRepository is a mock of AsyncSMSRepository interface.
+type MockAsyncSMSRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAsyncSMSRepositoryMockRecorder
+}
+
+// MockAsyncSMSRepositoryMockRecorder is the mock recorder for MockAsyncSMSRepository.
+type MockAsyncSMSRepositoryMockRecorder struct {
+	mock *MockAsyncSMSRepository
+}
+
+// NewMockAsyncSMSRepository creates a new mock instance.
+func NewMockAsyncSMSRepository(ctrl *gomock.Controller) *MockAsyncSMSRepository {
+	mock := &MockAsyncSMSRepository{ctrl: ctrl}
+	mock.recorder = &MockAsyncSMSRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAsyncSMSRepository) EXPECT() *MockAsyncSMSRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockAsyncSMSRepository) Add(ctx context.Context, s domain.AsyncSMS) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", ctx, s)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockAsyncSMSRepositoryMockRecorder) Add(ctx, s any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockAsyncSMSRepository)(nil).Add), ctx, s)
+}
+
+// MarkRetry mocks base method.
+func (m *MockAsyncSMSRepository) MarkRetry(ctx context.Context, id, attempts int, lastErr string, nextRetry time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkRetry", ctx, id, attempts, lastErr, nextRetry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkRetry indicates an expected call of MarkRetry.
+func (mr *MockAsyncSMSRepositoryMockRecorder) MarkRetry(ctx, id, attempts, lastErr, nextRetry any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkRetry", reflect.TypeOf((*MockAsyncSMSRepository)(nil).MarkRetry), ctx, id, attempts, lastErr, nextRetry)
+}
+
+// MarkSuccess mocks base method.
+func (m *MockAsyncSMSRepository) MarkSuccess(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkSuccess", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkSuccess indicates an expected call of MarkSuccess.
+func (mr *MockAsyncSMSRepositoryMockRecorder) MarkSuccess(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkSuccess", reflect.TypeOf((*MockAsyncSMSRepository)(nil).MarkSuccess), ctx, id)
+}
+
+// Preempt mocks base method.
+func (m *MockAsyncSMSRepository) Preempt(ctx context.Context, lease time.Duration, limit int) ([]domain.AsyncSMS, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Preempt", ctx, lease, limit)
+	ret0, _ := ret[0].([]domain.AsyncSMS)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Preempt indicates an expected call of Preempt.
+func (mr *MockAsyncSMSRepositoryMockRecorder) Preempt(ctx, lease, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Preempt", reflect.TypeOf((*MockAsyncSMSRepository)(nil).Preempt), ctx, lease, limit)
+}
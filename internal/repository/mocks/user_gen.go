@@ -11,6 +11,7 @@ package repomocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 	domain "webook/internal/domain"
 
 	gomock "go.uber.org/mock/gomock"
@@ -98,6 +99,64 @@ func (mr *MockUserRepositoryMockRecorder) FindByPhone(ctx, user any) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByPhone", reflect.TypeOf((*MockUserRepository)(nil).FindByPhone), ctx, user)
 }
 
+// FindByWechat mocks base method.
+func (m *MockUserRepository) FindByWechat(ctx context.Context, openId string) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByWechat", ctx, openId)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByWechat indicates an expected call of FindByWechat.
+func (mr *MockUserRepositoryMockRecorder) FindByWechat(ctx, openId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByWechat", reflect.TypeOf((*MockUserRepository)(nil).FindByWechat), ctx, openId)
+}
+
+// FindDeleteDue mocks base method.
+func (m *MockUserRepository) FindDeleteDue(ctx context.Context, now time.Time, limit int) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDeleteDue", ctx, now, limit)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDeleteDue indicates an expected call of FindDeleteDue.
+func (mr *MockUserRepositoryMockRecorder) FindDeleteDue(ctx, now, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDeleteDue", reflect.TypeOf((*MockUserRepository)(nil).FindDeleteDue), ctx, now, limit)
+}
+
+// MarkEmailVerified mocks base method.
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, id int, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEmailVerified", ctx, id, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEmailVerified indicates an expected call of MarkEmailVerified.
+func (mr *MockUserRepositoryMockRecorder) MarkEmailVerified(ctx, id, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEmailVerified", reflect.TypeOf((*MockUserRepository)(nil).MarkEmailVerified), ctx, id, email)
+}
+
+// Purge mocks base method.
+func (m *MockUserRepository) Purge(ctx context.Context, id int, now time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, id, now)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockUserRepositoryMockRecorder) Purge(ctx, id, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockUserRepository)(nil).Purge), ctx, id, now)
+}
+
 // Update mocks base method.
 func (m *MockUserRepository) Update(ctx context.Context, user domain.User) error {
 	m.ctrl.T.Helper()
@@ -111,3 +170,45 @@ func (mr *MockUserRepositoryMockRecorder) Update(ctx, user any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserRepository)(nil).Update), ctx, user)
 }
+
+// UpdateDeleteAt mocks base method.
+func (m *MockUserRepository) UpdateDeleteAt(ctx context.Context, id int, deleteAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDeleteAt", ctx, id, deleteAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDeleteAt indicates an expected call of UpdateDeleteAt.
+func (mr *MockUserRepositoryMockRecorder) UpdateDeleteAt(ctx, id, deleteAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDeleteAt", reflect.TypeOf((*MockUserRepository)(nil).UpdateDeleteAt), ctx, id, deleteAt)
+}
+
+// UpdateLoginIdentity mocks base method.
+func (m *MockUserRepository) UpdateLoginIdentity(ctx context.Context, user domain.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLoginIdentity", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLoginIdentity indicates an expected call of UpdateLoginIdentity.
+func (mr *MockUserRepositoryMockRecorder) UpdateLoginIdentity(ctx, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLoginIdentity", reflect.TypeOf((*MockUserRepository)(nil).UpdateLoginIdentity), ctx, user)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockUserRepository) UpdateProfile(ctx context.Context, user domain.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockUserRepositoryMockRecorder) UpdateProfile(ctx, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockUserRepository)(nil).UpdateProfile), ctx, user)
+}
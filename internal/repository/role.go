@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"webook/internal/domain"
+	"webook/internal/repository/cache"
+	"webook/internal/repository/dao"
+)
+
+type RoleRepository interface {
+	FindByUserId(ctx context.Context, userId int) ([]domain.Role, error)
+}
+
+type RoleCacheRepository struct {
+	dao   *dao.RoleDAO
+	cache cache.RoleCache
+}
+
+func NewRoleCacheRepository(dao *dao.RoleDAO, roleCache cache.RoleCache) *RoleCacheRepository {
+	return &RoleCacheRepository{dao: dao, cache: roleCache}
+}
+
+func (r *RoleCacheRepository) FindByUserId(ctx context.Context, userId int) ([]domain.Role, error) {
+	//先从cache找
+	result, err := r.cache.Get(ctx, userId)
+	if err == nil {
+		return result, nil
+	}
+	result, err = r.findByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	//找到了回写cache
+	go func() {
+		er := r.cache.Set(ctx, userId, result)
+		if er != nil {
+			//打日志,做监控
+		}
+	}()
+	return result, nil
+}
+
+func (r *RoleCacheRepository) findByUserId(ctx context.Context, userId int) ([]domain.Role, error) {
+	roles, err := r.dao.FindByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(roles))
+	for _, role := range roles {
+		ids = append(ids, role.Id)
+	}
+	perms, err := r.dao.FindPermissions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	permMap := make(map[int][]string, len(roles))
+	for _, perm := range perms {
+		permMap[perm.RoleId] = append(permMap[perm.RoleId], perm.Permission)
+	}
+	result := make([]domain.Role, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, domain.Role{
+			Id:          role.Id,
+			Name:        role.Name,
+			Permissions: permMap[role.Id],
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+	"webook/internal/domain"
+	"webook/internal/repository/dao"
+)
+
+type AsyncSMSRepository interface {
+	Add(ctx context.Context, s domain.AsyncSMS) error
+	// Preempt 抢占到期的任务 lease内不会被其他worker抢到
+	Preempt(ctx context.Context, lease time.Duration, limit int) ([]domain.AsyncSMS, error)
+	MarkSuccess(ctx context.Context, id int) error
+	// MarkRetry nextRetry为零值表示不再重试
+	MarkRetry(ctx context.Context, id int, attempts int, lastErr string, nextRetry time.Time) error
+}
+
+type AsyncSMSDAORepository struct {
+	dao *dao.AsyncSMSDAO
+}
+
+func NewAsyncSMSDAORepository(dao *dao.AsyncSMSDAO) *AsyncSMSDAORepository {
+	return &AsyncSMSDAORepository{dao: dao}
+}
+
+func (r *AsyncSMSDAORepository) Add(ctx context.Context, s domain.AsyncSMS) error {
+	args, err := json.Marshal(s.Args)
+	if err != nil {
+		return err
+	}
+	numbers, err := json.Marshal(s.Numbers)
+	if err != nil {
+		return err
+	}
+	return r.dao.Insert(ctx, dao.AsyncSMS{
+		Biz:         s.Biz,
+		Args:        string(args),
+		Numbers:     string(numbers),
+		Status:      dao.AsyncSMSStatusWaiting,
+		Attempts:    s.Attempts,
+		MaxAttempts: s.MaxAttempts,
+		LastErr:     s.LastErr,
+		NextRetry:   s.NextRetry.UnixMilli(),
+	})
+}
+
+func (r *AsyncSMSDAORepository) Preempt(ctx context.Context, lease time.Duration, limit int) ([]domain.AsyncSMS, error) {
+	entities, err := r.dao.Preempt(ctx, time.Now().UnixMilli(), lease, limit)
+	res := make([]domain.AsyncSMS, 0, len(entities))
+	for _, e := range entities {
+		s, er := r.toDomain(e)
+		if er != nil {
+			//数据坏了 不可能发成功
+			_ = r.dao.MarkRetry(ctx, e.Id, e.Attempts, er.Error(), 0)
+			continue
+		}
+		res = append(res, s)
+	}
+	return res, err
+}
+
+func (r *AsyncSMSDAORepository) MarkSuccess(ctx context.Context, id int) error {
+	return r.dao.MarkSuccess(ctx, id)
+}
+
+func (r *AsyncSMSDAORepository) MarkRetry(ctx context.Context, id int, attempts int, lastErr string, nextRetry time.Time) error {
+	var next int64
+	if !nextRetry.IsZero() {
+		next = nextRetry.UnixMilli()
+	}
+	//列的长度有限 按字符截断
+	if utf8.RuneCountInString(lastErr) > 512 {
+		lastErr = string([]rune(lastErr)[:512])
+	}
+	return r.dao.MarkRetry(ctx, id, attempts, lastErr, next)
+}
+
+func (r *AsyncSMSDAORepository) toDomain(e dao.AsyncSMS) (domain.AsyncSMS, error) {
+	s := domain.AsyncSMS{
+		Id:          e.Id,
+		Biz:         e.Biz,
+		Attempts:    e.Attempts,
+		MaxAttempts: e.MaxAttempts,
+		LastErr:     e.LastErr,
+		NextRetry:   time.UnixMilli(e.NextRetry),
+	}
+	err := json.Unmarshal([]byte(e.Args), &s.Args)
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal([]byte(e.Numbers), &s.Numbers)
+	return s, err
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 	"webook/internal/domain"
 	"webook/internal/repository/cache"
 	"webook/internal/repository/dao"
@@ -13,8 +14,21 @@ type UserRepository interface {
 	FindById(ctx context.Context, user domain.User) (domain.User, error)
 	FindByEmail(ctx context.Context, user domain.User) (domain.User, error)
 	FindByPhone(ctx context.Context, user domain.User) (domain.User, error)
+	// Update 按id更新密码
 	Update(ctx context.Context, user domain.User) error
+	// UpdateLoginIdentity 更新手机号 邮箱 微信 为空表示解绑
+	UpdateLoginIdentity(ctx context.Context, user domain.User) error
+	// UpdateProfile 更新昵称 生日 简介 头像
+	UpdateProfile(ctx context.Context, user domain.User) error
+	// MarkEmailVerified email必须还是用户当前的邮箱
+	MarkEmailVerified(ctx context.Context, id int, email string) error
 	FindByWechat(ctx context.Context, openId string) (domain.User, error)
+	// UpdateDeleteAt 申请注销 deleteAt为零值表示撤销
+	UpdateDeleteAt(ctx context.Context, id int, deleteAt time.Time) error
+	// FindDeleteDue 冷静期已经过了的用户id
+	FindDeleteDue(ctx context.Context, now time.Time, limit int) ([]int, error)
+	// Purge 彻底删除用户 冷静期内撤销了注销时返回ErrUserNotFind
+	Purge(ctx context.Context, id int, now time.Time) error
 }
 
 type UserCacheRepository struct {
@@ -23,7 +37,8 @@ type UserCacheRepository struct {
 }
 
 var (
-	ErrUserNotFind = dao.ErrUserNotFind
+	ErrUserNotFind   = dao.ErrUserNotFind
+	ErrUserDuplicate = dao.ErrUserDuplicate
 )
 
 func NewUserCacheRepository(dao *dao.UserDAO, userCache cache.UserCache) *UserCacheRepository {
@@ -52,7 +67,7 @@ func (r *UserCacheRepository) FindById(ctx context.Context, user domain.User) (d
 		return u, err
 	}
 	//再从dao里面找
-	userDb, err := r.dao.FindById(ctx, u.Id)
+	userDb, err := r.dao.FindById(ctx, user.Id)
 	if err != nil {
 		return domain.User{}, err
 	}
@@ -60,13 +75,13 @@ func (r *UserCacheRepository) FindById(ctx context.Context, user domain.User) (d
 
 	//找到了回写cache
 	go func() {
-		err = r.cache.Set(ctx, u)
-		if err != nil {
+		er := r.cache.Set(ctx, u)
+		if er != nil {
 			//打日志,做监控
 		}
 	}()
 
-	return domain.User{}, nil
+	return u, nil
 }
 
 func (r *UserCacheRepository) FindByEmail(ctx context.Context, user domain.User) (domain.User, error) {
@@ -86,16 +101,56 @@ func (r *UserCacheRepository) FindByPhone(ctx context.Context, user domain.User)
 }
 
 func (r *UserCacheRepository) Update(ctx context.Context, user domain.User) error {
-	result, err := r.dao.FindByEmail(ctx, user.Email)
+	err := r.dao.UpdatePassword(ctx, user.Id, user.Password)
+	if err != nil {
+		return err
+	}
+	//缓存里也有密码 必须删掉
+	return r.cache.Delete(ctx, user.Id)
+}
+
+func (r *UserCacheRepository) UpdateLoginIdentity(ctx context.Context, user domain.User) error {
+	err := r.dao.UpdateLoginIdentity(ctx, r.domainToEntity(user))
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, user.Id)
+}
+
+func (r *UserCacheRepository) UpdateProfile(ctx context.Context, user domain.User) error {
+	err := r.dao.UpdateProfile(ctx, r.domainToEntity(user))
 	if err != nil {
 		return err
 	}
-	result.Password = user.Password
-	err = r.dao.Update(ctx, result)
+	return r.cache.Delete(ctx, user.Id)
+}
+
+func (r *UserCacheRepository) MarkEmailVerified(ctx context.Context, id int, email string) error {
+	err := r.dao.UpdateEmailVerified(ctx, id, email)
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, id)
+}
+
+func (r *UserCacheRepository) UpdateDeleteAt(ctx context.Context, id int, deleteAt time.Time) error {
+	err := r.dao.UpdateDeleteAt(ctx, id, msToEntity(deleteAt))
 	if err != nil {
 		return err
 	}
-	return err
+	return r.cache.Delete(ctx, id)
+}
+
+func (r *UserCacheRepository) FindDeleteDue(ctx context.Context, now time.Time, limit int) ([]int, error) {
+	return r.dao.FindDeleteDue(ctx, now.UnixMilli(), limit)
+}
+
+func (r *UserCacheRepository) Purge(ctx context.Context, id int, now time.Time) error {
+	err := r.dao.Purge(ctx, id, now.UnixMilli())
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, id)
 }
 
 func (r *UserCacheRepository) entityToDomain(ud dao.User) domain.User {
@@ -105,9 +160,16 @@ func (r *UserCacheRepository) entityToDomain(ud dao.User) domain.User {
 		Phone: ud.Phone.String,
 		WechatInfo: domain.WechatInfo{
 			OpenID:  ud.WechatOpenID.String,
-			UnionID: ud.WechatOpenID.String,
+			UnionID: ud.WechatUnionID.String,
 		},
-		Password: ud.Password,
+		Password:      ud.Password,
+		EmailVerified: ud.EmailVerified,
+		Nickname:      ud.Nickname,
+		Birthday:      birthdayToDomain(ud.Birthday),
+		AboutMe:       ud.AboutMe,
+		Avatar:        ud.Avatar,
+		DeleteAt:      msToDomain(ud.DeleteAt),
+		Ctime:         time.UnixMilli(ud.Ctime),
 	}
 }
 
@@ -122,7 +184,16 @@ func (r *UserCacheRepository) domainToEntity(ud domain.User) dao.User {
 			String: ud.Phone,
 			Valid:  ud.Phone != "",
 		},
-		Password: ud.Password,
+		Password:      ud.Password,
+		EmailVerified: ud.EmailVerified,
+		Nickname:      ud.Nickname,
+		Birthday: sql.NullInt64{
+			Int64: ud.Birthday.UnixMilli(),
+			Valid: !ud.Birthday.IsZero(),
+		},
+		AboutMe:  ud.AboutMe,
+		Avatar:   ud.Avatar,
+		DeleteAt: msToEntity(ud.DeleteAt),
 		WechatOpenID: sql.NullString{
 			String: ud.WechatInfo.OpenID,
 			Valid:  ud.WechatInfo.OpenID != "",
@@ -133,3 +204,24 @@ func (r *UserCacheRepository) domainToEntity(ud domain.User) dao.User {
 		},
 	}
 }
+
+func birthdayToDomain(birthday sql.NullInt64) time.Time {
+	if !birthday.Valid {
+		return time.Time{}
+	}
+	return time.UnixMilli(birthday.Int64).UTC()
+}
+
+func msToDomain(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+func msToEntity(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
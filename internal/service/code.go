@@ -8,9 +8,6 @@ import (
 	"webook/internal/service/sms"
 )
 
-// TODO
-const codeTplId = ""
-
 var (
 	ErrCodeSendTooMany = repository.ErrCodeSendTooMany
 )
@@ -34,14 +31,15 @@ func (svc *CodeDevService) Send(ctx context.Context, biz string, phone string) e
 
 	//两个步骤
 	//1.生成一个验证码
-	code := svc.generateCode()
+	code := generateCode()
 	//2.加入redis
 	err := svc.repo.Store(ctx, biz, phone, code)
 	if err != nil {
 		return err
 	}
 	//发送
-	err = svc.smsSvc.Send(ctx, codeTplId, []string{code}, phone)
+	//biz对应的模板在配置里 SMS.Templates
+	err = svc.smsSvc.Send(ctx, biz, []string{code}, phone)
 	if err != nil {
 		//若出错,不可以删除redis中的验证码
 		//因为err可能是超时的err,无法知晓是否发送成功
@@ -55,7 +53,8 @@ func (svc *CodeDevService) Verify(ctx context.Context, biz string, phone string,
 	return svc.repo.Verify(ctx, biz, phone, inputCode)
 }
 
-func (svc *CodeDevService) generateCode() string {
+// generateCode 6位数字验证码
+func generateCode() string {
 	num := rand.Intn(1000000)
 	return fmt.Sprintf("%06d", num)
 }
@@ -0,0 +1,40 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"webook/internal/service/email"
+)
+
+var ErrAllFailed = errors.New("全部服务都失败了")
+
+// Service 轮询起始的服务商 失败了就换下一个
+type Service struct {
+	services []email.Service
+	idx      uint64
+}
+
+func NewService(services []email.Service) *Service {
+	return &Service{services: services}
+}
+
+func (s *Service) Send(ctx context.Context, msg email.Message) error {
+	idx := atomic.AddUint64(&s.idx, 1)
+	length := uint64(len(s.services))
+	for i := idx; i < idx+length; i++ {
+		svc := s.services[int(i%length)]
+		err := svc.Send(ctx, msg)
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+			//调用方已经不等了 换下一个也没用
+			return err
+		default:
+			log.Printf("发送邮件失败,换下一个服务商,err:%v", err)
+		}
+	}
+	return ErrAllFailed
+}
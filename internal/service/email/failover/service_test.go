@@ -0,0 +1,77 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"webook/internal/service/email"
+	emailmocks "webook/internal/service/email/mocks"
+)
+
+func TestService_Send(t *testing.T) {
+	//smtp客户端会把超时包一层再返回
+	wrappedTimeout := fmt.Errorf("连接smtp服务器失败,%w", context.DeadlineExceeded)
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) []email.Service
+
+		wantErr error
+	}{
+		{
+			name: "第一个失败 第二个成功",
+			mock: func(ctrl *gomock.Controller) []email.Service {
+				svc0 := emailmocks.NewMockService(ctrl)
+				svc1 := emailmocks.NewMockService(ctrl)
+				//idx从1开始
+				svc1.EXPECT().Send(gomock.Any(), gomock.Any()).Return(errors.New("发送失败"))
+				svc0.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil)
+				return []email.Service{svc0, svc1}
+			},
+		},
+		{
+			name: "全部失败",
+			mock: func(ctrl *gomock.Controller) []email.Service {
+				svc0 := emailmocks.NewMockService(ctrl)
+				svc1 := emailmocks.NewMockService(ctrl)
+				svc1.EXPECT().Send(gomock.Any(), gomock.Any()).Return(errors.New("发送失败"))
+				svc0.EXPECT().Send(gomock.Any(), gomock.Any()).Return(errors.New("发送失败"))
+				return []email.Service{svc0, svc1}
+			},
+			wantErr: ErrAllFailed,
+		},
+		{
+			name: "超时不再重试",
+			mock: func(ctrl *gomock.Controller) []email.Service {
+				svc0 := emailmocks.NewMockService(ctrl)
+				svc1 := emailmocks.NewMockService(ctrl)
+				svc1.EXPECT().Send(gomock.Any(), gomock.Any()).Return(context.DeadlineExceeded)
+				return []email.Service{svc0, svc1}
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "包装过的超时也不再重试",
+			mock: func(ctrl *gomock.Controller) []email.Service {
+				svc0 := emailmocks.NewMockService(ctrl)
+				svc1 := emailmocks.NewMockService(ctrl)
+				svc1.EXPECT().Send(gomock.Any(), gomock.Any()).Return(wrappedTimeout)
+				return []email.Service{svc0, svc1}
+			},
+			wantErr: wrappedTimeout,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := NewService(tc.mock(ctrl))
+			err := svc.Send(context.Background(), email.Message{To: []string{"123@qq.com"}, Text: "hi"})
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
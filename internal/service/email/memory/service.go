@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"webook/internal/service/email"
+)
+
+// Service 不真正发送 只打印并记录下来 给开发环境和测试用
+type Service struct {
+	lock sync.Mutex
+	sent []email.Message
+}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+func (s *Service) Send(ctx context.Context, msg email.Message) error {
+	fmt.Println(msg.To, msg.Subject, msg.Text)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+// Sent 已经发送的邮件
+func (s *Service) Sent() []email.Message {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	res := make([]email.Message, len(s.sent))
+	copy(res, s.sent)
+	return res
+}
@@ -0,0 +1,54 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/email/types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/service/email/types.go -package=emailmocks -destination=internal/service/email/mocks/service_gen.go
+//
+// Package emailmocks is a generated GoMock package.
+package emailmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	email "webook/internal/service/email"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockService is a mock of Service interface.
+type MockService struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceMockRecorder
+}
+
+// MockServiceMockRecorder is the mock recorder for MockService.
+type MockServiceMockRecorder struct {
+	mock *MockService
+}
+
+// NewMockService creates a new mock instance.
+func NewMockService(ctrl *gomock.Controller) *MockService {
+	mock := &MockService{ctrl: ctrl}
+	mock.recorder = &MockServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockService) EXPECT() *MockServiceMockRecorder {
+	return m.recorder
+}
+
+// Send mocks base method.
+func (m *MockService) Send(ctx context.Context, msg email.Message) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", ctx, msg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockServiceMockRecorder) Send(ctx, msg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockService)(nil).Send), ctx, msg)
+}
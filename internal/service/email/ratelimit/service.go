@@ -0,0 +1,31 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"webook/internal/service/email"
+	"webook/pkg/ratelimit"
+)
+
+var ErrLimited = errors.New("触发了限流")
+
+type Service struct {
+	svc     email.Service
+	limiter ratelimit.Limiter
+}
+
+func NewService(svc email.Service, limiter ratelimit.Limiter) *Service {
+	return &Service{svc: svc, limiter: limiter}
+}
+
+func (s *Service) Send(ctx context.Context, msg email.Message) error {
+	limited, err := s.limiter.Limit(ctx, "email:send")
+	if err != nil {
+		return fmt.Errorf("邮件服务判断是否限流出现问题,%w", err)
+	}
+	if limited {
+		return ErrLimited
+	}
+	return s.svc.Send(ctx, msg)
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"webook/internal/service/email"
+	emailmocks "webook/internal/service/email/mocks"
+	pkgmocks "webook/pkg/mocks"
+	"webook/pkg/ratelimit"
+)
+
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (email.Service, ratelimit.Limiter)
+
+		wantErr error
+	}{
+		{
+			name: "没有限流",
+			mock: func(ctrl *gomock.Controller) (email.Service, ratelimit.Limiter) {
+				limiter := pkgmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "email:send").Return(false, nil)
+				svc := emailmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil)
+				return svc, limiter
+			},
+		},
+		{
+			name: "触发限流 不发送",
+			mock: func(ctrl *gomock.Controller) (email.Service, ratelimit.Limiter) {
+				limiter := pkgmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "email:send").Return(true, nil)
+				return emailmocks.NewMockService(ctrl), limiter
+			},
+			wantErr: ErrLimited,
+		},
+		{
+			name: "限流器出错 不发送",
+			mock: func(ctrl *gomock.Controller) (email.Service, ratelimit.Limiter) {
+				limiter := pkgmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "email:send").Return(false, errors.New("redis出错"))
+				return emailmocks.NewMockService(ctrl), limiter
+			},
+			wantErr: errors.New("邮件服务判断是否限流出现问题,redis出错"),
+		},
+		{
+			name: "发送失败",
+			mock: func(ctrl *gomock.Controller) (email.Service, ratelimit.Limiter) {
+				limiter := pkgmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "email:send").Return(false, nil)
+				svc := emailmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), gomock.Any()).Return(errors.New("发送失败"))
+				return svc, limiter
+			},
+			wantErr: errors.New("发送失败"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := NewService(tc.mock(ctrl))
+			err := svc.Send(context.Background(), email.Message{To: []string{"123@qq.com"}, Text: "hi"})
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.wantErr.Error())
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+	"webook/internal/service/email"
+)
+
+var ErrInvalidMessage = errors.New("邮件格式错误")
+
+// defaultTimeout ctx没有deadline时 连接加整个SMTP交互最多这么久 服务器卡住时不能一直等
+const defaultTimeout = time.Second * 10
+
+// Service 通过SMTP发送 465端口用隐式TLS 其他端口服务器支持时用STARTTLS
+type Service struct {
+	host string
+	port int
+	auth smtp.Auth
+	from mail.Address
+	//ctx没有deadline时使用
+	timeout time.Duration
+}
+
+// NewService username为空时不做认证
+func NewService(host string, port int, username string, password string, from string) (*Service, error) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("发件人地址错误,%w", err)
+	}
+	s := &Service{
+		host:    host,
+		port:    port,
+		from:    *addr,
+		timeout: defaultTimeout,
+	}
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s, nil
+}
+
+func (s *Service) Send(ctx context.Context, msg email.Message) error {
+	to, err := parseAddressList(msg.To)
+	if err != nil {
+		return err
+	}
+	data, err := buildMessage(s.from, to, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	//net/smtp不支持ctx 用deadline兜底
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(s.timeout)
+	}
+	conn, err := s.dial(ctx, deadline)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if s.port != 465 {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			err = c.StartTLS(&tls.Config{ServerName: s.host})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if s.auth != nil {
+		err = c.Auth(s.auth)
+		if err != nil {
+			return err
+		}
+	}
+	err = c.Mail(s.from.Address)
+	if err != nil {
+		return err
+	}
+	for _, addr := range to {
+		err = c.Rcpt(addr.Address)
+		if err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (s *Service) dial(ctx context.Context, deadline time.Time) (net.Conn, error) {
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	dialer := &net.Dialer{Deadline: deadline}
+	if s.port == 465 {
+		td := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: s.host}}
+		return td.DialContext(ctx, "tcp", addr)
+	}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+func parseAddressList(list []string) ([]*mail.Address, error) {
+	if len(list) == 0 {
+		return nil, fmt.Errorf("%w,没有收件人", ErrInvalidMessage)
+	}
+	res := make([]*mail.Address, 0, len(list))
+	for _, s := range list {
+		addr, err := mail.ParseAddress(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w,收件人%q,%v", ErrInvalidMessage, s, err)
+		}
+		res = append(res, addr)
+	}
+	return res, nil
+}
+
+// buildMessage 组装RFC 5322格式的邮件 同时有纯文本和HTML时用multipart/alternative
+func buildMessage(from mail.Address, to []*mail.Address, msg email.Message, now time.Time) ([]byte, error) {
+	if msg.Text == "" && msg.HTML == "" {
+		return nil, fmt.Errorf("%w,没有正文", ErrInvalidMessage)
+	}
+	//防止邮件头注入
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, fmt.Errorf("%w,标题不能换行", ErrInvalidMessage)
+	}
+
+	var buf bytes.Buffer
+	recipients := make([]string, 0, len(to))
+	for _, addr := range to {
+		recipients = append(recipients, addr.String())
+	}
+	writeHeader(&buf, "From", from.String())
+	writeHeader(&buf, "To", strings.Join(recipients, ", "))
+	writeHeader(&buf, "Subject", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	writeHeader(&buf, "Date", now.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", messageId(from))
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	if msg.Text == "" || msg.HTML == "" {
+		contentType, body := "text/plain", msg.Text
+		if msg.HTML != "" {
+			contentType, body = "text/html", msg.HTML
+		}
+		writeHeader(&buf, "Content-Type", contentType+"; charset=UTF-8")
+		writeHeader(&buf, "Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		err := writeQuotedPrintable(&buf, body)
+		return buf.Bytes(), err
+	}
+
+	mw := multipart.NewWriter(&buf)
+	writeHeader(&buf, "Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+	//按照规范 越靠后的越优先 所以HTML放在最后
+	for _, part := range []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = writeQuotedPrintable(w, part.body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := mw.Close()
+	return buf.Bytes(), err
+}
+
+func writeHeader(buf *bytes.Buffer, key string, value string) {
+	buf.WriteString(key)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qw := quotedprintable.NewWriter(w)
+	_, err := qw.Write([]byte(body))
+	if err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+func messageId(from mail.Address) string {
+	buf := make([]byte, 12)
+	_, _ = rand.Read(buf)
+	domain := "webook"
+	if _, d, ok := strings.Cut(from.Address, "@"); ok {
+		domain = d
+	}
+	return "<" + hex.EncodeToString(buf) + "@" + domain + ">"
+}
@@ -0,0 +1,99 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+	"webook/internal/service/email"
+)
+
+func TestBuildMessage(t *testing.T) {
+	from := mail.Address{Name: "webook", Address: "noreply@webook.com"}
+	to := []*mail.Address{{Address: "123@qq.com"}}
+
+	t.Run("纯文本和HTML", func(t *testing.T) {
+		data, err := buildMessage(from, to, email.Message{
+			Subject: "重置密码",
+			Text:    "你好",
+			HTML:    "<p>你好</p>",
+		}, time.Now())
+		require.NoError(t, err)
+
+		m, err := mail.ReadMessage(strings.NewReader(string(data)))
+		require.NoError(t, err)
+		subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+		require.NoError(t, err)
+		assert.Equal(t, "重置密码", subject)
+		assert.Equal(t, "<123@qq.com>", m.Header.Get("To"))
+
+		mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		assert.Equal(t, "multipart/alternative", mediaType)
+		mr := multipart.NewReader(m.Body, params["boundary"])
+		var bodies []string
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			body, err := io.ReadAll(p)
+			require.NoError(t, err)
+			bodies = append(bodies, string(body))
+		}
+		assert.Equal(t, []string{"你好", "<p>你好</p>"}, bodies)
+	})
+
+	t.Run("标题注入邮件头", func(t *testing.T) {
+		_, err := buildMessage(from, to, email.Message{
+			Subject: "hi\r\nBcc: evil@example.com",
+			Text:    "你好",
+		}, time.Now())
+		assert.ErrorIs(t, err, ErrInvalidMessage)
+	})
+
+	t.Run("没有正文", func(t *testing.T) {
+		_, err := buildMessage(from, to, email.Message{Subject: "hi"}, time.Now())
+		assert.ErrorIs(t, err, ErrInvalidMessage)
+	})
+}
+
+func TestService_Send_Timeout(t *testing.T) {
+	//只接受连接 不发欢迎语 模拟卡住的服务器
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	addr := l.Addr().(*net.TCPAddr)
+	svc, err := NewService("127.0.0.1", addr.Port, "", "", "noreply@webook.com")
+	require.NoError(t, err)
+	svc.timeout = time.Millisecond * 100
+	start := time.Now()
+	err = svc.Send(context.Background(), email.Message{
+		To:      []string{"123@qq.com"},
+		Subject: "hello",
+		Text:    "hello",
+	})
+	var ne net.Error
+	require.True(t, errors.As(err, &ne))
+	assert.True(t, ne.Timeout())
+	assert.Less(t, time.Since(start), time.Second)
+}
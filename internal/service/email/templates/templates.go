@@ -0,0 +1,109 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"webook/internal/service/email"
+)
+
+// 内置的邮件模板
+// 每个模板由 名字.txt 和可选的 名字.html 组成
+// txt里必须用 {{define "subject"}} 定义标题
+//
+//go:embed tpl
+var defaultFS embed.FS
+
+var ErrTemplateNotFind = errors.New("邮件模板不存在")
+
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// NewDefaultTemplates 内置模板
+func NewDefaultTemplates() (*Templates, error) {
+	fsys, err := fs.Sub(defaultFS, "tpl")
+	if err != nil {
+		return nil, err
+	}
+	return NewTemplates(fsys)
+}
+
+// NewTemplates 加载fsys根目录下的所有模板
+func NewTemplates(fsys fs.FS) (*Templates, error) {
+	t := &Templates{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		file := entry.Name()
+		name := strings.TrimSuffix(file, path.Ext(file))
+		switch path.Ext(file) {
+		case ".txt":
+			tpl, err := texttemplate.ParseFS(fsys, file)
+			if err != nil {
+				return nil, err
+			}
+			if tpl.Lookup("subject") == nil {
+				return nil, fmt.Errorf("模板%s没有定义subject", file)
+			}
+			t.text[name] = tpl
+		case ".html":
+			tpl, err := htmltemplate.ParseFS(fsys, file)
+			if err != nil {
+				return nil, err
+			}
+			t.html[name] = tpl
+		}
+	}
+	for name := range t.html {
+		if _, ok := t.text[name]; !ok {
+			return nil, fmt.Errorf("模板%s缺少纯文本版本", name)
+		}
+	}
+	return t, nil
+}
+
+// Render 渲染出标题和正文 收件人由调用方填
+func (t *Templates) Render(name string, data any) (email.Message, error) {
+	textTpl, ok := t.text[name]
+	if !ok {
+		return email.Message{}, fmt.Errorf("%w,%s", ErrTemplateNotFind, name)
+	}
+	var subject, text bytes.Buffer
+	err := textTpl.ExecuteTemplate(&subject, "subject", data)
+	if err != nil {
+		return email.Message{}, err
+	}
+	err = textTpl.Execute(&text, data)
+	if err != nil {
+		return email.Message{}, err
+	}
+	msg := email.Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()),
+	}
+	if htmlTpl, ok := t.html[name]; ok {
+		var html bytes.Buffer
+		err = htmlTpl.Execute(&html, data)
+		if err != nil {
+			return email.Message{}, err
+		}
+		msg.HTML = html.String()
+	}
+	return msg, nil
+}
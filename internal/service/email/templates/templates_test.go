@@ -0,0 +1,63 @@
+package templates
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTemplates_Render(t *testing.T) {
+	tpls, err := NewDefaultTemplates()
+	require.NoError(t, err)
+
+	msg, err := tpls.Render("reset_password", map[string]any{
+		"Link":    "https://webook.com/reset_password?token=a&b",
+		"Minutes": 30,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "重置webook密码", msg.Subject)
+	assert.Contains(t, msg.Text, "https://webook.com/reset_password?token=a&b")
+	//HTML里要转义
+	assert.Contains(t, msg.HTML, "https://webook.com/reset_password?token=a&amp;b")
+
+	_, err = tpls.Render("not_exist", nil)
+	assert.ErrorIs(t, err, ErrTemplateNotFind)
+}
+
+func TestNewTemplates(t *testing.T) {
+	testCases := []struct {
+		name string
+		fsys fstest.MapFS
+
+		wantErr bool
+	}{
+		{
+			name: "只有纯文本",
+			fsys: fstest.MapFS{
+				"hello.txt": {Data: []byte(`{{define "subject"}}hi{{end}}hello`)},
+			},
+		},
+		{
+			name: "没有定义标题",
+			fsys: fstest.MapFS{
+				"hello.txt": {Data: []byte(`hello`)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "只有HTML",
+			fsys: fstest.MapFS{
+				"hello.html": {Data: []byte(`<p>hello</p>`)},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewTemplates(tc.fsys)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
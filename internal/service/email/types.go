@@ -0,0 +1,17 @@
+package email
+
+import "context"
+
+type Service interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Message 一封邮件 HTML和Text至少有一个 两个都有时客户端自己选择展示哪个
+type Message struct {
+	To      []string
+	Subject string
+	//纯文本正文
+	Text string
+	//HTML正文
+	HTML string
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	"webook/internal/service/email"
+	"webook/internal/service/email/templates"
+	"webook/pkg/ratelimit"
+)
+
+const (
+	//验证链接的有效期
+	verifyTokenExpiration = time.Hour * 24
+	bindEmailBiz          = "bind_email"
+)
+
+var (
+	ErrVerifyTokenInvalid = errors.New("验证链接无效或已过期")
+	ErrVerifySendTooMany  = errors.New("验证邮件发送太频繁")
+	ErrEmailNotVerified   = errors.New("邮箱还没有验证")
+)
+
+// EmailVerifyService 注册后验证邮箱
+type EmailVerifyService interface {
+	// SendVerifyEmail 发送验证链接 邮箱没有注册或者已经验证过时也返回成功 防止被用来探测邮箱
+	SendVerifyEmail(ctx context.Context, email string) error
+	// Verify 验证链接里的token
+	Verify(ctx context.Context, token string) error
+	// CheckLogin 按照配置的策略 判断邮箱没验证的用户能不能用邮箱密码登录
+	CheckLogin(u domain.User) error
+	// SendBindCode 绑定邮箱前发送验证码到新邮箱
+	SendBindCode(ctx context.Context, email string) error
+	// VerifyBindCode 验证码正确说明邮箱是用户自己的
+	VerifyBindCode(ctx context.Context, email string, code string) (bool, error)
+}
+
+type EmailVerifyDevService struct {
+	repo     repository.UserRepository
+	codeRepo repository.CodeRepository
+	emailSvc email.Service
+	tpls     *templates.Templates
+	//按邮箱限制发送频率
+	limiter ratelimit.Limiter
+	tokens  signedToken
+	//验证页面的地址 后面拼接token
+	linkURL string
+	//是否允许邮箱没验证的用户登录
+	allowUnverifiedLogin bool
+}
+
+func NewEmailVerifyDevService(repo repository.UserRepository, codeRepo repository.CodeRepository,
+	emailSvc email.Service, tpls *templates.Templates, limiter ratelimit.Limiter, secret []byte, linkURL string,
+	allowUnverifiedLogin bool) *EmailVerifyDevService {
+	return &EmailVerifyDevService{
+		repo:                 repo,
+		codeRepo:             codeRepo,
+		emailSvc:             emailSvc,
+		tpls:                 tpls,
+		limiter:              limiter,
+		tokens:               signedToken{secret: secret, purpose: "verify_email"},
+		linkURL:              linkURL,
+		allowUnverifiedLogin: allowUnverifiedLogin,
+	}
+}
+
+func (svc *EmailVerifyDevService) SendVerifyEmail(ctx context.Context, addr string) error {
+	limited, err := svc.limiter.Limit(ctx, "email_verify:"+addr)
+	if err != nil {
+		return fmt.Errorf("判断是否限流出现问题,%w", err)
+	}
+	if limited {
+		return ErrVerifySendTooMany
+	}
+	u, err := svc.repo.FindByEmail(ctx, domain.User{Email: addr})
+	if errors.Is(err, ErrUserNotFind) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if u.EmailVerified {
+		return nil
+	}
+	msg, err := svc.tpls.Render("verify_email", map[string]any{
+		//邮箱改了之后 之前的链接就失效了
+		"Link":  svc.linkURL + svc.tokens.sign(u.Id, time.Now().Add(verifyTokenExpiration), u.Email),
+		"Hours": int(verifyTokenExpiration.Hours()),
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = []string{u.Email}
+	return svc.emailSvc.Send(ctx, msg)
+}
+
+func (svc *EmailVerifyDevService) Verify(ctx context.Context, token string) error {
+	uid, err := svc.tokens.parse(token)
+	if err != nil {
+		return ErrVerifyTokenInvalid
+	}
+	u, err := svc.repo.FindById(ctx, domain.User{Id: uid})
+	if errors.Is(err, ErrUserNotFind) {
+		return ErrVerifyTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+	if !svc.tokens.verify(token, u.Email) {
+		return ErrVerifyTokenInvalid
+	}
+	if u.EmailVerified {
+		return nil
+	}
+	err = svc.repo.MarkEmailVerified(ctx, u.Id, u.Email)
+	if errors.Is(err, ErrUserNotFind) {
+		return ErrVerifyTokenInvalid
+	}
+	return err
+}
+
+func (svc *EmailVerifyDevService) CheckLogin(u domain.User) error {
+	if u.Email == "" || u.EmailVerified || svc.allowUnverifiedLogin {
+		return nil
+	}
+	return ErrEmailNotVerified
+}
+
+func (svc *EmailVerifyDevService) SendBindCode(ctx context.Context, addr string) error {
+	code := generateCode()
+	err := svc.codeRepo.Store(ctx, bindEmailBiz, addr, code)
+	if err != nil {
+		return err
+	}
+	msg, err := svc.tpls.Render("bind_email", map[string]any{
+		"Code": code,
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = []string{addr}
+	return svc.emailSvc.Send(ctx, msg)
+}
+
+func (svc *EmailVerifyDevService) VerifyBindCode(ctx context.Context, addr string, code string) (bool, error) {
+	return svc.codeRepo.Verify(ctx, bindEmailBiz, addr, code)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	repomocks "webook/internal/repository/mocks"
+)
+
+func TestEmailVerifyDevService_Verify(t *testing.T) {
+	tokens := signedToken{secret: []byte("secret"), purpose: "verify_email"}
+	token := tokens.sign(1, time.Now().Add(time.Minute), "123@qq.com")
+
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		token string
+
+		wantErr error
+	}{
+		{
+			name: "验证成功",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				repo.EXPECT().MarkEmailVerified(gomock.Any(), 1, "123@qq.com").Return(nil)
+				return repo
+			},
+			token: token,
+		},
+		{
+			name: "已经验证过",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "123@qq.com", EmailVerified: true}, nil)
+				return repo
+			},
+			token: token,
+		},
+		{
+			name: "邮箱已经改了",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "456@qq.com"}, nil)
+				return repo
+			},
+			token:   token,
+			wantErr: ErrVerifyTokenInvalid,
+		},
+		{
+			name: "重置密码的token不能用来验证邮箱",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				return repo
+			},
+			token: signedToken{secret: []byte("secret"), purpose: "reset_password"}.
+				sign(1, time.Now().Add(time.Minute), "123@qq.com"),
+			wantErr: ErrVerifyTokenInvalid,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := NewEmailVerifyDevService(tc.mock(ctrl), nil, nil, nil, nil, []byte("secret"), "", false)
+			err := svc.Verify(context.Background(), tc.token)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestEmailVerifyDevService_CheckLogin(t *testing.T) {
+	deny := NewEmailVerifyDevService(nil, nil, nil, nil, nil, nil, "", false)
+	assert.Equal(t, ErrEmailNotVerified, deny.CheckLogin(domain.User{Email: "123@qq.com"}))
+	assert.NoError(t, deny.CheckLogin(domain.User{Email: "123@qq.com", EmailVerified: true}))
+	//手机号用户没有邮箱
+	assert.NoError(t, deny.CheckLogin(domain.User{Phone: "186xxx"}))
+
+	allow := NewEmailVerifyDevService(nil, nil, nil, nil, nil, nil, "", true)
+	assert.NoError(t, allow.CheckLogin(domain.User{Email: "123@qq.com"}))
+}
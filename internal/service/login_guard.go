@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	"webook/internal/service/email"
+	"webook/internal/service/email/templates"
+	"webook/pkg/ratelimit"
+)
+
+const (
+	unlockLoginBiz = "unlock_login"
+	//失败次数的统计窗口 注入的ratelimit.Counter要用同样的窗口
+	LoginFailWindow = time.Minute * 30
+	//同一个账号失败这么多次之后开始要求等待 每多失败一次等待时间翻倍
+	loginDelayAfter = 3
+	loginDelayBase  = time.Second
+	loginDelayMax   = time.Minute
+	//同一个账号失败这么多次之后锁定
+	loginLockAfter = 10
+	loginLockTime  = time.Minute * 30
+	//同一个IP失败这么多次之后 这个IP在窗口内不能再尝试
+	loginIPLimit = 100
+)
+
+var (
+	ErrAccountLocked     = errors.New("账号已被临时锁定")
+	ErrLoginTooFrequent  = errors.New("登录失败次数太多,请稍后再试")
+	ErrUnlockCodeInvalid = errors.New("验证码错误")
+)
+
+// UnlockChannel 接收解锁验证码的方式
+type UnlockChannel string
+
+const (
+	UnlockBySMS   UnlockChannel = "sms"
+	UnlockByEmail UnlockChannel = "email"
+)
+
+// LoginGuardService 密码登录的防暴力破解 按账号和IP分别统计失败次数
+type LoginGuardService interface {
+	// Check 校验密码之前调用 返回ErrAccountLocked或者ErrLoginTooFrequent时 wait为需要等待的时间
+	Check(ctx context.Context, account string, ip string) (wait time.Duration, err error)
+	// Fail 密码错误之后调用 达到阈值时锁定账号并通知用户
+	Fail(ctx context.Context, account string, ip string) error
+	// Succeed 登录成功之后清空账号的失败次数
+	Succeed(ctx context.Context, account string) error
+	// SendUnlockCode 账号不存在或者没有锁定时也返回成功 防止被用来探测账号
+	SendUnlockCode(ctx context.Context, account string, channel UnlockChannel) error
+	Unlock(ctx context.Context, account string, channel UnlockChannel, code string) error
+}
+
+type LoginGuardDevService struct {
+	repo     repository.UserRepository
+	lockRepo repository.LoginLockRepository
+	counter  ratelimit.Counter
+	codeSvc  CodeService
+	codeRepo repository.CodeRepository
+	emailSvc email.Service
+	tpls     *templates.Templates
+}
+
+func NewLoginGuardDevService(repo repository.UserRepository, lockRepo repository.LoginLockRepository,
+	counter ratelimit.Counter, codeSvc CodeService, codeRepo repository.CodeRepository,
+	emailSvc email.Service, tpls *templates.Templates) *LoginGuardDevService {
+	return &LoginGuardDevService{
+		repo:     repo,
+		lockRepo: lockRepo,
+		counter:  counter,
+		codeSvc:  codeSvc,
+		codeRepo: codeRepo,
+		emailSvc: emailSvc,
+		tpls:     tpls,
+	}
+}
+
+func (svc *LoginGuardDevService) Check(ctx context.Context, account string, ip string) (time.Duration, error) {
+	account = normalizeAccount(account)
+	wait, err := svc.lockRepo.LockTTL(ctx, account)
+	if err != nil {
+		return 0, err
+	}
+	if wait > 0 {
+		return wait, ErrAccountLocked
+	}
+	wait, err = svc.lockRepo.DelayTTL(ctx, account)
+	if err != nil {
+		return 0, err
+	}
+	if wait > 0 {
+		return wait, ErrLoginTooFrequent
+	}
+	cnt, err := svc.counter.Count(ctx, svc.ipKey(ip))
+	if err != nil {
+		return 0, err
+	}
+	if cnt >= loginIPLimit {
+		//不知道最早的一次什么时候过期 按整个窗口算
+		return LoginFailWindow, ErrLoginTooFrequent
+	}
+	return 0, nil
+}
+
+func (svc *LoginGuardDevService) Fail(ctx context.Context, account string, ip string) error {
+	account = normalizeAccount(account)
+	_, err := svc.counter.Incr(ctx, svc.ipKey(ip))
+	if err != nil {
+		return err
+	}
+	cnt, err := svc.counter.Incr(ctx, svc.accountKey(account))
+	if err != nil {
+		return err
+	}
+	if cnt < loginLockAfter {
+		if cnt >= loginDelayAfter {
+			return svc.lockRepo.SetDelay(ctx, account, svc.delay(cnt))
+		}
+		return nil
+	}
+	ok, err := svc.lockRepo.Lock(ctx, account, loginLockTime)
+	if err != nil || !ok {
+		return err
+	}
+	//解锁之后重新计数
+	err = svc.counter.Reset(ctx, svc.accountKey(account))
+	if err != nil {
+		return err
+	}
+	err = svc.notifyLocked(ctx, account, ip)
+	if err != nil {
+		//已经锁定成功了 通知失败不影响
+		log.Printf("发送账号锁定通知失败,account:%s,err:%v", account, err)
+	}
+	return nil
+}
+
+func (svc *LoginGuardDevService) Succeed(ctx context.Context, account string) error {
+	account = normalizeAccount(account)
+	return svc.counter.Reset(ctx, svc.accountKey(account))
+}
+
+func (svc *LoginGuardDevService) SendUnlockCode(ctx context.Context, account string, channel UnlockChannel) error {
+	account = normalizeAccount(account)
+	ttl, err := svc.lockRepo.LockTTL(ctx, account)
+	if err != nil || ttl == 0 {
+		return err
+	}
+	u, err := svc.repo.FindByEmail(ctx, domain.User{Email: account})
+	if errors.Is(err, ErrUserNotFind) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	switch channel {
+	case UnlockBySMS:
+		if u.Phone == "" {
+			return nil
+		}
+		return svc.codeSvc.Send(ctx, unlockLoginBiz, u.Phone)
+	case UnlockByEmail:
+		code := generateCode()
+		err = svc.codeRepo.Store(ctx, unlockLoginBiz, u.Email, code)
+		if err != nil {
+			return err
+		}
+		msg, err := svc.tpls.Render("unlock_login", map[string]any{
+			"Code": code,
+		})
+		if err != nil {
+			return err
+		}
+		msg.To = []string{u.Email}
+		return svc.emailSvc.Send(ctx, msg)
+	default:
+		return nil
+	}
+}
+
+func (svc *LoginGuardDevService) Unlock(ctx context.Context, account string, channel UnlockChannel, code string) error {
+	account = normalizeAccount(account)
+	u, err := svc.repo.FindByEmail(ctx, domain.User{Email: account})
+	if errors.Is(err, ErrUserNotFind) {
+		return ErrUnlockCodeInvalid
+	}
+	if err != nil {
+		return err
+	}
+	var ok bool
+	switch channel {
+	case UnlockBySMS:
+		if u.Phone == "" {
+			return ErrUnlockCodeInvalid
+		}
+		ok, err = svc.codeSvc.Verify(ctx, unlockLoginBiz, u.Phone, code)
+	case UnlockByEmail:
+		ok, err = svc.codeRepo.Verify(ctx, unlockLoginBiz, u.Email, code)
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUnlockCodeInvalid
+	}
+	err = svc.lockRepo.Unlock(ctx, account)
+	if err != nil {
+		return err
+	}
+	return svc.counter.Reset(ctx, svc.accountKey(account))
+}
+
+// notifyLocked 账号不存在时不用通知
+func (svc *LoginGuardDevService) notifyLocked(ctx context.Context, account string, ip string) error {
+	u, err := svc.repo.FindByEmail(ctx, domain.User{Email: account})
+	if errors.Is(err, ErrUserNotFind) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	msg, err := svc.tpls.Render("account_locked", map[string]any{
+		"IP":      ip,
+		"Minutes": int(loginLockTime.Minutes()),
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = []string{u.Email}
+	return svc.emailSvc.Send(ctx, msg)
+}
+
+// delay 第loginDelayAfter次失败等1秒 之后每次翻倍
+func (svc *LoginGuardDevService) delay(cnt int) time.Duration {
+	d := loginDelayBase << (cnt - loginDelayAfter)
+	if d > loginDelayMax {
+		return loginDelayMax
+	}
+	return d
+}
+
+// normalizeAccount 查邮箱时数据库不区分大小写 计数和锁定也不能区分 否则换个大小写就能绕过
+func normalizeAccount(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
+
+func (svc *LoginGuardDevService) accountKey(account string) string {
+	return "login:fail:account:" + account
+}
+
+func (svc *LoginGuardDevService) ipKey(ip string) string {
+	return "login:fail:ip:" + ip
+}
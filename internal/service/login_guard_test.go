@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	repomocks "webook/internal/repository/mocks"
+	"webook/internal/service/email"
+	emailmocks "webook/internal/service/email/mocks"
+	"webook/internal/service/email/templates"
+	pkgmocks "webook/pkg/mocks"
+	"webook/pkg/ratelimit"
+)
+
+func TestLoginGuardDevService_Fail(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginLockRepository,
+			ratelimit.Counter, email.Service)
+
+		wantErr error
+	}{
+		{
+			name: "次数不多 不用等待",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginLockRepository,
+				ratelimit.Counter, email.Service) {
+				counter := pkgmocks.NewMockCounter(ctrl)
+				counter.EXPECT().Incr(gomock.Any(), "login:fail:ip:127.0.0.1").Return(1, nil)
+				counter.EXPECT().Incr(gomock.Any(), "login:fail:account:123@qq.com").Return(2, nil)
+				return nil, nil, counter, nil
+			},
+		},
+		{
+			name: "逐渐增加等待时间",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginLockRepository,
+				ratelimit.Counter, email.Service) {
+				counter := pkgmocks.NewMockCounter(ctrl)
+				counter.EXPECT().Incr(gomock.Any(), "login:fail:ip:127.0.0.1").Return(5, nil)
+				counter.EXPECT().Incr(gomock.Any(), "login:fail:account:123@qq.com").Return(5, nil)
+				lockRepo := repomocks.NewMockLoginLockRepository(ctrl)
+				lockRepo.EXPECT().SetDelay(gomock.Any(), "123@qq.com", time.Second*4).Return(nil)
+				return nil, lockRepo, counter, nil
+			},
+		},
+		{
+			name: "锁定并通知",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginLockRepository,
+				ratelimit.Counter, email.Service) {
+				counter := pkgmocks.NewMockCounter(ctrl)
+				counter.EXPECT().Incr(gomock.Any(), "login:fail:ip:127.0.0.1").Return(10, nil)
+				counter.EXPECT().Incr(gomock.Any(), "login:fail:account:123@qq.com").Return(10, nil)
+				counter.EXPECT().Reset(gomock.Any(), "login:fail:account:123@qq.com").Return(nil)
+				lockRepo := repomocks.NewMockLoginLockRepository(ctrl)
+				lockRepo.EXPECT().Lock(gomock.Any(), "123@qq.com", loginLockTime).Return(true, nil)
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), domain.User{Email: "123@qq.com"}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				emailSvc := emailmocks.NewMockService(ctrl)
+				emailSvc.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, msg email.Message) error {
+						assert.Equal(t, []string{"123@qq.com"}, msg.To)
+						assert.Contains(t, msg.Text, "127.0.0.1")
+						return nil
+					})
+				return repo, lockRepo, counter, emailSvc
+			},
+		},
+		{
+			name: "已经锁定 不重复通知",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginLockRepository,
+				ratelimit.Counter, email.Service) {
+				counter := pkgmocks.NewMockCounter(ctrl)
+				counter.EXPECT().Incr(gomock.Any(), "login:fail:ip:127.0.0.1").Return(11, nil)
+				counter.EXPECT().Incr(gomock.Any(), "login:fail:account:123@qq.com").Return(11, nil)
+				lockRepo := repomocks.NewMockLoginLockRepository(ctrl)
+				lockRepo.EXPECT().Lock(gomock.Any(), "123@qq.com", loginLockTime).Return(false, nil)
+				return nil, lockRepo, counter, nil
+			},
+		},
+	}
+
+	tpls, err := templates.NewDefaultTemplates()
+	require.NoError(t, err)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo, lockRepo, counter, emailSvc := tc.mock(ctrl)
+			svc := NewLoginGuardDevService(repo, lockRepo, counter, nil, nil, emailSvc, tpls)
+			err := svc.Fail(context.Background(), "123@qq.com", "127.0.0.1")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestLoginGuardDevService_Check(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.LoginLockRepository, ratelimit.Counter)
+
+		wantWait time.Duration
+		wantErr  error
+	}{
+		{
+			name: "账号被锁定",
+			mock: func(ctrl *gomock.Controller) (repository.LoginLockRepository, ratelimit.Counter) {
+				lockRepo := repomocks.NewMockLoginLockRepository(ctrl)
+				lockRepo.EXPECT().LockTTL(gomock.Any(), "123@qq.com").Return(time.Minute, nil)
+				return lockRepo, nil
+			},
+			wantWait: time.Minute,
+			wantErr:  ErrAccountLocked,
+		},
+		{
+			name: "还没到下次可以尝试的时间",
+			mock: func(ctrl *gomock.Controller) (repository.LoginLockRepository, ratelimit.Counter) {
+				lockRepo := repomocks.NewMockLoginLockRepository(ctrl)
+				lockRepo.EXPECT().LockTTL(gomock.Any(), "123@qq.com").Return(time.Duration(0), nil)
+				lockRepo.EXPECT().DelayTTL(gomock.Any(), "123@qq.com").Return(time.Second*2, nil)
+				return lockRepo, nil
+			},
+			wantWait: time.Second * 2,
+			wantErr:  ErrLoginTooFrequent,
+		},
+		{
+			name: "IP失败次数太多",
+			mock: func(ctrl *gomock.Controller) (repository.LoginLockRepository, ratelimit.Counter) {
+				lockRepo := repomocks.NewMockLoginLockRepository(ctrl)
+				lockRepo.EXPECT().LockTTL(gomock.Any(), "123@qq.com").Return(time.Duration(0), nil)
+				lockRepo.EXPECT().DelayTTL(gomock.Any(), "123@qq.com").Return(time.Duration(0), nil)
+				counter := pkgmocks.NewMockCounter(ctrl)
+				counter.EXPECT().Count(gomock.Any(), "login:fail:ip:127.0.0.1").Return(loginIPLimit, nil)
+				return lockRepo, counter
+			},
+			wantWait: LoginFailWindow,
+			wantErr:  ErrLoginTooFrequent,
+		},
+		{
+			name: "可以登录",
+			mock: func(ctrl *gomock.Controller) (repository.LoginLockRepository, ratelimit.Counter) {
+				lockRepo := repomocks.NewMockLoginLockRepository(ctrl)
+				lockRepo.EXPECT().LockTTL(gomock.Any(), "123@qq.com").Return(time.Duration(0), nil)
+				lockRepo.EXPECT().DelayTTL(gomock.Any(), "123@qq.com").Return(time.Duration(0), nil)
+				counter := pkgmocks.NewMockCounter(ctrl)
+				counter.EXPECT().Count(gomock.Any(), "login:fail:ip:127.0.0.1").Return(3, nil)
+				return lockRepo, counter
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			lockRepo, counter := tc.mock(ctrl)
+			svc := NewLoginGuardDevService(nil, lockRepo, counter, nil, nil, nil, nil)
+			wait, err := svc.Check(context.Background(), "123@qq.com", "127.0.0.1")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantWait, wait)
+		})
+	}
+}
+
+func TestLoginGuardDevService_Unlock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := repomocks.NewMockUserRepository(ctrl)
+	repo.EXPECT().FindByEmail(gomock.Any(), domain.User{Email: "123@qq.com"}).
+		Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+	codeRepo := repomocks.NewMockCodeRepository(ctrl)
+	codeRepo.EXPECT().Verify(gomock.Any(), unlockLoginBiz, "123@qq.com", "123456").Return(true, nil)
+	lockRepo := repomocks.NewMockLoginLockRepository(ctrl)
+	lockRepo.EXPECT().Unlock(gomock.Any(), "123@qq.com").Return(nil)
+	counter := pkgmocks.NewMockCounter(ctrl)
+	counter.EXPECT().Reset(gomock.Any(), "login:fail:account:123@qq.com").Return(nil)
+
+	svc := NewLoginGuardDevService(repo, lockRepo, counter, nil, codeRepo, nil, nil)
+	err := svc.Unlock(context.Background(), "123@qq.com", UnlockByEmail, "123456")
+	assert.NoError(t, err)
+}
+
+func TestLoginGuardDevService_NormalizeAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	//换大小写和加空格都算同一个账号
+	lockRepo := repomocks.NewMockLoginLockRepository(ctrl)
+	lockRepo.EXPECT().LockTTL(gomock.Any(), "victim@qq.com").Return(time.Duration(0), nil)
+	lockRepo.EXPECT().DelayTTL(gomock.Any(), "victim@qq.com").Return(time.Duration(0), nil)
+	lockRepo.EXPECT().SetDelay(gomock.Any(), "victim@qq.com", loginDelayBase).Return(nil)
+	counter := pkgmocks.NewMockCounter(ctrl)
+	counter.EXPECT().Count(gomock.Any(), "login:fail:ip:127.0.0.1").Return(0, nil)
+	counter.EXPECT().Incr(gomock.Any(), "login:fail:ip:127.0.0.1").Return(3, nil)
+	counter.EXPECT().Incr(gomock.Any(), "login:fail:account:victim@qq.com").Return(3, nil)
+
+	svc := NewLoginGuardDevService(nil, lockRepo, counter, nil, nil, nil, nil)
+	_, err := svc.Check(context.Background(), "Victim@QQ.com", "127.0.0.1")
+	require.NoError(t, err)
+	err = svc.Fail(context.Background(), " VICTIM@qq.com ", "127.0.0.1")
+	require.NoError(t, err)
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	"webook/pkg/totp"
+)
+
+const (
+	totpIssuer = "webook"
+	//允许前后各偏差一个时间步 容忍手机时间不准
+	totpSkew          = 1
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrMFACodeInvalid    = errors.New("二次验证码错误")
+	ErrMFANotEnabled     = errors.New("没有开启二次验证")
+	ErrMFAAlreadyEnabled = errors.New("已经开启了二次验证")
+)
+
+type MFAService interface {
+	// SetupTOTP 生成新的密钥 返回密钥和给验证器App扫码的otpauth URI
+	SetupTOTP(ctx context.Context, userId int, account string) (secret string, uri string, err error)
+	// EnableTOTP 用验证器App生成的验证码确认绑定 返回一次性恢复码
+	EnableTOTP(ctx context.Context, userId int, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userId int, code string) error
+	Enabled(ctx context.Context, userId int) (bool, error)
+	// Verify 验证码或者恢复码都可以
+	Verify(ctx context.Context, userId int, code string) error
+}
+
+type MFADevService struct {
+	repo repository.MFARepository
+}
+
+func NewMFADevService(repo repository.MFARepository) *MFADevService {
+	return &MFADevService{repo: repo}
+}
+
+func (svc *MFADevService) SetupTOTP(ctx context.Context, userId int, account string) (string, string, error) {
+	t, err := svc.repo.FindTOTP(ctx, userId)
+	switch {
+	case err == nil && t.Enabled:
+		return "", "", ErrMFAAlreadyEnabled
+	case err != nil && !errors.Is(err, repository.ErrTOTPNotFind):
+		return "", "", err
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	err = svc.repo.SaveTOTP(ctx, domain.TOTP{
+		UserId: userId,
+		Secret: secret,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return secret, totp.URI(totpIssuer, account, secret), nil
+}
+
+func (svc *MFADevService) EnableTOTP(ctx context.Context, userId int, code string) ([]string, error) {
+	t, err := svc.repo.FindTOTP(ctx, userId)
+	if errors.Is(err, repository.ErrTOTPNotFind) {
+		//还没调用SetupTOTP
+		return nil, ErrMFANotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	counter, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrMFACodeInvalid
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		c, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+		hashes = append(hashes, hashRecoveryCode(c))
+	}
+	err = svc.repo.EnableTOTP(ctx, userId, counter, hashes)
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (svc *MFADevService) DisableTOTP(ctx context.Context, userId int, code string) error {
+	err := svc.Verify(ctx, userId, code)
+	if err != nil {
+		return err
+	}
+	return svc.repo.DeleteTOTP(ctx, userId)
+}
+
+func (svc *MFADevService) Enabled(ctx context.Context, userId int) (bool, error) {
+	t, err := svc.repo.FindTOTP(ctx, userId)
+	if errors.Is(err, repository.ErrTOTPNotFind) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return t.Enabled, nil
+}
+
+func (svc *MFADevService) Verify(ctx context.Context, userId int, code string) error {
+	t, err := svc.repo.FindTOTP(ctx, userId)
+	if errors.Is(err, repository.ErrTOTPNotFind) {
+		return ErrMFANotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	if !t.Enabled {
+		return ErrMFANotEnabled
+	}
+	if counter, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew); ok {
+		//同一个时间步的验证码只能用一次
+		ok, err = svc.repo.UpdateLastCounter(ctx, userId, counter)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrMFACodeInvalid
+		}
+		return nil
+	}
+	ok, err := svc.repo.UseRecoveryCode(ctx, userId, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrMFACodeInvalid
+	}
+	return nil
+}
+
+// newRecoveryCode 形如 a1b2c-3d4e5
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, 5)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	s := hex.EncodeToString(buf)
+	return s[:5] + "-" + s[5:], nil
+}
+
+// hashRecoveryCode 恢复码本身是高熵随机数 sha256就够了 输入时忽略大小写和横线
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	repomocks "webook/internal/repository/mocks"
+	"webook/pkg/totp"
+)
+
+func TestMFADevService_Verify(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	counter := totp.Counter(time.Now())
+	code, err := totp.Code(secret, counter)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.MFARepository
+
+		code string
+
+		wantErr error
+	}{
+		{
+			name: "验证码正确",
+			mock: func(ctrl *gomock.Controller) repository.MFARepository {
+				repo := repomocks.NewMockMFARepository(ctrl)
+				repo.EXPECT().FindTOTP(gomock.Any(), 1).
+					Return(domain.TOTP{UserId: 1, Secret: secret, Enabled: true}, nil)
+				repo.EXPECT().UpdateLastCounter(gomock.Any(), 1, gomock.Any()).Return(true, nil)
+				return repo
+			},
+			code: code,
+		},
+		{
+			name: "验证码已经用过",
+			mock: func(ctrl *gomock.Controller) repository.MFARepository {
+				repo := repomocks.NewMockMFARepository(ctrl)
+				repo.EXPECT().FindTOTP(gomock.Any(), 1).
+					Return(domain.TOTP{UserId: 1, Secret: secret, Enabled: true, LastCounter: counter}, nil)
+				repo.EXPECT().UpdateLastCounter(gomock.Any(), 1, gomock.Any()).Return(false, nil)
+				return repo
+			},
+			code:    code,
+			wantErr: ErrMFACodeInvalid,
+		},
+		{
+			name: "恢复码",
+			mock: func(ctrl *gomock.Controller) repository.MFARepository {
+				repo := repomocks.NewMockMFARepository(ctrl)
+				repo.EXPECT().FindTOTP(gomock.Any(), 1).
+					Return(domain.TOTP{UserId: 1, Secret: secret, Enabled: true}, nil)
+				repo.EXPECT().UseRecoveryCode(gomock.Any(), 1, hashRecoveryCode("a1b2c3d4e5")).Return(true, nil)
+				return repo
+			},
+			code: "A1B2C-3D4E5",
+		},
+		{
+			name: "恢复码错误",
+			mock: func(ctrl *gomock.Controller) repository.MFARepository {
+				repo := repomocks.NewMockMFARepository(ctrl)
+				repo.EXPECT().FindTOTP(gomock.Any(), 1).
+					Return(domain.TOTP{UserId: 1, Secret: secret, Enabled: true}, nil)
+				repo.EXPECT().UseRecoveryCode(gomock.Any(), 1, gomock.Any()).Return(false, nil)
+				return repo
+			},
+			code:    "00000-00000",
+			wantErr: ErrMFACodeInvalid,
+		},
+		{
+			name: "没有开启",
+			mock: func(ctrl *gomock.Controller) repository.MFARepository {
+				repo := repomocks.NewMockMFARepository(ctrl)
+				repo.EXPECT().FindTOTP(gomock.Any(), 1).Return(domain.TOTP{}, repository.ErrTOTPNotFind)
+				return repo
+			},
+			code:    code,
+			wantErr: ErrMFANotEnabled,
+		},
+		{
+			name: "数据库出错",
+			mock: func(ctrl *gomock.Controller) repository.MFARepository {
+				repo := repomocks.NewMockMFARepository(ctrl)
+				repo.EXPECT().FindTOTP(gomock.Any(), 1).Return(domain.TOTP{}, errors.New("数据库出错"))
+				return repo
+			},
+			code:    code,
+			wantErr: errors.New("数据库出错"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := NewMFADevService(tc.mock(ctrl))
+			err := svc.Verify(context.Background(), 1, tc.code)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/email_verify.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/service/email_verify.go -package=svcmocks -destination=internal/service/mocks/email_verify_gen.go
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	domain "webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockEmailVerifyService is a mock of EmailVerifyService interface.
+type MockEmailVerifyService struct {
+	ctrl     *gomock.Controller
+	recorder *MockEmailVerifyServiceMockRecorder
+}
+
+// MockEmailVerifyServiceMockRecorder is the mock recorder for MockEmailVerifyService.
+type MockEmailVerifyServiceMockRecorder struct {
+	mock *MockEmailVerifyService
+}
+
+// NewMockEmailVerifyService creates a new mock instance.
+func NewMockEmailVerifyService(ctrl *gomock.Controller) *MockEmailVerifyService {
+	mock := &MockEmailVerifyService{ctrl: ctrl}
+	mock.recorder = &MockEmailVerifyServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEmailVerifyService) EXPECT() *MockEmailVerifyServiceMockRecorder {
+	return m.recorder
+}
+
+// CheckLogin mocks base method.
+func (m *MockEmailVerifyService) CheckLogin(u domain.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckLogin", u)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckLogin indicates an expected call of CheckLogin.
+func (mr *MockEmailVerifyServiceMockRecorder) CheckLogin(u any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLogin", reflect.TypeOf((*MockEmailVerifyService)(nil).CheckLogin), u)
+}
+
+// SendBindCode mocks base method.
+func (m *MockEmailVerifyService) SendBindCode(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendBindCode", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendBindCode indicates an expected call of SendBindCode.
+func (mr *MockEmailVerifyServiceMockRecorder) SendBindCode(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendBindCode", reflect.TypeOf((*MockEmailVerifyService)(nil).SendBindCode), ctx, email)
+}
+
+// SendVerifyEmail mocks base method.
+func (m *MockEmailVerifyService) SendVerifyEmail(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendVerifyEmail", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendVerifyEmail indicates an expected call of SendVerifyEmail.
+func (mr *MockEmailVerifyServiceMockRecorder) SendVerifyEmail(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendVerifyEmail", reflect.TypeOf((*MockEmailVerifyService)(nil).SendVerifyEmail), ctx, email)
+}
+
+// Verify mocks base method.
+func (m *MockEmailVerifyService) Verify(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockEmailVerifyServiceMockRecorder) Verify(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockEmailVerifyService)(nil).Verify), ctx, token)
+}
+
+// VerifyBindCode mocks base method.
+func (m *MockEmailVerifyService) VerifyBindCode(ctx context.Context, email, code string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyBindCode", ctx, email, code)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyBindCode indicates an expected call of VerifyBindCode.
+func (mr *MockEmailVerifyServiceMockRecorder) VerifyBindCode(ctx, email, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyBindCode", reflect.TypeOf((*MockEmailVerifyService)(nil).VerifyBindCode), ctx, email, code)
+}
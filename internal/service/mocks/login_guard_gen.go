@@ -0,0 +1,112 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/login_guard.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/service/login_guard.go -package=svcmocks -destination=internal/service/mocks/login_guard_gen.go
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+	service "webook/internal/service"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLoginGuardService is a mock of LoginGuardService interface.
+type MockLoginGuardService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginGuardServiceMockRecorder
+}
+
+// MockLoginGuardServiceMockRecorder is the mock recorder for MockLoginGuardService.
+type MockLoginGuardServiceMockRecorder struct {
+	mock *MockLoginGuardService
+}
+
+// NewMockLoginGuardService creates a new mock instance.
+func NewMockLoginGuardService(ctrl *gomock.Controller) *MockLoginGuardService {
+	mock := &MockLoginGuardService{ctrl: ctrl}
+	mock.recorder = &MockLoginGuardServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginGuardService) EXPECT() *MockLoginGuardServiceMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockLoginGuardService) Check(ctx context.Context, account, ip string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", ctx, account, ip)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockLoginGuardServiceMockRecorder) Check(ctx, account, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockLoginGuardService)(nil).Check), ctx, account, ip)
+}
+
+// Fail mocks base method.
+func (m *MockLoginGuardService) Fail(ctx context.Context, account, ip string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fail", ctx, account, ip)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Fail indicates an expected call of Fail.
+func (mr *MockLoginGuardServiceMockRecorder) Fail(ctx, account, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fail", reflect.TypeOf((*MockLoginGuardService)(nil).Fail), ctx, account, ip)
+}
+
+// SendUnlockCode mocks base method.
+func (m *MockLoginGuardService) SendUnlockCode(ctx context.Context, account string, channel service.UnlockChannel) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendUnlockCode", ctx, account, channel)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendUnlockCode indicates an expected call of SendUnlockCode.
+func (mr *MockLoginGuardServiceMockRecorder) SendUnlockCode(ctx, account, channel any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendUnlockCode", reflect.TypeOf((*MockLoginGuardService)(nil).SendUnlockCode), ctx, account, channel)
+}
+
+// Succeed mocks base method.
+func (m *MockLoginGuardService) Succeed(ctx context.Context, account string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Succeed", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Succeed indicates an expected call of Succeed.
+func (mr *MockLoginGuardServiceMockRecorder) Succeed(ctx, account any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Succeed", reflect.TypeOf((*MockLoginGuardService)(nil).Succeed), ctx, account)
+}
+
+// Unlock mocks base method.
+func (m *MockLoginGuardService) Unlock(ctx context.Context, account string, channel service.UnlockChannel, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlock", ctx, account, channel, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlock indicates an expected call of Unlock.
+func (mr *MockLoginGuardServiceMockRecorder) Unlock(ctx, account, channel, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockLoginGuardService)(nil).Unlock), ctx, account, channel, code)
+}
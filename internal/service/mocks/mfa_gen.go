@@ -0,0 +1,113 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/mfa.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/service/mfa.go -package=svcmocks -destination=internal/service/mocks/mfa_gen.go
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockMFAService is a mock of MFAService interface.
+type MockMFAService struct {
+	ctrl     *gomock.Controller
+	recorder *MockMFAServiceMockRecorder
+}
+
+// MockMFAServiceMockRecorder is the mock recorder for MockMFAService.
+type MockMFAServiceMockRecorder struct {
+	mock *MockMFAService
+}
+
+// NewMockMFAService creates a new mock instance.
+func NewMockMFAService(ctrl *gomock.Controller) *MockMFAService {
+	mock := &MockMFAService{ctrl: ctrl}
+	mock.recorder = &MockMFAServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMFAService) EXPECT() *MockMFAServiceMockRecorder {
+	return m.recorder
+}
+
+// DisableTOTP mocks base method.
+func (m *MockMFAService) DisableTOTP(ctx context.Context, userId int, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", ctx, userId, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockMFAServiceMockRecorder) DisableTOTP(ctx, userId, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockMFAService)(nil).DisableTOTP), ctx, userId, code)
+}
+
+// EnableTOTP mocks base method.
+func (m *MockMFAService) EnableTOTP(ctx context.Context, userId int, code string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTP", ctx, userId, code)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnableTOTP indicates an expected call of EnableTOTP.
+func (mr *MockMFAServiceMockRecorder) EnableTOTP(ctx, userId, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTP", reflect.TypeOf((*MockMFAService)(nil).EnableTOTP), ctx, userId, code)
+}
+
+// Enabled mocks base method.
+func (m *MockMFAService) Enabled(ctx context.Context, userId int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enabled", ctx, userId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Enabled indicates an expected call of Enabled.
+func (mr *MockMFAServiceMockRecorder) Enabled(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enabled", reflect.TypeOf((*MockMFAService)(nil).Enabled), ctx, userId)
+}
+
+// SetupTOTP mocks base method.
+func (m *MockMFAService) SetupTOTP(ctx context.Context, userId int, account string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetupTOTP", ctx, userId, account)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SetupTOTP indicates an expected call of SetupTOTP.
+func (mr *MockMFAServiceMockRecorder) SetupTOTP(ctx, userId, account any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupTOTP", reflect.TypeOf((*MockMFAService)(nil).SetupTOTP), ctx, userId, account)
+}
+
+// Verify mocks base method.
+func (m *MockMFAService) Verify(ctx context.Context, userId int, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", ctx, userId, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockMFAServiceMockRecorder) Verify(ctx, userId, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockMFAService)(nil).Verify), ctx, userId, code)
+}
@@ -0,0 +1,97 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/password_reset.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/service/password_reset.go -package=svcmocks -destination=internal/service/mocks/password_reset_gen.go
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPasswordResetService is a mock of PasswordResetService interface.
+type MockPasswordResetService struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordResetServiceMockRecorder
+}
+
+// MockPasswordResetServiceMockRecorder is the mock recorder for MockPasswordResetService.
+type MockPasswordResetServiceMockRecorder struct {
+	mock *MockPasswordResetService
+}
+
+// NewMockPasswordResetService creates a new mock instance.
+func NewMockPasswordResetService(ctrl *gomock.Controller) *MockPasswordResetService {
+	mock := &MockPasswordResetService{ctrl: ctrl}
+	mock.recorder = &MockPasswordResetServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPasswordResetService) EXPECT() *MockPasswordResetServiceMockRecorder {
+	return m.recorder
+}
+
+// ResetByEmail mocks base method.
+func (m *MockPasswordResetService) ResetByEmail(ctx context.Context, token, password string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetByEmail", ctx, token, password)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetByEmail indicates an expected call of ResetByEmail.
+func (mr *MockPasswordResetServiceMockRecorder) ResetByEmail(ctx, token, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetByEmail", reflect.TypeOf((*MockPasswordResetService)(nil).ResetByEmail), ctx, token, password)
+}
+
+// ResetBySMS mocks base method.
+func (m *MockPasswordResetService) ResetBySMS(ctx context.Context, phone, code, password string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetBySMS", ctx, phone, code, password)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetBySMS indicates an expected call of ResetBySMS.
+func (mr *MockPasswordResetServiceMockRecorder) ResetBySMS(ctx, phone, code, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetBySMS", reflect.TypeOf((*MockPasswordResetService)(nil).ResetBySMS), ctx, phone, code, password)
+}
+
+// SendEmailLink mocks base method.
+func (m *MockPasswordResetService) SendEmailLink(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendEmailLink", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendEmailLink indicates an expected call of SendEmailLink.
+func (mr *MockPasswordResetServiceMockRecorder) SendEmailLink(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendEmailLink", reflect.TypeOf((*MockPasswordResetService)(nil).SendEmailLink), ctx, email)
+}
+
+// SendSMSCode mocks base method.
+func (m *MockPasswordResetService) SendSMSCode(ctx context.Context, phone string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendSMSCode", ctx, phone)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendSMSCode indicates an expected call of SendSMSCode.
+func (mr *MockPasswordResetServiceMockRecorder) SendSMSCode(ctx, phone any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendSMSCode", reflect.TypeOf((*MockPasswordResetService)(nil).SendSMSCode), ctx, phone)
+}
@@ -0,0 +1,55 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/role.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/service/role.go -package=svcmocks -destination=internal/service/mocks/role_gen.go
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRoleService is a mock of RoleService interface.
+type MockRoleService struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoleServiceMockRecorder
+}
+
+// MockRoleServiceMockRecorder is the mock recorder for MockRoleService.
+type MockRoleServiceMockRecorder struct {
+	mock *MockRoleService
+}
+
+// NewMockRoleService creates a new mock instance.
+func NewMockRoleService(ctrl *gomock.Controller) *MockRoleService {
+	mock := &MockRoleService{ctrl: ctrl}
+	mock.recorder = &MockRoleServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoleService) EXPECT() *MockRoleServiceMockRecorder {
+	return m.recorder
+}
+
+// UserPermissions mocks base method.
+func (m *MockRoleService) UserPermissions(ctx context.Context, userId int) ([]string, []string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserPermissions", ctx, userId)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].([]string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// UserPermissions indicates an expected call of UserPermissions.
+func (mr *MockRoleServiceMockRecorder) UserPermissions(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserPermissions", reflect.TypeOf((*MockRoleService)(nil).UserPermissions), ctx, userId)
+}
@@ -11,7 +11,9 @@ package svcmocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 	domain "webook/internal/domain"
+	service "webook/internal/service"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -39,6 +41,62 @@ func (m *MockUserService) EXPECT() *MockUserServiceMockRecorder {
 	return m.recorder
 }
 
+// BindEmail mocks base method.
+func (m *MockUserService) BindEmail(ctx context.Context, userId int, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BindEmail", ctx, userId, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BindEmail indicates an expected call of BindEmail.
+func (mr *MockUserServiceMockRecorder) BindEmail(ctx, userId, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BindEmail", reflect.TypeOf((*MockUserService)(nil).BindEmail), ctx, userId, email)
+}
+
+// BindPhone mocks base method.
+func (m *MockUserService) BindPhone(ctx context.Context, userId int, phone string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BindPhone", ctx, userId, phone)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BindPhone indicates an expected call of BindPhone.
+func (mr *MockUserServiceMockRecorder) BindPhone(ctx, userId, phone any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BindPhone", reflect.TypeOf((*MockUserService)(nil).BindPhone), ctx, userId, phone)
+}
+
+// BindWechat mocks base method.
+func (m *MockUserService) BindWechat(ctx context.Context, userId int, info domain.WechatInfo) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BindWechat", ctx, userId, info)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BindWechat indicates an expected call of BindWechat.
+func (mr *MockUserServiceMockRecorder) BindWechat(ctx, userId, info any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BindWechat", reflect.TypeOf((*MockUserService)(nil).BindWechat), ctx, userId, info)
+}
+
+// ChangePassword mocks base method.
+func (m *MockUserService) ChangePassword(ctx context.Context, userId int, oldPassword, newPassword string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, userId, oldPassword, newPassword)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockUserServiceMockRecorder) ChangePassword(ctx, userId, oldPassword, newPassword any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockUserService)(nil).ChangePassword), ctx, userId, oldPassword, newPassword)
+}
+
 // EditUserPassword mocks base method.
 func (m *MockUserService) EditUserPassword(ctx context.Context, u domain.User) error {
 	m.ctrl.T.Helper()
@@ -68,6 +126,21 @@ func (mr *MockUserServiceMockRecorder) FindOrCreate(ctx, u any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrCreate", reflect.TypeOf((*MockUserService)(nil).FindOrCreate), ctx, u)
 }
 
+// FindOrCreateByWechat mocks base method.
+func (m *MockUserService) FindOrCreateByWechat(ctx context.Context, info domain.WechatInfo) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrCreateByWechat", ctx, info)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrCreateByWechat indicates an expected call of FindOrCreateByWechat.
+func (mr *MockUserServiceMockRecorder) FindOrCreateByWechat(ctx, info any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrCreateByWechat", reflect.TypeOf((*MockUserService)(nil).FindOrCreateByWechat), ctx, info)
+}
+
 // Login mocks base method.
 func (m *MockUserService) Login(ctx context.Context, u domain.User) (domain.User, error) {
 	m.ctrl.T.Helper()
@@ -98,6 +171,36 @@ func (mr *MockUserServiceMockRecorder) Profile(ctx, u any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Profile", reflect.TypeOf((*MockUserService)(nil).Profile), ctx, u)
 }
 
+// PurgeDeleted mocks base method.
+func (m *MockUserService) PurgeDeleted(ctx context.Context, limit int) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", ctx, limit)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted.
+func (mr *MockUserServiceMockRecorder) PurgeDeleted(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockUserService)(nil).PurgeDeleted), ctx, limit)
+}
+
+// RequestDeletion mocks base method.
+func (m *MockUserService) RequestDeletion(ctx context.Context, userId int) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestDeletion", ctx, userId)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequestDeletion indicates an expected call of RequestDeletion.
+func (mr *MockUserServiceMockRecorder) RequestDeletion(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestDeletion", reflect.TypeOf((*MockUserService)(nil).RequestDeletion), ctx, userId)
+}
+
 // SignUp mocks base method.
 func (m *MockUserService) SignUp(ctx context.Context, u domain.User) error {
 	m.ctrl.T.Helper()
@@ -111,3 +214,31 @@ func (mr *MockUserServiceMockRecorder) SignUp(ctx, u any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignUp", reflect.TypeOf((*MockUserService)(nil).SignUp), ctx, u)
 }
+
+// Unbind mocks base method.
+func (m *MockUserService) Unbind(ctx context.Context, userId int, identity service.Identity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unbind", ctx, userId, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unbind indicates an expected call of Unbind.
+func (mr *MockUserServiceMockRecorder) Unbind(ctx, userId, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unbind", reflect.TypeOf((*MockUserService)(nil).Unbind), ctx, userId, identity)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockUserService) UpdateProfile(ctx context.Context, u domain.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, u)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockUserServiceMockRecorder) UpdateProfile(ctx, u any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockUserService)(nil).UpdateProfile), ctx, u)
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	"webook/internal/service/email"
+	"webook/internal/service/email/templates"
+	"webook/pkg/ratelimit"
+)
+
+const (
+	resetPasswordBiz = "reset_pwd"
+	//重置链接的有效期
+	resetTokenExpiration = time.Minute * 30
+)
+
+var (
+	ErrResetCodeInvalid  = errors.New("验证码错误")
+	ErrResetTokenInvalid = errors.New("重置链接无效或已过期")
+	ErrResetSendTooMany  = errors.New("重置邮件发送太频繁")
+)
+
+// PasswordResetService 忘记密码 手机号用短信验证码 邮箱用一次性链接
+type PasswordResetService interface {
+	// SendSMSCode 手机号没有注册时也返回成功 防止被用来探测手机号
+	SendSMSCode(ctx context.Context, phone string) error
+	// ResetBySMS 返回被重置的用户id
+	ResetBySMS(ctx context.Context, phone string, code string, password string) (int, error)
+	// SendEmailLink 邮箱没有注册时也返回成功 同一个邮箱不管有没有注册都一样限流
+	SendEmailLink(ctx context.Context, email string) error
+	ResetByEmail(ctx context.Context, token string, password string) (int, error)
+}
+
+type PasswordResetDevService struct {
+	repo     repository.UserRepository
+	userSvc  UserService
+	codeSvc  CodeService
+	emailSvc email.Service
+	tpls     *templates.Templates
+	//同一个邮箱两次发送的最小间隔
+	cooldown ratelimit.Limiter
+	//同一个邮箱一段时间内最多发送的次数
+	limiter ratelimit.Limiter
+	tokens  signedToken
+	//重置页面的地址 后面拼接token
+	linkURL string
+}
+
+func NewPasswordResetDevService(repo repository.UserRepository, userSvc UserService, codeSvc CodeService,
+	emailSvc email.Service, tpls *templates.Templates, cooldown ratelimit.Limiter, limiter ratelimit.Limiter,
+	secret []byte, linkURL string) *PasswordResetDevService {
+	return &PasswordResetDevService{
+		repo:     repo,
+		userSvc:  userSvc,
+		codeSvc:  codeSvc,
+		emailSvc: emailSvc,
+		tpls:     tpls,
+		cooldown: cooldown,
+		limiter:  limiter,
+		tokens:   signedToken{secret: secret, purpose: "reset_password"},
+		linkURL:  linkURL,
+	}
+}
+
+func (svc *PasswordResetDevService) SendSMSCode(ctx context.Context, phone string) error {
+	_, err := svc.repo.FindByPhone(ctx, domain.User{Phone: phone})
+	if errors.Is(err, ErrUserNotFind) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return svc.codeSvc.Send(ctx, resetPasswordBiz, phone)
+}
+
+func (svc *PasswordResetDevService) ResetBySMS(ctx context.Context, phone string, code string, password string) (int, error) {
+	ok, err := svc.codeSvc.Verify(ctx, resetPasswordBiz, phone, code)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrResetCodeInvalid
+	}
+	u, err := svc.repo.FindByPhone(ctx, domain.User{Phone: phone})
+	if err != nil {
+		return 0, err
+	}
+	return u.Id, svc.userSvc.EditUserPassword(ctx, domain.User{
+		Id:       u.Id,
+		Email:    u.Email,
+		Password: password,
+	})
+}
+
+func (svc *PasswordResetDevService) SendEmailLink(ctx context.Context, addr string) error {
+	//查用户之前限流 不然可以根据响应判断邮箱有没有注册
+	//MySQL比较邮箱不区分大小写 限流的key也不能区分
+	key := strings.ToLower(strings.TrimSpace(addr))
+	limited, err := svc.cooldown.Limit(ctx, "reset_pwd_email:cooldown:"+key)
+	if err != nil {
+		return fmt.Errorf("判断是否限流出现问题,%w", err)
+	}
+	if limited {
+		return ErrResetSendTooMany
+	}
+	limited, err = svc.limiter.Limit(ctx, "reset_pwd_email:"+key)
+	if err != nil {
+		return fmt.Errorf("判断是否限流出现问题,%w", err)
+	}
+	if limited {
+		return ErrResetSendTooMany
+	}
+	u, err := svc.repo.FindByEmail(ctx, domain.User{Email: addr})
+	if errors.Is(err, ErrUserNotFind) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	msg, err := svc.tpls.Render("reset_password", map[string]any{
+		"Link":    svc.linkURL + svc.signToken(u, time.Now().Add(resetTokenExpiration)),
+		"Minutes": int(resetTokenExpiration.Minutes()),
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = []string{u.Email}
+	return svc.emailSvc.Send(ctx, msg)
+}
+
+func (svc *PasswordResetDevService) ResetByEmail(ctx context.Context, token string, password string) (int, error) {
+	u, err := svc.verifyToken(ctx, token)
+	if err != nil {
+		return 0, err
+	}
+	return u.Id, svc.userSvc.EditUserPassword(ctx, domain.User{
+		Id:       u.Id,
+		Email:    u.Email,
+		Password: password,
+	})
+}
+
+// signToken 签名里带上当前的密码哈希 密码改过之后链接自动失效 所以链接只能用一次
+func (svc *PasswordResetDevService) signToken(u domain.User, exp time.Time) string {
+	return svc.tokens.sign(u.Id, exp, u.Email, u.Password)
+}
+
+func (svc *PasswordResetDevService) verifyToken(ctx context.Context, token string) (domain.User, error) {
+	uid, err := svc.tokens.parse(token)
+	if err != nil {
+		return domain.User{}, ErrResetTokenInvalid
+	}
+	u, err := svc.repo.FindById(ctx, domain.User{Id: uid})
+	if errors.Is(err, ErrUserNotFind) {
+		return domain.User{}, ErrResetTokenInvalid
+	}
+	if err != nil {
+		return domain.User{}, err
+	}
+	if !svc.tokens.verify(token, u.Email, u.Password) {
+		return domain.User{}, ErrResetTokenInvalid
+	}
+	return u, nil
+}
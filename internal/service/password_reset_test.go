@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"strings"
+	"testing"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	repomocks "webook/internal/repository/mocks"
+	"webook/internal/service/email"
+	emailmocks "webook/internal/service/email/mocks"
+	"webook/internal/service/email/templates"
+	pkgmocks "webook/pkg/mocks"
+	"webook/pkg/ratelimit"
+)
+
+func TestPasswordResetDevService_verifyToken(t *testing.T) {
+	user := domain.User{Id: 1, Email: "123@qq.com", Password: "hash"}
+	signer := NewPasswordResetDevService(nil, nil, nil, nil, nil, nil, nil, []byte("secret"), "")
+	token := signer.signToken(user, time.Now().Add(time.Minute))
+
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		token string
+
+		wantUser domain.User
+		wantErr  error
+	}{
+		{
+			name: "链接有效",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).Return(user, nil)
+				return repo
+			},
+			token:    token,
+			wantUser: user,
+		},
+		{
+			name: "密码已经改过 链接失效",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "123@qq.com", Password: "new hash"}, nil)
+				return repo
+			},
+			token:   token,
+			wantErr: ErrResetTokenInvalid,
+		},
+		{
+			name: "链接过期",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				return repomocks.NewMockUserRepository(ctrl)
+			},
+			token:   signer.signToken(user, time.Now().Add(-time.Minute)),
+			wantErr: ErrResetTokenInvalid,
+		},
+		{
+			name: "签名被篡改",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).Return(user, nil)
+				return repo
+			},
+			token:   token[:strings.Index(token, ".")+1] + "AAAA",
+			wantErr: ErrResetTokenInvalid,
+		},
+		{
+			name: "格式错误",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				return repomocks.NewMockUserRepository(ctrl)
+			},
+			token:   "abc",
+			wantErr: ErrResetTokenInvalid,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := NewPasswordResetDevService(tc.mock(ctrl), nil, nil, nil, nil, nil, nil, []byte("secret"), "")
+			u, err := svc.verifyToken(context.Background(), tc.token)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUser, u)
+		})
+	}
+}
+
+func TestPasswordResetDevService_SendEmailLink(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, email.Service,
+			ratelimit.Limiter, ratelimit.Limiter)
+		addr string
+
+		wantErr error
+	}{
+		{
+			name: "发送成功",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, email.Service,
+				ratelimit.Limiter, ratelimit.Limiter) {
+				cooldown := pkgmocks.NewMockLimiter(ctrl)
+				cooldown.EXPECT().Limit(gomock.Any(), "reset_pwd_email:cooldown:123@qq.com").Return(false, nil)
+				limiter := pkgmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "reset_pwd_email:123@qq.com").Return(false, nil)
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), domain.User{Email: "123@qq.com"}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				emailSvc := emailmocks.NewMockService(ctrl)
+				emailSvc.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil)
+				return repo, emailSvc, cooldown, limiter
+			},
+			addr: "123@qq.com",
+		},
+		{
+			name: "邮箱没有注册 也计入限流",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, email.Service,
+				ratelimit.Limiter, ratelimit.Limiter) {
+				cooldown := pkgmocks.NewMockLimiter(ctrl)
+				cooldown.EXPECT().Limit(gomock.Any(), "reset_pwd_email:cooldown:123@qq.com").Return(false, nil)
+				limiter := pkgmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "reset_pwd_email:123@qq.com").Return(false, nil)
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), domain.User{Email: "123@qq.com"}).
+					Return(domain.User{}, ErrUserNotFind)
+				return repo, emailmocks.NewMockService(ctrl), cooldown, limiter
+			},
+			addr: "123@qq.com",
+		},
+		{
+			name: "冷却中 不查用户",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, email.Service,
+				ratelimit.Limiter, ratelimit.Limiter) {
+				cooldown := pkgmocks.NewMockLimiter(ctrl)
+				cooldown.EXPECT().Limit(gomock.Any(), "reset_pwd_email:cooldown:123@qq.com").Return(true, nil)
+				return repomocks.NewMockUserRepository(ctrl), emailmocks.NewMockService(ctrl),
+					cooldown, pkgmocks.NewMockLimiter(ctrl)
+			},
+			addr:    "123@qq.com",
+			wantErr: ErrResetSendTooMany,
+		},
+		{
+			name: "换大小写也算同一个邮箱",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, email.Service,
+				ratelimit.Limiter, ratelimit.Limiter) {
+				cooldown := pkgmocks.NewMockLimiter(ctrl)
+				cooldown.EXPECT().Limit(gomock.Any(), "reset_pwd_email:cooldown:123@qq.com").Return(false, nil)
+				limiter := pkgmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "reset_pwd_email:123@qq.com").Return(true, nil)
+				return repomocks.NewMockUserRepository(ctrl), emailmocks.NewMockService(ctrl), cooldown, limiter
+			},
+			addr:    " 123@QQ.com",
+			wantErr: ErrResetSendTooMany,
+		},
+	}
+
+	tpls, err := templates.NewDefaultTemplates()
+	require.NoError(t, err)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo, emailSvc, cooldown, limiter := tc.mock(ctrl)
+			svc := NewPasswordResetDevService(repo, nil, nil, emailSvc, tpls, cooldown, limiter, []byte("secret"), "")
+			err := svc.SendEmailLink(context.Background(), tc.addr)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
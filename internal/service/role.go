@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"webook/internal/repository"
+)
+
+type RoleService interface {
+	// UserPermissions 用户的角色名和去重后的权限
+	UserPermissions(ctx context.Context, userId int) (roles []string, permissions []string, err error)
+}
+
+type RoleDevService struct {
+	repo repository.RoleRepository
+}
+
+func NewRoleDevService(repo repository.RoleRepository) *RoleDevService {
+	return &RoleDevService{repo: repo}
+}
+
+func (svc *RoleDevService) UserPermissions(ctx context.Context, userId int) ([]string, []string, error) {
+	rs, err := svc.repo.FindByUserId(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+	roles := make([]string, 0, len(rs))
+	permissions := make([]string, 0, len(rs))
+	seen := make(map[string]struct{}, len(rs))
+	for _, r := range rs {
+		roles = append(roles, r.Name)
+		for _, p := range r.Permissions {
+			if _, ok := seen[p]; ok {
+				continue
+			}
+			seen[p] = struct{}{}
+			permissions = append(permissions, p)
+		}
+	}
+	return roles, permissions, nil
+}
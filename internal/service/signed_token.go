@@ -0,0 +1,85 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errSignedTokenInvalid = errors.New("token无效或已过期")
+
+// signedToken 邮件链接里用的无状态token 格式为 base64(uid.过期时间).base64(签名)
+// purpose区分用途 防止一种链接的token被拿去另一种链接用
+// 签名时可以绑定用户的数据 这些数据变了token就自动失效
+type signedToken struct {
+	secret  []byte
+	purpose string
+}
+
+func (s signedToken) sign(uid int, exp time.Time, bind ...string) string {
+	payload := fmt.Sprintf("%d.%d", uid, exp.Unix())
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.mac(payload, bind))
+}
+
+// parse 只校验格式和有效期 返回uid 用uid查出绑定的数据后再调用verify
+func (s signedToken) parse(token string) (int, error) {
+	payload, _, err := s.split(token)
+	if err != nil {
+		return 0, err
+	}
+	uidStr, expStr, ok := strings.Cut(payload, ".")
+	if !ok {
+		return 0, errSignedTokenInvalid
+	}
+	uid, err := strconv.Atoi(uidStr)
+	if err != nil {
+		return 0, errSignedTokenInvalid
+	}
+	exp, err := strconv.ParseInt(expStr, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return 0, errSignedTokenInvalid
+	}
+	return uid, nil
+}
+
+func (s signedToken) verify(token string, bind ...string) bool {
+	payload, sig, err := s.split(token)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(sig, s.mac(payload, bind))
+}
+
+func (s signedToken) split(token string) (string, []byte, error) {
+	encPayload, encSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", nil, errSignedTokenInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return "", nil, errSignedTokenInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil {
+		return "", nil, errSignedTokenInvalid
+	}
+	return string(payload), sig, nil
+}
+
+func (s signedToken) mac(payload string, bind []string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(s.purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(payload))
+	for _, b := range bind {
+		mac.Write([]byte{0})
+		mac.Write([]byte(b))
+	}
+	return mac.Sum(nil)
+}
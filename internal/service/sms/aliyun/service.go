@@ -0,0 +1,170 @@
+package aliyun
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	smsratelimit "webook/internal/service/sms/ratelimit"
+	"webook/internal/service/sms/templates"
+)
+
+const (
+	defaultEndpoint = "https://dysmsapi.aliyuncs.com/"
+	//服务商卡住时不能一直等 熔断器要能统计到
+	defaultTimeout = time.Second * 5
+)
+
+// Service 直接调用阿里云短信的RPC接口 签名算法见
+// https://help.aliyun.com/document_detail/101343.html
+type Service struct {
+	//在模板注册表里的名字
+	name            string
+	endpoint        string
+	accessKeyId     string
+	accessKeySecret string
+	signName        string
+	tpls            *templates.Registry
+	client          *http.Client
+}
+
+type Option func(s *Service)
+
+// WithEndpoint 本地联调时指向假的服务商
+func WithEndpoint(endpoint string) Option {
+	return func(s *Service) {
+		s.endpoint = endpoint
+	}
+}
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Service) {
+		s.client = client
+	}
+}
+
+func NewService(name string, accessKeyId string, accessKeySecret string, signName string,
+	tpls *templates.Registry, opts ...Option) *Service {
+	s := &Service{
+		name:            name,
+		endpoint:        defaultEndpoint,
+		accessKeyId:     accessKeyId,
+		accessKeySecret: accessKeySecret,
+		signName:        signName,
+		tpls:            tpls,
+		client:          &http.Client{Timeout: defaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+type response struct {
+	Code      string
+	Message   string
+	BizId     string
+	RequestId string
+}
+
+func (s *Service) Send(ctx context.Context, biz string, args []string, numbers ...string) error {
+	tpl, err := s.tpls.Find(s.name, biz, args)
+	if err != nil {
+		return err
+	}
+	tplParam, err := s.templateParam(tpl, args)
+	if err != nil {
+		return err
+	}
+	signName := tpl.SignName
+	if signName == "" {
+		signName = s.signName
+	}
+	nonce := make([]byte, 16)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	query.Set("AccessKeyId", s.accessKeyId)
+	query.Set("Action", "SendSms")
+	query.Set("Format", "JSON")
+	query.Set("PhoneNumbers", strings.Join(numbers, ","))
+	query.Set("RegionId", "cn-hangzhou")
+	query.Set("SignName", signName)
+	query.Set("SignatureMethod", "HMAC-SHA1")
+	query.Set("SignatureNonce", hex.EncodeToString(nonce))
+	query.Set("SignatureVersion", "1.0")
+	query.Set("TemplateCode", tpl.Id)
+	query.Set("TemplateParam", tplParam)
+	query.Set("Timestamp", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	query.Set("Version", "2017-05-25")
+	query.Set("Signature", s.sign(http.MethodGet, query))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var res response
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return fmt.Errorf("解析阿里云短信响应失败,http状态码%d,%w", resp.StatusCode, err)
+	}
+	switch {
+	case res.Code == "OK":
+		return nil
+	case res.Code == "isv.BUSINESS_LIMIT_CONTROL" || strings.HasPrefix(res.Code, "Throttling"):
+		return fmt.Errorf("%w,%s", smsratelimit.ErrLimited, res.Message)
+	default:
+		return fmt.Errorf("发送短信失败%s,%s", res.Code, res.Message)
+	}
+}
+
+// templateParam 阿里云的模板参数是具名的 参数个数已经由注册表校验过
+func (s *Service) templateParam(tpl templates.Template, args []string) (string, error) {
+	m := make(map[string]string, len(args))
+	for i, name := range tpl.Params {
+		m[name] = args[i]
+	}
+	data, err := json.Marshal(m)
+	return string(data), err
+}
+
+// sign 参数按key排序之后拼接 用HMAC-SHA1签名 密钥是AccessKeySecret加上&
+func (s *Service) sign(method string, query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, encode(k)+"="+encode(query.Get(k)))
+	}
+	str := method + "&" + encode("/") + "&" + encode(strings.Join(pairs, "&"))
+	mac := hmac.New(sha1.New, []byte(s.accessKeySecret+"&"))
+	mac.Write([]byte(str))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// encode 阿里云要求的RFC3986编码
+func encode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
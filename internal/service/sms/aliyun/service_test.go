@@ -0,0 +1,39 @@
+package aliyun
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+	"webook/internal/service/sms/templates"
+)
+
+// 阿里云文档里的签名示例
+func TestService_sign(t *testing.T) {
+	query := url.Values{}
+	query.Set("AccessKeyId", "testId")
+	query.Set("Action", "SendSms")
+	query.Set("Format", "XML")
+	query.Set("OutId", "123")
+	query.Set("PhoneNumbers", "15300000001")
+	query.Set("RegionId", "cn-hangzhou")
+	query.Set("SignName", "阿里云短信测试专用")
+	query.Set("SignatureMethod", "HMAC-SHA1")
+	query.Set("SignatureNonce", "45e25e9b-0a6f-4070-8c85-2956eda1b466")
+	query.Set("SignatureVersion", "1.0")
+	query.Set("TemplateCode", "SMS_71390007")
+	query.Set("TemplateParam", `{"customer":"test"}`)
+	query.Set("Timestamp", "2017-07-12T02:42:19Z")
+	query.Set("Version", "2017-05-25")
+
+	svc := NewService("aliyun", "testId", "testSecret", "阿里云短信测试专用", nil)
+	assert.Equal(t, "zJDF+Lrzhj/ThnlvIToysFRq6t4=", svc.sign(http.MethodGet, query))
+}
+
+func TestService_templateParam(t *testing.T) {
+	svc := NewService("aliyun", "testId", "testSecret", "webook", nil)
+	param, err := svc.templateParam(templates.Template{Id: "SMS_1", Params: []string{"code", "minutes"}},
+		[]string{"123456", "5"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"code":"123456","minutes":"5"}`, param)
+}
@@ -0,0 +1,262 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	"webook/internal/service/sms"
+	smsratelimit "webook/internal/service/sms/ratelimit"
+)
+
+const (
+	defaultWorkers      = 4
+	defaultMaxAttempts  = 5
+	defaultBatch        = 16
+	defaultPollInterval = time.Second
+	//worker抢占任务之后 超过这个时间没有结束就认为worker挂了
+	defaultLease = time.Minute
+	sendTimeout  = time.Second * 10
+
+	//第n次重试等待 retryBase*2^(n-1) 最多retryMax
+	retryBase = time.Second * 2
+	retryMax  = time.Minute * 10
+
+	//最近windowSize次同步发送里 至少minSamples次并且失败率达到errRate 就转异步
+	windowSize = 50
+	minSamples = 10
+	errRate    = 0.2
+	//转异步之后多久再尝试同步发送
+	asyncDuration = time.Minute
+)
+
+// Service 服务商正常时同步发送 失败或者限流时把任务存到数据库 由worker按指数退避重试
+type Service struct {
+	svc  sms.Service
+	repo repository.AsyncSMSRepository
+
+	workers      int
+	maxAttempts  int
+	batch        int
+	pollInterval time.Duration
+	lease        time.Duration
+	//这些业务只同步发送 失败直接返回 例如验证码 不能明文存到数据库 过期之后再送达也没有意义
+	syncOnly map[string]struct{}
+
+	mu sync.Mutex
+	//最近同步发送的结果 true表示失败 环形使用
+	results []bool
+	pos     int
+	filled  int
+	//在这个时间之前新的请求都直接转异步 ms
+	asyncUntil atomic.Int64
+}
+
+type Option func(s *Service)
+
+func WithWorkers(n int) Option {
+	return func(s *Service) {
+		s.workers = n
+	}
+}
+
+func WithMaxAttempts(n int) Option {
+	return func(s *Service) {
+		s.maxAttempts = n
+	}
+}
+
+func WithPollInterval(interval time.Duration) Option {
+	return func(s *Service) {
+		s.pollInterval = interval
+	}
+}
+
+func WithSyncOnly(bizes ...string) Option {
+	return func(s *Service) {
+		for _, biz := range bizes {
+			s.syncOnly[biz] = struct{}{}
+		}
+	}
+}
+
+func NewService(svc sms.Service, repo repository.AsyncSMSRepository, opts ...Option) *Service {
+	s := &Service{
+		svc:          svc,
+		repo:         repo,
+		workers:      defaultWorkers,
+		maxAttempts:  defaultMaxAttempts,
+		batch:        defaultBatch,
+		pollInterval: defaultPollInterval,
+		lease:        defaultLease,
+		syncOnly:     map[string]struct{}{},
+		results:      make([]bool, windowSize),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Send 转异步或者同步发送失败时 任务存下来就返回nil 由worker负责送达
+func (s *Service) Send(ctx context.Context, biz string, args []string, numbers ...string) error {
+	if _, ok := s.syncOnly[biz]; ok {
+		err := s.svc.Send(ctx, biz, args, numbers...)
+		s.record(err)
+		return err
+	}
+	if s.isAsync() {
+		return s.enqueue(ctx, biz, args, numbers, 0, "")
+	}
+	err := s.svc.Send(ctx, biz, args, numbers...)
+	s.record(err)
+	if err == nil {
+		return nil
+	}
+	er := s.enqueue(ctx, biz, args, numbers, 1, err.Error())
+	if er != nil {
+		//存都存不进去 只能让调用方知道没发出去
+		log.Printf("短信转异步失败,biz:%s,err:%v", biz, er)
+		return err
+	}
+	return nil
+}
+
+// Start 启动worker 阻塞直到ctx被取消
+func (s *Service) Start(ctx context.Context) {
+	tasks := make(chan domain.AsyncSMS)
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range tasks {
+				s.retry(ctx, task)
+			}
+		}()
+	}
+	ticker := time.NewTicker(s.pollInterval)
+	defer func() {
+		ticker.Stop()
+		close(tasks)
+		wg.Wait()
+	}()
+	for {
+		s.dispatch(ctx, tasks)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch 没来得及交给worker的任务 等租约到期后会被重新抢占
+func (s *Service) dispatch(ctx context.Context, tasks chan<- domain.AsyncSMS) {
+	list, err := s.repo.Preempt(ctx, s.lease, s.batch)
+	if err != nil {
+		log.Printf("抢占短信任务失败,err:%v", err)
+	}
+	for _, task := range list {
+		select {
+		case tasks <- task:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Service) retry(ctx context.Context, task domain.AsyncSMS) {
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	err := s.svc.Send(sendCtx, task.Biz, task.Args, task.Numbers...)
+	cancel()
+	if err == nil {
+		if er := s.repo.MarkSuccess(ctx, task.Id); er != nil {
+			log.Printf("更新短信任务失败,id:%d,err:%v", task.Id, er)
+		}
+		return
+	}
+	attempts := task.Attempts + 1
+	var next time.Time
+	if attempts < task.MaxAttempts {
+		next = time.Now().Add(s.backoff(attempts))
+	} else {
+		log.Printf("短信重试次数用完,id:%d,biz:%s,err:%v", task.Id, task.Biz, err)
+	}
+	if er := s.repo.MarkRetry(ctx, task.Id, attempts, err.Error(), next); er != nil {
+		log.Printf("更新短信任务失败,id:%d,err:%v", task.Id, er)
+	}
+}
+
+// enqueue attempts为已经尝试过的次数 0表示马上发
+func (s *Service) enqueue(ctx context.Context, biz string, args []string, numbers []string,
+	attempts int, lastErr string) error {
+	next := time.Now()
+	if attempts > 0 {
+		next = next.Add(s.backoff(attempts))
+	}
+	return s.repo.Add(ctx, domain.AsyncSMS{
+		Biz:         biz,
+		Args:        args,
+		Numbers:     numbers,
+		Attempts:    attempts,
+		MaxAttempts: s.maxAttempts,
+		LastErr:     lastErr,
+		NextRetry:   next,
+	})
+}
+
+// backoff 加上最多20%的随机抖动 避免大量任务同时重试
+func (s *Service) backoff(attempts int) time.Duration {
+	d := retryMax
+	if shift := attempts - 1; shift < 20 {
+		d = retryBase << shift
+		if d > retryMax {
+			d = retryMax
+		}
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/5+1))
+}
+
+func (s *Service) isAsync() bool {
+	return time.Now().UnixMilli() < s.asyncUntil.Load()
+}
+
+// record 限流直接转异步 失败率太高也转异步
+func (s *Service) record(err error) {
+	if errors.Is(err, smsratelimit.ErrLimited) {
+		s.switchAsync("触发了限流")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[s.pos] = err != nil
+	s.pos = (s.pos + 1) % len(s.results)
+	if s.filled < len(s.results) {
+		s.filled++
+	}
+	if s.filled < minSamples {
+		return
+	}
+	failed := 0
+	for i := 0; i < s.filled; i++ {
+		if s.results[i] {
+			failed++
+		}
+	}
+	if float64(failed)/float64(s.filled) >= errRate {
+		s.switchAsync("失败率太高")
+		//重新统计
+		s.pos, s.filled = 0, 0
+	}
+}
+
+func (s *Service) switchAsync(reason string) {
+	s.asyncUntil.Store(time.Now().Add(asyncDuration).UnixMilli())
+	log.Printf("短信服务%s,%v内转为异步发送", reason, asyncDuration)
+}
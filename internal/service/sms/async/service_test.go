@@ -0,0 +1,241 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	repomocks "webook/internal/repository/mocks"
+	"webook/internal/service/sms"
+	"webook/internal/service/sms/failover"
+	smsmocks "webook/internal/service/sms/mocks"
+	smsratelimit "webook/internal/service/sms/ratelimit"
+)
+
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSMSRepository)
+		//Send之前准备状态
+		before func(s *Service)
+
+		wantErr   error
+		wantAsync bool
+	}{
+		{
+			name: "同步发送成功",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", []string{"123456"}, "15212345678").Return(nil)
+				return svc, repomocks.NewMockAsyncSMSRepository(ctrl)
+			},
+		},
+		{
+			name: "同步发送失败 存下来重试",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", []string{"123456"}, "15212345678").
+					Return(errors.New("服务商出错"))
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, task domain.AsyncSMS) error {
+						assert.Equal(t, 1, task.Attempts)
+						assert.Equal(t, defaultMaxAttempts, task.MaxAttempts)
+						assert.Equal(t, "服务商出错", task.LastErr)
+						assert.True(t, task.NextRetry.After(time.Now()))
+						return nil
+					})
+				return svc, repo
+			},
+		},
+		{
+			name: "同步发送失败 存也存不进去",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", []string{"123456"}, "15212345678").
+					Return(errors.New("服务商出错"))
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(errors.New("数据库出错"))
+				return svc, repo
+			},
+			wantErr: errors.New("服务商出错"),
+		},
+		{
+			name: "触发限流 转异步",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", []string{"123456"}, "15212345678").
+					Return(smsratelimit.ErrLimited)
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil)
+				return svc, repo
+			},
+			wantAsync: true,
+		},
+		{
+			name: "失败率太高 转异步",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", []string{"123456"}, "15212345678").
+					Return(errors.New("服务商出错"))
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil)
+				return svc, repo
+			},
+			before: func(s *Service) {
+				//之前9次 已经失败1次
+				s.results[0] = true
+				s.pos, s.filled = minSamples-1, minSamples-1
+			},
+			wantAsync: true,
+		},
+		{
+			name: "异步模式 直接存下来",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSMSRepository) {
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, task domain.AsyncSMS) error {
+						assert.Equal(t, 0, task.Attempts)
+						assert.Equal(t, []string{"15212345678"}, task.Numbers)
+						return nil
+					})
+				return smsmocks.NewMockService(ctrl), repo
+			},
+			before: func(s *Service) {
+				s.asyncUntil.Store(time.Now().Add(time.Minute).UnixMilli())
+			},
+			wantAsync: true,
+		},
+		{
+			name: "验证码发送失败 不存下来",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", []string{"123456"}, "15212345678").
+					Return(errors.New("服务商出错"))
+				return svc, repomocks.NewMockAsyncSMSRepository(ctrl)
+			},
+			before: func(s *Service) {
+				WithSyncOnly("login")(s)
+			},
+			wantErr: errors.New("服务商出错"),
+		},
+		{
+			name: "异步模式 验证码也同步发送",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", []string{"123456"}, "15212345678").Return(nil)
+				return svc, repomocks.NewMockAsyncSMSRepository(ctrl)
+			},
+			before: func(s *Service) {
+				WithSyncOnly("login")(s)
+				s.asyncUntil.Store(time.Now().Add(time.Minute).UnixMilli())
+			},
+			wantAsync: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc, repo := tc.mock(ctrl)
+			s := NewService(svc, repo)
+			if tc.before != nil {
+				tc.before(s)
+			}
+			err := s.Send(context.Background(), "login", []string{"123456"}, "15212345678")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantAsync, s.isAsync())
+		})
+	}
+}
+
+func TestService_Send_Failover(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	//两家都限流 failover返回的错误要能识别出限流
+	svc0 := smsmocks.NewMockService(ctrl)
+	svc0.EXPECT().Send(gomock.Any(), "order_notify", gomock.Any(), gomock.Any()).Return(smsratelimit.ErrLimited)
+	svc1 := smsmocks.NewMockService(ctrl)
+	svc1.EXPECT().Send(gomock.Any(), "order_notify", gomock.Any(), gomock.Any()).Return(smsratelimit.ErrLimited)
+	repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+	repo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil)
+
+	s := NewService(failover.NewService([]failover.Provider{
+		{Name: "p0", Svc: svc0},
+		{Name: "p1", Svc: svc1},
+	}), repo)
+	err := s.Send(context.Background(), "order_notify", []string{"1001"}, "15212345678")
+	assert.NoError(t, err)
+	assert.True(t, s.isAsync())
+}
+
+func TestService_retry(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSMSRepository)
+
+		task domain.AsyncSMS
+	}{
+		{
+			name: "重试成功",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", []string{"123456"}, "15212345678").Return(nil)
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().MarkSuccess(gomock.Any(), 1).Return(nil)
+				return svc, repo
+			},
+			task: domain.AsyncSMS{Id: 1, Biz: "login", Args: []string{"123456"},
+				Numbers: []string{"15212345678"}, Attempts: 1, MaxAttempts: 5},
+		},
+		{
+			name: "重试失败 等待时间翻倍",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", []string{"123456"}, "15212345678").
+					Return(errors.New("服务商出错"))
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().MarkRetry(gomock.Any(), 1, 3, "服务商出错", gomock.Any()).DoAndReturn(
+					func(ctx context.Context, id int, attempts int, lastErr string, next time.Time) error {
+						//第3次失败 等待8s 加上最多20%的抖动
+						wait := time.Until(next)
+						assert.True(t, wait > time.Second*7 && wait <= time.Second*10)
+						return nil
+					})
+				return svc, repo
+			},
+			task: domain.AsyncSMS{Id: 1, Biz: "login", Args: []string{"123456"},
+				Numbers: []string{"15212345678"}, Attempts: 2, MaxAttempts: 5},
+		},
+		{
+			name: "次数用完 不再重试",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", []string{"123456"}, "15212345678").
+					Return(errors.New("服务商出错"))
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().MarkRetry(gomock.Any(), 1, 5, "服务商出错", time.Time{}).Return(nil)
+				return svc, repo
+			},
+			task: domain.AsyncSMS{Id: 1, Biz: "login", Args: []string{"123456"},
+				Numbers: []string{"15212345678"}, Attempts: 4, MaxAttempts: 5},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc, repo := tc.mock(ctrl)
+			NewService(svc, repo).retry(context.Background(), tc.task)
+		})
+	}
+}
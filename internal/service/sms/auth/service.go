@@ -3,34 +3,156 @@ package auth
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"time"
 	"webook/internal/service/sms"
+	"webook/internal/service/sms/templates"
+	"webook/pkg/ratelimit"
 )
 
+var (
+	ErrTokenInvalid   = errors.New("业务方token不合法")
+	ErrQuotaExceeded  = errors.New("业务方超过了发送配额")
+	ErrTTLInvalid     = errors.New("token有效期不合法")
+	ErrTplDenied      = errors.New("这个模板不能给业务方使用")
+	ErrTooManyNumbers = errors.New("一次发送的手机号太多")
+)
+
+// MaxNumbers 一次最多发给多少个手机号 转异步时要能存进async_sms.numbers
+const MaxNumbers = 50
+
+// SMSService 内部短信网关 其他服务拿着webook签发的token调用 token里带着业务方和能用的模板
 type SMSService struct {
 	svc sms.Service
-	key string
+	key []byte
+	//吊销的token 按jti存到token过期
+	cmd redis.Cmdable
+	//窗口内的发送次数 窗口由注入的Counter决定
+	counter ratelimit.Counter
+	//只能给业务方签发注册表里有的模板
+	tpls *templates.Registry
+	//验证码之类的模板不能给业务方用 否则可以冒充webook发验证码
+	denied map[string]struct{}
+	maxTTL time.Duration
+	//业务方在窗口内最多发给多少个手机号 没有配置的用defaultQuota
+	quotas       map[string]int
+	defaultQuota int
+}
+
+func NewSMSService(svc sms.Service, key []byte, cmd redis.Cmdable, counter ratelimit.Counter,
+	tpls *templates.Registry, denied []string, maxTTL time.Duration,
+	quotas map[string]int, defaultQuota int) *SMSService {
+	s := &SMSService{
+		svc:          svc,
+		key:          key,
+		cmd:          cmd,
+		counter:      counter,
+		tpls:         tpls,
+		denied:       make(map[string]struct{}, len(denied)),
+		maxTTL:       maxTTL,
+		quotas:       quotas,
+		defaultQuota: defaultQuota,
+	}
+	for _, biz := range denied {
+		s.denied[biz] = struct{}{}
+	}
+	return s
+}
+
+// IssueToken 给业务方签发token tpl是模板注册表里的biz ttl必须在(0,maxTTL]之间
+func (s *SMSService) IssueToken(caller string, tpl string, ttl time.Duration) (string, error) {
+	if ttl <= 0 || ttl > s.maxTTL {
+		return "", fmt.Errorf("%w,最长%v", ErrTTLInvalid, s.maxTTL)
+	}
+	if _, ok := s.denied[tpl]; ok {
+		return "", ErrTplDenied
+	}
+	if !s.tpls.Has(tpl) {
+		return "", fmt.Errorf("%w,biz:%s", templates.ErrTemplateNotFind, tpl)
+	}
+	now := time.Now()
+	tc := TokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Caller: caller,
+		Tpl:    tpl,
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, tc).SignedString(s.key)
+}
+
+// Revoke 吊销token 过期之后自动从黑名单里删掉
+func (s *SMSService) Revoke(ctx context.Context, token string) error {
+	tc, err := s.parse(token)
+	if err != nil {
+		return err
+	}
+	return s.cmd.Set(ctx, s.revokedKey(tc.ID), tc.Caller, time.Until(tc.ExpiresAt.Time)).Err()
 }
 
+// Send biz是IssueToken签发的token 实际使用token里的模板
 func (s *SMSService) Send(ctx context.Context, biz string, args []string, numbers ...string) error {
-	var tc TokenClaims
+	tc, err := s.parse(biz)
+	if err != nil {
+		return err
+	}
+	if _, ok := s.denied[tc.Tpl]; ok {
+		return ErrTplDenied
+	}
+	if len(numbers) > MaxNumbers {
+		return ErrTooManyNumbers
+	}
+	revoked, err := s.cmd.Exists(ctx, s.revokedKey(tc.ID)).Result()
+	if err != nil {
+		return fmt.Errorf("短信网关查询token是否吊销出现问题,%w", err)
+	}
+	if revoked > 0 {
+		return ErrTokenInvalid
+	}
+	//按手机号个数计算配额
+	cnt, err := s.counter.IncrBy(ctx, "sms:gateway:"+tc.Caller, len(numbers))
+	if err != nil {
+		return fmt.Errorf("短信网关统计配额出现问题,%w", err)
+	}
+	if cnt > s.quota(tc.Caller) {
+		return ErrQuotaExceeded
+	}
+	return s.svc.Send(ctx, tc.Tpl, args, numbers...)
+}
 
+// parse token必须带jti和过期时间
+func (s *SMSService) parse(token string) (TokenClaims, error) {
+	var tc TokenClaims
 	//如果成功解析,说明就是对应的业务方
 	//没有error就说明,token是我发的
-	token, err := jwt.ParseWithClaims(biz, &tc, func(token *jwt.Token) (interface{}, error) {
+	t, err := jwt.ParseWithClaims(token, &tc, func(token *jwt.Token) (interface{}, error) {
 		return s.key, nil
-	})
-	if err != nil {
-		return err
-	}
-	if !token.Valid {
-		return errors.New("token不合法")
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !t.Valid || tc.Caller == "" || tc.Tpl == "" || tc.ID == "" || tc.ExpiresAt == nil {
+		return TokenClaims{}, ErrTokenInvalid
 	}
+	return tc, nil
+}
+
+func (s *SMSService) revokedKey(jti string) string {
+	return "sms:gateway:revoked:" + jti
+}
 
-	return s.svc.Send(ctx, biz, args, numbers...)
+func (s *SMSService) quota(caller string) int {
+	if q, ok := s.quotas[caller]; ok {
+		return q
+	}
+	return s.defaultQuota
 }
 
 type TokenClaims struct {
 	jwt.RegisteredClaims
-	Tpl string
+	//业务方 按业务方统计配额
+	Caller string
+	Tpl    string
 }
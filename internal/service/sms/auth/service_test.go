@@ -0,0 +1,283 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"webook/internal/repository/redismocks"
+	"webook/internal/service/sms"
+	smsmocks "webook/internal/service/sms/mocks"
+	"webook/internal/service/sms/templates"
+	pkgmocks "webook/pkg/mocks"
+	"webook/pkg/ratelimit"
+)
+
+var testKey = []byte("a3f0a1c7e9b24d6f8e5c2b1a0d9f7e6c")
+
+func newTestService(svc sms.Service, key []byte, cmd redis.Cmdable, counter ratelimit.Counter) *SMSService {
+	tpls := templates.NewRegistry()
+	tpls.Register("order_notify", "tencent", templates.Template{Id: "1000003", Params: []string{"order"}})
+	tpls.Register("search_alert", "tencent", templates.Template{Id: "1000004", Params: []string{"word"}})
+	tpls.Register("login", "tencent", templates.Template{Id: "1000001", Params: []string{"code"}})
+	return NewSMSService(svc, key, cmd, counter, tpls, []string{"login"}, time.Hour*24,
+		map[string]int{"order": 10}, 5)
+}
+
+// notRevoked token没有被吊销
+func notRevoked(ctrl *gomock.Controller) redis.Cmdable {
+	cmd := redismocks.NewMockCmdable(ctrl)
+	res := redis.NewIntCmd(context.Background())
+	res.SetVal(0)
+	cmd.EXPECT().Exists(gomock.Any(), gomock.Any()).Return(res)
+	return cmd
+}
+
+// signToken 直接签名 模拟修复之前签发的或者已经过期的token
+func signToken(t *testing.T, tc TokenClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, tc).SignedString(testKey)
+	require.NoError(t, err)
+	return token
+}
+
+func TestSMSService_Send(t *testing.T) {
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter)
+		token func(t *testing.T, s *SMSService) string
+		//为空时发给一个手机号
+		numbers []string
+
+		wantErr error
+	}{
+		{
+			name: "使用token里的模板发送",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				counter := pkgmocks.NewMockCounter(ctrl)
+				counter.EXPECT().IncrBy(gomock.Any(), "sms:gateway:order", 1).Return(1, nil)
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "order_notify", []string{"1001"}, "15212345678").Return(nil)
+				return svc, notRevoked(ctrl), counter
+			},
+			token: func(t *testing.T, s *SMSService) string {
+				token, err := s.IssueToken("order", "order_notify", time.Hour)
+				require.NoError(t, err)
+				return token
+			},
+		},
+		{
+			name: "按手机号个数计算配额",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				counter := pkgmocks.NewMockCounter(ctrl)
+				counter.EXPECT().IncrBy(gomock.Any(), "sms:gateway:order", 3).Return(3, nil)
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "order_notify", []string{"1001"},
+					"15212345678", "15212345679", "15212345670").Return(nil)
+				return svc, notRevoked(ctrl), counter
+			},
+			token: func(t *testing.T, s *SMSService) string {
+				token, err := s.IssueToken("order", "order_notify", time.Hour)
+				require.NoError(t, err)
+				return token
+			},
+			numbers: []string{"15212345678", "15212345679", "15212345670"},
+		},
+		{
+			name: "一次发送的手机号太多",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				return nil, nil, nil
+			},
+			token: func(t *testing.T, s *SMSService) string {
+				token, err := s.IssueToken("order", "order_notify", time.Hour)
+				require.NoError(t, err)
+				return token
+			},
+			numbers: make([]string, MaxNumbers+1),
+			wantErr: ErrTooManyNumbers,
+		},
+		{
+			name: "不是webook签发的token",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				return nil, nil, nil
+			},
+			token: func(t *testing.T, s *SMSService) string {
+				token, err := newTestService(nil, []byte("other"), nil, nil).
+					IssueToken("order", "order_notify", time.Hour)
+				require.NoError(t, err)
+				return token
+			},
+			wantErr: ErrTokenInvalid,
+		},
+		{
+			name: "token过期",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				return nil, nil, nil
+			},
+			token: func(t *testing.T, s *SMSService) string {
+				return signToken(t, TokenClaims{
+					RegisteredClaims: jwt.RegisteredClaims{
+						ID:        "jti",
+						ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+					},
+					Caller: "order",
+					Tpl:    "order_notify",
+				})
+			},
+			wantErr: ErrTokenInvalid,
+		},
+		{
+			name: "之前签发的永久token",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				return nil, nil, nil
+			},
+			token: func(t *testing.T, s *SMSService) string {
+				return signToken(t, TokenClaims{Caller: "order", Tpl: "order_notify"})
+			},
+			wantErr: ErrTokenInvalid,
+		},
+		{
+			name: "直接把模板当token",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				return nil, nil, nil
+			},
+			token: func(t *testing.T, s *SMSService) string {
+				return "order_notify"
+			},
+			wantErr: ErrTokenInvalid,
+		},
+		{
+			name: "token已经吊销",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewIntCmd(context.Background())
+				res.SetVal(1)
+				cmd.EXPECT().Exists(gomock.Any(), gomock.Any()).Return(res)
+				return nil, cmd, nil
+			},
+			token: func(t *testing.T, s *SMSService) string {
+				token, err := s.IssueToken("order", "order_notify", time.Hour)
+				require.NoError(t, err)
+				return token
+			},
+			wantErr: ErrTokenInvalid,
+		},
+		{
+			name: "token里是验证码模板",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				return nil, nil, nil
+			},
+			token: func(t *testing.T, s *SMSService) string {
+				return signToken(t, TokenClaims{
+					RegisteredClaims: jwt.RegisteredClaims{
+						ID:        "jti",
+						ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+					},
+					Caller: "order",
+					Tpl:    "login",
+				})
+			},
+			wantErr: ErrTplDenied,
+		},
+		{
+			name: "超过配置的配额",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				counter := pkgmocks.NewMockCounter(ctrl)
+				counter.EXPECT().IncrBy(gomock.Any(), "sms:gateway:order", 1).Return(11, nil)
+				return nil, notRevoked(ctrl), counter
+			},
+			token: func(t *testing.T, s *SMSService) string {
+				token, err := s.IssueToken("order", "order_notify", time.Hour)
+				require.NoError(t, err)
+				return token
+			},
+			wantErr: ErrQuotaExceeded,
+		},
+		{
+			name: "超过默认配额",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				counter := pkgmocks.NewMockCounter(ctrl)
+				counter.EXPECT().IncrBy(gomock.Any(), "sms:gateway:search", 1).Return(6, nil)
+				return nil, notRevoked(ctrl), counter
+			},
+			token: func(t *testing.T, s *SMSService) string {
+				token, err := s.IssueToken("search", "search_alert", time.Hour)
+				require.NoError(t, err)
+				return token
+			},
+			wantErr: ErrQuotaExceeded,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc, cmd, counter := tc.mock(ctrl)
+			s := newTestService(svc, testKey, cmd, counter)
+			numbers := tc.numbers
+			if numbers == nil {
+				numbers = []string{"15212345678"}
+			}
+			err := s.Send(context.Background(), tc.token(t, s), []string{"1001"}, numbers...)
+			assert.True(t, errors.Is(err, tc.wantErr))
+		})
+	}
+}
+
+func TestSMSService_IssueToken(t *testing.T) {
+	testCases := []struct {
+		name string
+		tpl  string
+		ttl  time.Duration
+
+		wantErr error
+	}{
+		{name: "签发成功", tpl: "order_notify", ttl: time.Hour},
+		{name: "不能签发永久token", tpl: "order_notify", ttl: 0, wantErr: ErrTTLInvalid},
+		{name: "有效期太长", tpl: "order_notify", ttl: time.Hour * 25, wantErr: ErrTTLInvalid},
+		{name: "验证码模板", tpl: "login", ttl: time.Hour, wantErr: ErrTplDenied},
+		{name: "模板没有注册", tpl: "unknown", ttl: time.Hour, wantErr: templates.ErrTemplateNotFind},
+	}
+
+	s := newTestService(nil, testKey, nil, nil)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := s.IssueToken("order", tc.tpl, tc.ttl)
+			assert.True(t, errors.Is(err, tc.wantErr))
+			if tc.wantErr != nil {
+				return
+			}
+			claims, err := s.parse(token)
+			require.NoError(t, err)
+			assert.NotEmpty(t, claims.ID)
+			assert.WithinDuration(t, time.Now().Add(tc.ttl), claims.ExpiresAt.Time, time.Second)
+		})
+	}
+}
+
+func TestSMSService_Revoke(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := redismocks.NewMockCmdable(ctrl)
+	s := newTestService(nil, testKey, cmd, nil)
+	token, err := s.IssueToken("order", "order_notify", time.Hour)
+	require.NoError(t, err)
+	claims, err := s.parse(token)
+	require.NoError(t, err)
+
+	//黑名单存到token过期
+	cmd.EXPECT().Set(gomock.Any(), "sms:gateway:revoked:"+claims.ID, "order", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, key string, val any, exp time.Duration) *redis.StatusCmd {
+			assert.InDelta(t, float64(time.Hour), float64(exp), float64(time.Second*2))
+			return redis.NewStatusCmd(ctx)
+		})
+	assert.NoError(t, s.Revoke(context.Background(), token))
+	assert.Equal(t, ErrTokenInvalid, s.Revoke(context.Background(), "order_notify"))
+}
@@ -0,0 +1,162 @@
+package failover
+
+import (
+	"sync"
+	"time"
+)
+
+// State 熔断器状态
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half_open"
+)
+
+// call 一次调用的结果
+type call struct {
+	failed  bool
+	latency time.Duration
+}
+
+// breaker 每个服务商一个 统计最近cfg.window次调用的失败率和慢调用比例
+type breaker struct {
+	cfg *config
+
+	mu        sync.Mutex
+	state     State
+	openUntil time.Time
+	//半开状态下正在进行的探测 和已经成功的探测
+	probing int
+	probeOK int
+
+	//环形使用
+	calls  []call
+	pos    int
+	filled int
+}
+
+func newBreaker(cfg *config) *breaker {
+	return &breaker{
+		cfg:   cfg,
+		state: StateClosed,
+		calls: make([]call, cfg.window),
+	}
+}
+
+// allow 返回能不能调用 probe表示这次调用是半开状态下的探测
+func (b *breaker) allow(now time.Time) (probe bool, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case StateOpen:
+		if now.Before(b.openUntil) {
+			return false, false
+		}
+		b.state, b.probing, b.probeOK = StateHalfOpen, 0, 0
+		fallthrough
+	case StateHalfOpen:
+		if b.probing >= b.cfg.probes {
+			return false, false
+		}
+		b.probing++
+		return true, true
+	default:
+		return false, true
+	}
+}
+
+// record 状态切换之后 之前发出去的调用结果不再统计
+func (b *breaker) record(now time.Time, probe bool, failed bool, latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	slow := latency >= b.cfg.slowCall
+	if probe {
+		if b.state != StateHalfOpen {
+			return
+		}
+		b.probing--
+		if failed || slow {
+			b.trip(now)
+			return
+		}
+		b.probeOK++
+		if b.probeOK >= b.cfg.probes {
+			//恢复了 重新统计
+			b.state, b.pos, b.filled = StateClosed, 0, 0
+		}
+		return
+	}
+	if b.state != StateClosed {
+		return
+	}
+	b.calls[b.pos] = call{failed: failed, latency: latency}
+	b.pos = (b.pos + 1) % len(b.calls)
+	if b.filled < len(b.calls) {
+		b.filled++
+	}
+	if b.filled < b.cfg.minSamples {
+		return
+	}
+	errRate, slowRate, _ := b.stats()
+	if errRate >= b.cfg.errRate || slowRate >= b.cfg.slowRate {
+		b.trip(now)
+	}
+}
+
+// release 调用没有结果 比如触发了限流 只归还探测名额
+func (b *breaker) release(probe bool) {
+	if !probe {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateHalfOpen && b.probing > 0 {
+		b.probing--
+	}
+}
+
+func (b *breaker) trip(now time.Time) {
+	b.state = StateOpen
+	b.openUntil = now.Add(b.cfg.openTimeout)
+	b.pos, b.filled = 0, 0
+}
+
+// stats 调用方持有锁
+func (b *breaker) stats() (errRate float64, slowRate float64, avgLatency time.Duration) {
+	if b.filled == 0 {
+		return 0, 0, 0
+	}
+	var failed, slow int
+	var total time.Duration
+	for i := 0; i < b.filled; i++ {
+		c := b.calls[i]
+		if c.failed {
+			failed++
+		}
+		if c.latency >= b.cfg.slowCall {
+			slow++
+		}
+		total += c.latency
+	}
+	n := float64(b.filled)
+	return float64(failed) / n, float64(slow) / n, total / time.Duration(b.filled)
+}
+
+func (b *breaker) health() ProviderHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	errRate, slowRate, avg := b.stats()
+	h := ProviderHealth{
+		State:      b.state,
+		Samples:    b.filled,
+		ErrRate:    errRate,
+		SlowRate:   slowRate,
+		AvgLatency: avg,
+	}
+	if b.state == StateOpen {
+		h.OpenUntil = b.openUntil
+	}
+	return h
+}
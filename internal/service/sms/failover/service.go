@@ -3,49 +3,155 @@ package failover
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
-	"sync/atomic"
+	"time"
 	"webook/internal/service/sms"
+	smsratelimit "webook/internal/service/sms/ratelimit"
+	"webook/internal/service/sms/templates"
 )
 
-type SMSFailoverService struct {
-	services []sms.Service
-	idx      uint64
+var (
+	ErrAllFailed  = errors.New("全部短信服务商都失败了")
+	ErrNoProvider = errors.New("没有可用的短信服务商")
+)
+
+const (
+	defaultWindow     = 100
+	defaultMinSamples = 10
+	defaultErrRate    = 0.5
+	//超过这个时间的调用算慢调用
+	defaultSlowCall = time.Second * 3
+	defaultSlowRate = 0.5
+	//熔断之后多久进入半开状态
+	defaultOpenTimeout = time.Second * 30
+	//半开状态下连续这么多次探测成功就恢复
+	defaultProbes = 3
+)
+
+type config struct {
+	window      int
+	minSamples  int
+	errRate     float64
+	slowCall    time.Duration
+	slowRate    float64
+	openTimeout time.Duration
+	probes      int
 }
 
-func NewSMSFailoverService(services []sms.Service) *SMSFailoverService {
-	return &SMSFailoverService{services: services}
+type Option func(c *config)
+
+func WithErrRate(rate float64) Option {
+	return func(c *config) {
+		c.errRate = rate
+	}
 }
 
-// Send 服务轮询
-func (s SMSFailoverService) Send(ctx context.Context, tpl string, args []string, numbers ...string) error {
-	for _, svc := range s.services {
-		err := svc.Send(ctx, tpl, args, numbers...)
-		if err != nil {
-			return nil
+func WithSlowCall(threshold time.Duration, rate float64) Option {
+	return func(c *config) {
+		c.slowCall = threshold
+		c.slowRate = rate
+	}
+}
+
+func WithOpenTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.openTimeout = timeout
+	}
+}
+
+// Provider 一个短信服务商 Name用于展示健康状态
+type Provider struct {
+	Name string
+	Svc  sms.Service
+}
+
+// ProviderHealth 服务商当前的健康状态 统计最近一个窗口内的调用
+type ProviderHealth struct {
+	Name       string
+	State      State
+	Samples    int
+	ErrRate    float64
+	SlowRate   float64
+	AvgLatency time.Duration
+	//熔断状态下 这个时间之后开始探测
+	OpenUntil time.Time
+}
+
+type provider struct {
+	Provider
+	breaker *breaker
+}
+
+// Service 按顺序尝试服务商 每个服务商一个熔断器 熔断的服务商直接跳过
+type Service struct {
+	providers []*provider
+}
+
+func NewService(providers []Provider, opts ...Option) *Service {
+	cfg := &config{
+		window:      defaultWindow,
+		minSamples:  defaultMinSamples,
+		errRate:     defaultErrRate,
+		slowCall:    defaultSlowCall,
+		slowRate:    defaultSlowRate,
+		openTimeout: defaultOpenTimeout,
+		probes:      defaultProbes,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	s := &Service{providers: make([]*provider, 0, len(providers))}
+	for _, p := range providers {
+		s.providers = append(s.providers, &provider{Provider: p, breaker: newBreaker(cfg)})
+	}
+	return s
+}
+
+func (s *Service) Send(ctx context.Context, biz string, args []string, numbers ...string) error {
+	tried := false
+	var lastErr error
+	for _, p := range s.providers {
+		probe, ok := p.breaker.allow(time.Now())
+		if !ok {
+			continue
 		}
-		//正常流程 输出日志
-		//做好监控
-		log.Println(err)
-	}
-	return errors.New("全部服务都失败了")
-}
-
-// SendV1 动态指针轮询
-func (s SMSFailoverService) SendV1(ctx context.Context, tpl string, args []string, numbers ...string) error {
-	idx := atomic.AddUint64(&s.idx, 1)
-	length := uint64(len(s.services))
-	for i := idx; i < idx+length; i++ {
-		svc := s.services[int(i%length)]
-		err := svc.Send(ctx, tpl, args, numbers...)
-		switch err {
-		case nil:
+		tried = true
+		start := time.Now()
+		err := p.Svc.Send(ctx, biz, args, numbers...)
+		latency := time.Since(start)
+		switch {
+		case err == nil:
+			p.breaker.record(time.Now(), probe, false, latency)
 			return nil
-		case context.DeadlineExceeded, context.Canceled:
-			return err
+		case errors.Is(err, smsratelimit.ErrLimited), errors.Is(err, context.Canceled),
+			errors.Is(err, templates.ErrTemplateNotFind):
+			//不是服务商的问题
+			p.breaker.release(probe)
 		default:
-			//输出日志
+			p.breaker.record(time.Now(), probe, true, latency)
+		}
+		if ctx.Err() != nil {
+			//调用方已经不等了 换下一个也没用
+			return err
 		}
+		log.Printf("短信服务商%s发送失败,换下一个,err:%v", p.Name, err)
+		lastErr = err
+	}
+	if !tried {
+		return ErrNoProvider
+	}
+	//带上最后一个错误 上层要根据限流之类的错误决定怎么处理
+	return fmt.Errorf("%w: %w", ErrAllFailed, lastErr)
+}
+
+// Health 按配置顺序返回所有服务商的健康状态
+func (s *Service) Health() []ProviderHealth {
+	res := make([]ProviderHealth, 0, len(s.providers))
+	for _, p := range s.providers {
+		h := p.breaker.health()
+		h.Name = p.Name
+		res = append(res, h)
 	}
-	return errors.New("全部服务都失败了")
+	return res
 }
@@ -0,0 +1,199 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	smsmocks "webook/internal/service/sms/mocks"
+	smsratelimit "webook/internal/service/sms/ratelimit"
+)
+
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) []Provider
+		//Send之前准备熔断器状态
+		before func(s *Service)
+
+		wantErr    error
+		wantStates []State
+	}{
+		{
+			name: "第一个失败 第二个成功",
+			mock: func(ctrl *gomock.Controller) []Provider {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc0.EXPECT().Send(gomock.Any(), "login", gomock.Any(), gomock.Any()).Return(errors.New("发送失败"))
+				svc1 := smsmocks.NewMockService(ctrl)
+				svc1.EXPECT().Send(gomock.Any(), "login", gomock.Any(), gomock.Any()).Return(nil)
+				return []Provider{{Name: "p0", Svc: svc0}, {Name: "p1", Svc: svc1}}
+			},
+			wantStates: []State{StateClosed, StateClosed},
+		},
+		{
+			name: "全部失败",
+			mock: func(ctrl *gomock.Controller) []Provider {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc0.EXPECT().Send(gomock.Any(), "login", gomock.Any(), gomock.Any()).Return(errors.New("发送失败"))
+				svc1 := smsmocks.NewMockService(ctrl)
+				svc1.EXPECT().Send(gomock.Any(), "login", gomock.Any(), gomock.Any()).Return(errors.New("发送失败"))
+				return []Provider{{Name: "p0", Svc: svc0}, {Name: "p1", Svc: svc1}}
+			},
+			wantErr:    ErrAllFailed,
+			wantStates: []State{StateClosed, StateClosed},
+		},
+		{
+			name: "失败率太高 熔断",
+			mock: func(ctrl *gomock.Controller) []Provider {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc0.EXPECT().Send(gomock.Any(), "login", gomock.Any(), gomock.Any()).Return(errors.New("发送失败"))
+				svc1 := smsmocks.NewMockService(ctrl)
+				svc1.EXPECT().Send(gomock.Any(), "login", gomock.Any(), gomock.Any()).Return(nil)
+				return []Provider{{Name: "p0", Svc: svc0}, {Name: "p1", Svc: svc1}}
+			},
+			before: func(s *Service) {
+				//之前9次 失败4次
+				b := s.providers[0].breaker
+				for i := 0; i < defaultMinSamples-1; i++ {
+					b.record(time.Now(), false, i < 4, time.Millisecond)
+				}
+			},
+			wantStates: []State{StateOpen, StateClosed},
+		},
+		{
+			name: "熔断的服务商直接跳过",
+			mock: func(ctrl *gomock.Controller) []Provider {
+				svc1 := smsmocks.NewMockService(ctrl)
+				svc1.EXPECT().Send(gomock.Any(), "login", gomock.Any(), gomock.Any()).Return(nil)
+				return []Provider{{Name: "p0", Svc: smsmocks.NewMockService(ctrl)}, {Name: "p1", Svc: svc1}}
+			},
+			before: func(s *Service) {
+				s.providers[0].breaker.trip(time.Now())
+			},
+			wantStates: []State{StateOpen, StateClosed},
+		},
+		{
+			name: "全部熔断",
+			mock: func(ctrl *gomock.Controller) []Provider {
+				return []Provider{{Name: "p0", Svc: smsmocks.NewMockService(ctrl)}}
+			},
+			before: func(s *Service) {
+				s.providers[0].breaker.trip(time.Now())
+			},
+			wantErr:    ErrNoProvider,
+			wantStates: []State{StateOpen},
+		},
+		{
+			name: "半开状态探测成功",
+			mock: func(ctrl *gomock.Controller) []Provider {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc0.EXPECT().Send(gomock.Any(), "login", gomock.Any(), gomock.Any()).Return(nil)
+				return []Provider{{Name: "p0", Svc: svc0}}
+			},
+			before: func(s *Service) {
+				b := s.providers[0].breaker
+				b.trip(time.Now().Add(-defaultOpenTimeout))
+				//已经成功探测了2次
+				b.state, b.probeOK = StateHalfOpen, defaultProbes-1
+			},
+			wantStates: []State{StateClosed},
+		},
+		{
+			name: "半开状态探测失败 重新熔断",
+			mock: func(ctrl *gomock.Controller) []Provider {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc0.EXPECT().Send(gomock.Any(), "login", gomock.Any(), gomock.Any()).Return(errors.New("发送失败"))
+				return []Provider{{Name: "p0", Svc: svc0}}
+			},
+			before: func(s *Service) {
+				s.providers[0].breaker.trip(time.Now().Add(-defaultOpenTimeout))
+			},
+			wantErr:    ErrAllFailed,
+			wantStates: []State{StateOpen},
+		},
+		{
+			name: "慢调用太多 熔断",
+			mock: func(ctrl *gomock.Controller) []Provider {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc0.EXPECT().Send(gomock.Any(), "login", gomock.Any(), gomock.Any()).Return(nil)
+				return []Provider{{Name: "p0", Svc: svc0}}
+			},
+			before: func(s *Service) {
+				b := s.providers[0].breaker
+				for i := 0; i < defaultMinSamples-1; i++ {
+					b.record(time.Now(), false, false, defaultSlowCall)
+				}
+			},
+			wantStates: []State{StateOpen},
+		},
+		{
+			name: "限流不算失败",
+			mock: func(ctrl *gomock.Controller) []Provider {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc0.EXPECT().Send(gomock.Any(), "login", gomock.Any(), gomock.Any()).Return(smsratelimit.ErrLimited)
+				svc1 := smsmocks.NewMockService(ctrl)
+				svc1.EXPECT().Send(gomock.Any(), "login", gomock.Any(), gomock.Any()).Return(nil)
+				return []Provider{{Name: "p0", Svc: svc0}, {Name: "p1", Svc: svc1}}
+			},
+			before: func(s *Service) {
+				b := s.providers[0].breaker
+				for i := 0; i < defaultMinSamples-1; i++ {
+					b.record(time.Now(), false, i < 4, time.Millisecond)
+				}
+			},
+			wantStates: []State{StateClosed, StateClosed},
+		},
+		{
+			name: "全部限流 带上最后一个错误",
+			mock: func(ctrl *gomock.Controller) []Provider {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc0.EXPECT().Send(gomock.Any(), "login", gomock.Any(), gomock.Any()).Return(errors.New("发送失败"))
+				svc1 := smsmocks.NewMockService(ctrl)
+				svc1.EXPECT().Send(gomock.Any(), "login", gomock.Any(), gomock.Any()).Return(smsratelimit.ErrLimited)
+				return []Provider{{Name: "p0", Svc: svc0}, {Name: "p1", Svc: svc1}}
+			},
+			wantErr:    smsratelimit.ErrLimited,
+			wantStates: []State{StateClosed, StateClosed},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := NewService(tc.mock(ctrl))
+			if tc.before != nil {
+				tc.before(svc)
+			}
+			err := svc.Send(context.Background(), "login", []string{"123456"}, "15212345678")
+			assert.True(t, errors.Is(err, tc.wantErr))
+			states := make([]State, 0, len(tc.wantStates))
+			for _, h := range svc.Health() {
+				states = append(states, h.State)
+			}
+			assert.Equal(t, tc.wantStates, states)
+		})
+	}
+}
+
+func TestService_Health(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := NewService([]Provider{{Name: "p0", Svc: smsmocks.NewMockService(ctrl)}})
+	b := svc.providers[0].breaker
+	b.record(time.Now(), false, true, time.Millisecond*100)
+	b.record(time.Now(), false, false, time.Millisecond*300)
+
+	hs := svc.Health()
+	assert.Equal(t, []ProviderHealth{{
+		Name:       "p0",
+		State:      StateClosed,
+		Samples:    2,
+		ErrRate:    0.5,
+		AvgLatency: time.Millisecond * 200,
+	}}, hs)
+}
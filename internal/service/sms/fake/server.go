@@ -0,0 +1,280 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ProviderTencent = "tencent"
+	ProviderAliyun  = "aliyun"
+)
+
+// Mode 服务商的表现
+type Mode string
+
+const (
+	ModeOK Mode = "ok"
+	//返回服务商的限流错误码
+	ModeThrottle Mode = "throttle"
+	//返回服务商的错误码
+	ModeError Mode = "error"
+	//等待Delay之后正常返回 没有配置Delay时等待defaultSlowDelay
+	ModeSlow Mode = "slow"
+)
+
+const defaultSlowDelay = time.Second * 5
+
+type Behavior struct {
+	Mode Mode `json:"mode"`
+	//所有模式下都会先等待这么久
+	Delay time.Duration `json:"delay"`
+	//ModeError时返回的错误码 为空时使用服务商的系统错误码
+	Code string `json:"code"`
+}
+
+// Message 收到的一条发送请求
+type Message struct {
+	Provider string
+	Template string
+	Numbers  []string
+	//腾讯云按顺序传参数
+	Args []string
+	//阿里云按名字传参数
+	Params map[string]string
+}
+
+// Server 假的短信服务商 同时模拟腾讯云和阿里云的发送接口 用于离线联调和集成测试
+// 腾讯云请求带X-TC-Action头 阿里云请求带Action参数 两家可以共用一个地址
+type Server struct {
+	mu        sync.Mutex
+	behaviors map[string]Behavior
+	sent      []Message
+	seq       atomic.Int64
+}
+
+func NewServer() *Server {
+	return &Server{behaviors: make(map[string]Behavior)}
+}
+
+// Set 修改服务商的表现 也可以POST /_fake/behavior
+func (s *Server) Set(provider string, b Behavior) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.behaviors[provider] = b
+}
+
+// Sent 成功发送的消息 按收到的顺序
+func (s *Server) Sent() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]Message, len(s.sent))
+	copy(res, s.sent)
+	return res
+}
+
+// Reset 恢复正常并清空收到的消息
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.behaviors = make(map[string]Behavior)
+	s.sent = nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/_fake/behavior":
+		s.serveBehavior(w, r)
+	case r.Header.Get("X-TC-Action") != "":
+		s.serveTencent(w, r)
+	case r.URL.Query().Get("Action") != "":
+		s.serveAliyun(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type behaviorReq struct {
+	Provider string `json:"provider"`
+	Mode     Mode   `json:"mode"`
+	DelayMs  int64  `json:"delay_ms"`
+	Code     string `json:"code"`
+}
+
+func (s *Server) serveBehavior(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(s.behaviors)
+		return
+	}
+	var req behaviorReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.Set(req.Provider, Behavior{
+		Mode:  req.Mode,
+		Delay: time.Duration(req.DelayMs) * time.Millisecond,
+		Code:  req.Code,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// behave 等待配置的延迟 客户端先断开时返回false
+func (s *Server) behave(r *http.Request, provider string) (Behavior, bool) {
+	s.mu.Lock()
+	b := s.behaviors[provider]
+	s.mu.Unlock()
+	if b.Mode == "" {
+		b.Mode = ModeOK
+	}
+	if b.Mode == ModeSlow && b.Delay <= 0 {
+		b.Delay = defaultSlowDelay
+	}
+	if b.Delay <= 0 {
+		return b, true
+	}
+	select {
+	case <-time.After(b.Delay):
+		return b, true
+	case <-r.Context().Done():
+		return b, false
+	}
+}
+
+func (s *Server) record(msg Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, msg)
+}
+
+func (s *Server) requestId() string {
+	return fmt.Sprintf("fake-%d", s.seq.Add(1))
+}
+
+type tencentReq struct {
+	PhoneNumberSet   []string
+	TemplateId       string
+	TemplateParamSet []string
+}
+
+type tencentError struct {
+	Code    string
+	Message string
+}
+
+type tencentStatus struct {
+	SerialNo    string
+	PhoneNumber string
+	Fee         int
+	Code        string
+	Message     string
+	IsoCode     string
+}
+
+// serveTencent 腾讯云的SDK只认200 错误放在Response.Error里
+func (s *Server) serveTencent(w http.ResponseWriter, r *http.Request) {
+	var req tencentReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeTencent(w, obj{"Error": tencentError{Code: "InvalidParameter", Message: err.Error()}})
+		return
+	}
+	b, ok := s.behave(r, ProviderTencent)
+	if !ok {
+		return
+	}
+	switch b.Mode {
+	case ModeThrottle:
+		s.writeTencent(w, obj{"Error": tencentError{Code: "RequestLimitExceeded", Message: "请求的次数超过了频率限制"}})
+	case ModeError:
+		code := b.Code
+		if code == "" {
+			code = "InternalError"
+		}
+		s.writeTencent(w, obj{"Error": tencentError{Code: code, Message: "假的服务商返回错误"}})
+	default:
+		s.record(Message{
+			Provider: ProviderTencent,
+			Template: req.TemplateId,
+			Numbers:  req.PhoneNumberSet,
+			Args:     req.TemplateParamSet,
+		})
+		set := make([]tencentStatus, 0, len(req.PhoneNumberSet))
+		for _, number := range req.PhoneNumberSet {
+			set = append(set, tencentStatus{
+				SerialNo:    s.requestId(),
+				PhoneNumber: number,
+				Fee:         1,
+				Code:        "Ok",
+				Message:     "send success",
+				IsoCode:     "CN",
+			})
+		}
+		s.writeTencent(w, obj{"SendStatusSet": set})
+	}
+}
+
+func (s *Server) writeTencent(w http.ResponseWriter, resp obj) {
+	resp["RequestId"] = s.requestId()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(obj{"Response": resp})
+}
+
+type aliyunResp struct {
+	Code      string
+	Message   string
+	BizId     string `json:",omitempty"`
+	RequestId string
+}
+
+func (s *Server) serveAliyun(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("Action") != "SendSms" {
+		s.writeAliyun(w, http.StatusBadRequest, aliyunResp{Code: "InvalidAction.NotFound", Message: "不支持的接口"})
+		return
+	}
+	var params map[string]string
+	if tp := query.Get("TemplateParam"); tp != "" {
+		if err := json.Unmarshal([]byte(tp), &params); err != nil {
+			s.writeAliyun(w, http.StatusOK, aliyunResp{Code: "isv.INVALID_JSON_PARAM", Message: err.Error()})
+			return
+		}
+	}
+	b, ok := s.behave(r, ProviderAliyun)
+	if !ok {
+		return
+	}
+	switch b.Mode {
+	case ModeThrottle:
+		s.writeAliyun(w, http.StatusOK, aliyunResp{Code: "isv.BUSINESS_LIMIT_CONTROL", Message: "触发分钟级流控"})
+	case ModeError:
+		code := b.Code
+		if code == "" {
+			code = "isp.SYSTEM_ERROR"
+		}
+		s.writeAliyun(w, http.StatusOK, aliyunResp{Code: code, Message: "假的服务商返回错误"})
+	default:
+		s.record(Message{
+			Provider: ProviderAliyun,
+			Template: query.Get("TemplateCode"),
+			Numbers:  strings.Split(query.Get("PhoneNumbers"), ","),
+			Params:   params,
+		})
+		s.writeAliyun(w, http.StatusOK, aliyunResp{Code: "OK", Message: "OK", BizId: s.requestId()})
+	}
+}
+
+func (s *Server) writeAliyun(w http.ResponseWriter, status int, resp aliyunResp) {
+	resp.RequestId = s.requestId()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+type obj map[string]any
@@ -0,0 +1,118 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	tencentsms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"webook/internal/service/sms/aliyun"
+	"webook/internal/service/sms/failover"
+	smsratelimit "webook/internal/service/sms/ratelimit"
+	"webook/internal/service/sms/templates"
+	"webook/internal/service/sms/tencent"
+)
+
+func newProviders(t *testing.T, endpoint string) (*tencent.Service, *aliyun.Service) {
+	cpf := profile.NewClientProfile()
+	cpf.HttpProfile.Scheme = "HTTP"
+	cpf.HttpProfile.Endpoint = strings.TrimPrefix(endpoint, "http://")
+	client, err := tencentsms.NewClient(common.NewCredential("id", "key"), "ap-guangzhou", cpf)
+	require.NoError(t, err)
+	tpls := templates.NewRegistry()
+	tpls.Register("login", ProviderTencent, templates.Template{Id: "1000001", Params: []string{"code"}})
+	tpls.Register("login", ProviderAliyun, templates.Template{Id: "SMS_1", Params: []string{"code"}})
+	tc := tencent.NewService(ProviderTencent, "1400000000", "webook", client, tpls)
+	ali := aliyun.NewService(ProviderAliyun, "id", "secret", "webook", tpls, aliyun.WithEndpoint(endpoint+"/"))
+	return tc, ali
+}
+
+func TestServer_Providers(t *testing.T) {
+	testCases := []struct {
+		name     string
+		provider string
+		behavior Behavior
+
+		wantErr  error
+		wantSent int
+	}{
+		{name: "腾讯云发送成功", provider: ProviderTencent, behavior: Behavior{Mode: ModeOK}, wantSent: 1},
+		{name: "腾讯云限流", provider: ProviderTencent, behavior: Behavior{Mode: ModeThrottle}, wantErr: smsratelimit.ErrLimited},
+		{name: "腾讯云出错", provider: ProviderTencent, behavior: Behavior{Mode: ModeError}, wantErr: errors.New("出错")},
+		{name: "阿里云发送成功", provider: ProviderAliyun, behavior: Behavior{Mode: ModeOK}, wantSent: 1},
+		{name: "阿里云限流", provider: ProviderAliyun, behavior: Behavior{Mode: ModeThrottle}, wantErr: smsratelimit.ErrLimited},
+		{name: "阿里云出错", provider: ProviderAliyun, behavior: Behavior{Mode: ModeError}, wantErr: errors.New("出错")},
+	}
+
+	fs := NewServer()
+	server := httptest.NewServer(fs)
+	defer server.Close()
+	tc, ali := newProviders(t, server.URL)
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			fs.Reset()
+			fs.Set(c.provider, c.behavior)
+			var err error
+			if c.provider == ProviderTencent {
+				err = tc.Send(context.Background(), "login", []string{"123456"}, "+8615212345678")
+			} else {
+				err = ali.Send(context.Background(), "login", []string{"123456"}, "15212345678")
+			}
+			switch {
+			case c.wantErr == nil:
+				assert.NoError(t, err)
+			case errors.Is(c.wantErr, smsratelimit.ErrLimited):
+				assert.ErrorIs(t, err, smsratelimit.ErrLimited)
+			default:
+				assert.Error(t, err)
+				assert.NotErrorIs(t, err, smsratelimit.ErrLimited)
+			}
+			assert.Len(t, fs.Sent(), c.wantSent)
+		})
+	}
+}
+
+func TestServer_Failover(t *testing.T) {
+	fs := NewServer()
+	server := httptest.NewServer(fs)
+	defer server.Close()
+	tc, ali := newProviders(t, server.URL)
+	svc := failover.NewService([]failover.Provider{
+		{Name: ProviderTencent, Svc: tc},
+		{Name: ProviderAliyun, Svc: ali},
+	}, failover.WithSlowCall(time.Millisecond*50, 0.5))
+	send := func() error {
+		return svc.Send(context.Background(), "login", []string{"123456"}, "15212345678")
+	}
+
+	//限流换下一个 但是不算失败
+	fs.Set(ProviderTencent, Behavior{Mode: ModeThrottle})
+	require.NoError(t, send())
+	sent := fs.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, ProviderAliyun, sent[0].Provider)
+	assert.Equal(t, "SMS_1", sent[0].Template)
+	assert.Equal(t, map[string]string{"code": "123456"}, sent[0].Params)
+	assert.Equal(t, failover.StateClosed, svc.Health()[0].State)
+
+	//一直响应很慢 熔断之后直接用阿里云
+	fs.Reset()
+	fs.Set(ProviderTencent, Behavior{Mode: ModeSlow, Delay: time.Millisecond * 60})
+	for i := 0; i < 10; i++ {
+		require.NoError(t, send())
+	}
+	assert.Equal(t, failover.StateOpen, svc.Health()[0].State)
+	require.NoError(t, send())
+	sent = fs.Sent()
+	assert.Equal(t, ProviderAliyun, sent[len(sent)-1].Provider)
+
+	//两家都出错
+	fs.Set(ProviderAliyun, Behavior{Mode: ModeError})
+	assert.ErrorIs(t, send(), failover.ErrAllFailed)
+}
@@ -3,7 +3,7 @@
 //
 // Generated by this command:
 //
-//	mockgen -source=internal/service/sms/types.go -package=smsmocks -destination=internal/service/sms/syntoasy/mocks/sms_service_gen.go
+//	mockgen -source=internal/service/sms/types.go -package=smsmocks -destination=internal/service/sms/mocks/service_gen.go
 //
 // Package smsmocks is a generated GoMock package.
 package smsmocks
@@ -39,9 +39,9 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 }
 
 // Send mocks base method.
-func (m *MockService) Send(ctx context.Context, tpl string, args []string, numbers ...string) error {
+func (m *MockService) Send(ctx context.Context, biz string, args []string, numbers ...string) error {
 	m.ctrl.T.Helper()
-	varargs := []any{ctx, tpl, args}
+	varargs := []any{ctx, biz, args}
 	for _, a := range numbers {
 		varargs = append(varargs, a)
 	}
@@ -51,8 +51,8 @@ func (m *MockService) Send(ctx context.Context, tpl string, args []string, numbe
 }
 
 // Send indicates an expected call of Send.
-func (mr *MockServiceMockRecorder) Send(ctx, tpl, args any, numbers ...any) *gomock.Call {
+func (mr *MockServiceMockRecorder) Send(ctx, biz, args any, numbers ...any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]any{ctx, tpl, args}, numbers...)
+	varargs := append([]any{ctx, biz, args}, numbers...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockService)(nil).Send), varargs...)
 }
@@ -2,11 +2,14 @@ package ratelimit
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"webook/internal/service/sms"
 	"webook/pkg/ratelimit"
 )
 
+var ErrLimited = errors.New("短信服务触发了限流")
+
 type Service struct {
 	svc     sms.Service
 	limiter ratelimit.Limiter
@@ -23,7 +26,7 @@ func (s *Service) Send(ctx context.Context, tpl string, args []string, number ..
 		return fmt.Errorf("短信服务判断是否限流出现问题,%w", err)
 	}
 	if limited {
-		return fmt.Errorf("触发了限流")
+		return ErrLimited
 	}
 	err = s.svc.Send(ctx, tpl, args, number...)
 	//加一些代码 新特性
@@ -0,0 +1,72 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrTemplateNotFind = errors.New("短信模板不存在")
+	ErrArgsMismatch    = errors.New("短信模板参数个数不对")
+)
+
+// Template 一个业务在某个服务商上的模板
+type Template struct {
+	Id string
+	//为空时使用服务商默认的签名
+	SignName string
+	//参数名 按顺序和args对应 阿里云按名字传参 腾讯云只用来校验个数
+	Params []string
+}
+
+// Registry 业务到各个服务商模板的映射 业务代码只认biz 换服务商只需要改配置
+type Registry struct {
+	//biz -> 服务商 -> 模板
+	tpls map[string]map[string]Template
+}
+
+func NewRegistry() *Registry {
+	return &Registry{tpls: make(map[string]map[string]Template)}
+}
+
+// Register provider和failover.Provider的Name一致
+func (r *Registry) Register(biz string, provider string, tpl Template) {
+	m, ok := r.tpls[biz]
+	if !ok {
+		m = make(map[string]Template)
+		r.tpls[biz] = m
+	}
+	m[provider] = tpl
+}
+
+// Find 服务商发送之前调用
+func (r *Registry) Find(provider string, biz string, args []string) (Template, error) {
+	tpl, ok := r.tpls[biz][provider]
+	if !ok {
+		return Template{}, fmt.Errorf("%w,biz:%s,provider:%s", ErrTemplateNotFind, biz, provider)
+	}
+	if len(tpl.Params) != len(args) {
+		return Template{}, fmt.Errorf("%w,biz:%s,provider:%s,需要%d个,传了%d个",
+			ErrArgsMismatch, biz, provider, len(tpl.Params), len(args))
+	}
+	return tpl, nil
+}
+
+// Has 至少有一个服务商配置了这个业务
+func (r *Registry) Has(biz string) bool {
+	return len(r.tpls[biz]) > 0
+}
+
+// Validate 至少有一个服务商配置了这个业务 并且所有服务商的参数个数都一致
+func (r *Registry) Validate(biz string, args []string) error {
+	m, ok := r.tpls[biz]
+	if !ok || len(m) == 0 {
+		return fmt.Errorf("%w,biz:%s", ErrTemplateNotFind, biz)
+	}
+	for provider := range m {
+		if _, err := r.Find(provider, biz, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
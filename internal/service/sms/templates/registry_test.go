@@ -0,0 +1,117 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	smsmocks "webook/internal/service/sms/mocks"
+)
+
+func newTestRegistry() *Registry {
+	reg := NewRegistry()
+	reg.Register("login", "tencent", Template{Id: "1000001", Params: []string{"code"}})
+	reg.Register("login", "aliyun", Template{Id: "SMS_1", SignName: "webook", Params: []string{"code"}})
+	//配置错了 两家的参数个数不一致
+	reg.Register("reset_pwd", "tencent", Template{Id: "1000002", Params: []string{"code"}})
+	reg.Register("reset_pwd", "aliyun", Template{Id: "SMS_2", Params: []string{"code", "minutes"}})
+	return reg
+}
+
+func TestRegistry_Find(t *testing.T) {
+	testCases := []struct {
+		name     string
+		provider string
+		biz      string
+		args     []string
+
+		wantTpl Template
+		wantErr error
+	}{
+		{
+			name:     "找到模板",
+			provider: "aliyun",
+			biz:      "login",
+			args:     []string{"123456"},
+			wantTpl:  Template{Id: "SMS_1", SignName: "webook", Params: []string{"code"}},
+		},
+		{
+			name:     "业务没有配置",
+			provider: "aliyun",
+			biz:      "bind_phone",
+			args:     []string{"123456"},
+			wantErr:  ErrTemplateNotFind,
+		},
+		{
+			name:     "服务商没有配置",
+			provider: "memory",
+			biz:      "login",
+			args:     []string{"123456"},
+			wantErr:  ErrTemplateNotFind,
+		},
+		{
+			name:     "参数个数不对",
+			provider: "tencent",
+			biz:      "login",
+			args:     []string{"123456", "5"},
+			wantErr:  ErrArgsMismatch,
+		},
+	}
+
+	reg := newTestRegistry()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := reg.Find(tc.provider, tc.biz, tc.args)
+			assert.True(t, errors.Is(err, tc.wantErr))
+			assert.Equal(t, tc.wantTpl, tpl)
+		})
+	}
+}
+
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) *smsmocks.MockService
+		biz  string
+
+		wantErr error
+	}{
+		{
+			name: "校验通过",
+			mock: func(ctrl *gomock.Controller) *smsmocks.MockService {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", []string{"123456"}, "15212345678").Return(nil)
+				return svc
+			},
+			biz: "login",
+		},
+		{
+			name: "业务没有配置 不发送",
+			mock: func(ctrl *gomock.Controller) *smsmocks.MockService {
+				return smsmocks.NewMockService(ctrl)
+			},
+			biz:     "bind_phone",
+			wantErr: ErrTemplateNotFind,
+		},
+		{
+			name: "有一家参数个数不一致 不发送",
+			mock: func(ctrl *gomock.Controller) *smsmocks.MockService {
+				return smsmocks.NewMockService(ctrl)
+			},
+			biz:     "reset_pwd",
+			wantErr: ErrArgsMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := NewService(tc.mock(ctrl), newTestRegistry())
+			err := svc.Send(context.Background(), tc.biz, []string{"123456"}, "15212345678")
+			assert.True(t, errors.Is(err, tc.wantErr))
+		})
+	}
+}
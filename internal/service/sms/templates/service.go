@@ -0,0 +1,23 @@
+package templates
+
+import (
+	"context"
+	"webook/internal/service/sms"
+)
+
+// Service 发送之前校验模板 参数不对的请求不进入重试队列
+type Service struct {
+	svc sms.Service
+	reg *Registry
+}
+
+func NewService(svc sms.Service, reg *Registry) *Service {
+	return &Service{svc: svc, reg: reg}
+}
+
+func (s *Service) Send(ctx context.Context, biz string, args []string, numbers ...string) error {
+	if err := s.reg.Validate(biz, args); err != nil {
+		return err
+	}
+	return s.svc.Send(ctx, biz, args, numbers...)
+}
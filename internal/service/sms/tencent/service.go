@@ -2,40 +2,76 @@ package tencent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/ecodeclub/ekit"
 	"github.com/ecodeclub/ekit/slice"
+	sdkerrors "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
 	sms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
+	"strings"
+	smsratelimit "webook/internal/service/sms/ratelimit"
+	"webook/internal/service/sms/templates"
 )
 
 type Service struct {
+	//在模板注册表里的名字
+	name     string
 	appId    *string
-	signName *string
+	signName string
 	client   *sms.Client
+	tpls     *templates.Registry
 }
 
-func NewService(appId string, signName string, client *sms.Client) *Service {
-	return &Service{appId: &appId, signName: &signName, client: client}
+func NewService(name string, appId string, signName string, client *sms.Client, tpls *templates.Registry) *Service {
+	return &Service{name: name, appId: &appId, signName: signName, client: client, tpls: tpls}
 }
 func (svc *Service) Send(ctx context.Context, biz string, args []string, number ...string) error {
+	tpl, err := svc.tpls.Find(svc.name, biz, args)
+	if err != nil {
+		return err
+	}
+	signName := tpl.SignName
+	if signName == "" {
+		signName = svc.signName
+	}
 	req := sms.NewSendSmsRequest()
 	req.SmsSdkAppId = svc.appId
-	req.SignName = svc.signName
-	req.TemplateId = ekit.ToPtr[string](biz)
+	req.SignName = &signName
+	req.TemplateId = ekit.ToPtr[string](tpl.Id)
 	req.PhoneNumberSet = svc.toStringPtrSlice(number)
 	req.TemplateParamSet = svc.toStringPtrSlice(args)
-	resp, err := svc.client.SendSms(req)
+	resp, err := svc.client.SendSmsWithContext(ctx, req)
+	var sdkErr *sdkerrors.TencentCloudSDKError
+	if errors.As(err, &sdkErr) && svc.limited(sdkErr.Code) {
+		return fmt.Errorf("%w,%s", smsratelimit.ErrLimited, sdkErr.Message)
+	}
 	if err != nil {
 		return err
 	}
 	for _, status := range resp.Response.SendStatusSet {
-		if status.Code == nil || *(status.Code) != "Ok" {
-			return fmt.Errorf("发送短信失败%s,%s", *status.Code, *status.Message)
+		code, msg := ekit.ToPtr[string](""), ekit.ToPtr[string]("")
+		if status.Code != nil {
+			code = status.Code
 		}
+		if status.Message != nil {
+			msg = status.Message
+		}
+		if *code == "Ok" {
+			continue
+		}
+		if svc.limited(*code) {
+			return fmt.Errorf("%w,%s", smsratelimit.ErrLimited, *msg)
+		}
+		return fmt.Errorf("发送短信失败%s,%s", *code, *msg)
 	}
 	return nil
 }
 
+// limited 接口调用频率和单个号码的发送频率限制
+func (svc *Service) limited(code string) bool {
+	return code == "RequestLimitExceeded" || strings.HasPrefix(code, "LimitExceeded")
+}
+
 func (svc *Service) toStringPtrSlice(src []string) []*string {
 	return slice.Map[string, *string](src, func(idx int, src string) *string {
 		return &src
@@ -3,27 +3,52 @@ package service
 import (
 	"context"
 	"errors"
-	"golang.org/x/crypto/bcrypt"
+	"log"
+	"time"
 	"webook/internal/domain"
 	"webook/internal/repository"
+	"webook/pkg/password"
 )
 
 type UserService interface {
 	SignUp(ctx context.Context, u domain.User) error
 	Login(ctx context.Context, u domain.User) (domain.User, error)
 	FindOrCreate(ctx context.Context, u domain.User) (domain.User, error)
+	// EditUserPassword 按u.Id修改密码
 	EditUserPassword(ctx context.Context, u domain.User) error
+	// ChangePassword 校验原密码之后修改
+	ChangePassword(ctx context.Context, userId int, oldPassword string, newPassword string) error
 	Profile(ctx context.Context, u domain.User) (domain.User, error)
+	// UpdateProfile 更新昵称 生日 简介 头像 按u.Id更新
+	UpdateProfile(ctx context.Context, u domain.User) error
+	// BindPhone 手机号需要调用方先用验证码验证过
+	BindPhone(ctx context.Context, userId int, phone string) error
+	// BindEmail 邮箱需要调用方先用验证码验证过 绑定后就是已验证状态
+	BindEmail(ctx context.Context, userId int, email string) error
+	BindWechat(ctx context.Context, userId int, info domain.WechatInfo) error
+	// Unbind 至少要保留一种登录方式
+	Unbind(ctx context.Context, userId int, identity Identity) error
 	FindOrCreateByWechat(ctx context.Context, info domain.WechatInfo) (domain.User, error)
+	// RequestDeletion 申请注销 返回计划彻底删除的时间 冷静期内重新登录视为撤销
+	RequestDeletion(ctx context.Context, userId int) (time.Time, error)
+	// PurgeDeleted 彻底删除冷静期已过的账号 最多limit个 返回删除的用户id
+	PurgeDeleted(ctx context.Context, limit int) ([]int, error)
 }
 
+// DeletionGracePeriod 注销的冷静期
+const DeletionGracePeriod = 14 * 24 * time.Hour
+
 type UserDevService struct {
-	repo repository.UserRepository
+	repo   repository.UserRepository
+	hasher password.Hasher
 }
 
 func (svc *UserDevService) FindOrCreateByWechat(ctx context.Context, info domain.WechatInfo) (domain.User, error) {
 	//快路径
 	uResult, err := svc.repo.FindByWechat(ctx, info.OpenID)
+	if err == nil {
+		return svc.restore(ctx, uResult)
+	}
 	if err != ErrUserNotFind {
 		return uResult, err
 	}
@@ -39,44 +64,73 @@ func (svc *UserDevService) FindOrCreateByWechat(ctx context.Context, info domain
 
 var (
 	ErrInvalidUserOrPassword = errors.New("邮箱或者密码不对")
+	ErrPasswordMismatch      = errors.New("原密码不对")
+	ErrPhoneConflict         = errors.New("手机号已经绑定了其他账号")
+	ErrEmailConflict         = errors.New("邮箱已经绑定了其他账号")
+	ErrWechatConflict        = errors.New("微信已经绑定了其他账号")
+	ErrLastLoginMethod       = errors.New("至少要保留一种登录方式")
 	ErrUserNotFind           = repository.ErrUserNotFind
 )
 
-func NewUserDevService(userRepository repository.UserRepository) *UserDevService {
-	return &UserDevService{repo: userRepository}
+func NewUserDevService(userRepository repository.UserRepository, hasher password.Hasher) *UserDevService {
+	return &UserDevService{repo: userRepository, hasher: hasher}
 }
 
 func (svc *UserDevService) SignUp(ctx context.Context, u domain.User) error {
 	//加密
-	hash, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	hash, err := svc.hasher.Hash(u.Password)
 	if err != nil {
 		return err
 	}
-	u.Password = string(hash)
+	u.Password = hash
 	//存储
 	return svc.repo.Create(ctx, u)
 }
 
 func (svc *UserDevService) Login(ctx context.Context, u domain.User) (domain.User, error) {
 	result, err := svc.repo.FindByEmail(ctx, u)
+	//账号不存在和密码错误返回同一个错误 防止被用来探测邮箱
+	if errors.Is(err, ErrUserNotFind) {
+		return domain.User{}, ErrInvalidUserOrPassword
+	}
 	if err != nil {
 		return result, err
 	}
-	if errors.Is(err, ErrUserNotFind) {
-		return result, ErrInvalidUserOrPassword
-	}
 
 	//判断哈希
-	err = bcrypt.CompareHashAndPassword([]byte(result.Password), []byte(u.Password))
+	err = svc.hasher.Verify(result.Password, u.Password)
 	if err != nil {
 		return result, ErrInvalidUserOrPassword
 	}
-	return result, err
+	//只有登录时才拿得到明文 趁机把弱的哈希升级
+	if svc.hasher.NeedsRehash(result.Password) {
+		err = svc.EditUserPassword(ctx, domain.User{Id: result.Id, Password: u.Password})
+		if err != nil {
+			log.Printf("升级密码哈希失败,uid:%d,err:%v", result.Id, err)
+		}
+	}
+	return svc.restore(ctx, result)
+}
+
+// restore 申请注销的账号在彻底删除之前登录 就撤销注销
+func (svc *UserDevService) restore(ctx context.Context, u domain.User) (domain.User, error) {
+	if u.DeleteAt.IsZero() {
+		return u, nil
+	}
+	err := svc.repo.UpdateDeleteAt(ctx, u.Id, time.Time{})
+	if err != nil {
+		return domain.User{}, err
+	}
+	u.DeleteAt = time.Time{}
+	return u, nil
 }
 
 func (svc *UserDevService) FindOrCreate(ctx context.Context, u domain.User) (domain.User, error) {
 	//快路径
 	uResult, err := svc.repo.FindByPhone(ctx, u)
+	if err == nil {
+		return svc.restore(ctx, uResult)
+	}
 	if err != ErrUserNotFind {
 		return uResult, err
 	}
@@ -91,11 +145,11 @@ func (svc *UserDevService) FindOrCreate(ctx context.Context, u domain.User) (dom
 }
 
 func (svc *UserDevService) EditUserPassword(ctx context.Context, u domain.User) error {
-	password, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	hash, err := svc.hasher.Hash(u.Password)
 	if err != nil {
 		return err
 	}
-	u.Password = string(password)
+	u.Password = hash
 	err = svc.repo.Update(ctx, u)
 	if err != nil {
 		return err
@@ -103,7 +157,139 @@ func (svc *UserDevService) EditUserPassword(ctx context.Context, u domain.User)
 	return err
 }
 
+func (svc *UserDevService) ChangePassword(ctx context.Context, userId int, oldPassword string, newPassword string) error {
+	u, err := svc.repo.FindById(ctx, domain.User{Id: userId})
+	if err != nil {
+		return err
+	}
+	//手机号和微信注册的用户没有密码 只能走找回密码
+	if u.Password == "" {
+		return ErrPasswordMismatch
+	}
+	err = svc.hasher.Verify(u.Password, oldPassword)
+	if err != nil {
+		return ErrPasswordMismatch
+	}
+	return svc.EditUserPassword(ctx, domain.User{
+		Id:       userId,
+		Password: newPassword,
+	})
+}
+
 func (svc *UserDevService) Profile(ctx context.Context, u domain.User) (domain.User, error) {
 	user, err := svc.repo.FindById(ctx, u)
 	return user, err
 }
+
+func (svc *UserDevService) UpdateProfile(ctx context.Context, u domain.User) error {
+	return svc.repo.UpdateProfile(ctx, u)
+}
+
+// Identity 可以绑定到账号上的登录方式
+type Identity string
+
+const (
+	IdentityPhone  Identity = "phone"
+	IdentityEmail  Identity = "email"
+	IdentityWechat Identity = "wechat"
+)
+
+func (svc *UserDevService) BindPhone(ctx context.Context, userId int, phone string) error {
+	other, err := svc.repo.FindByPhone(ctx, domain.User{Phone: phone})
+	if err == nil && other.Id != userId {
+		return ErrPhoneConflict
+	}
+	if err != nil && !errors.Is(err, ErrUserNotFind) {
+		return err
+	}
+	return svc.updateIdentity(ctx, userId, ErrPhoneConflict, func(u *domain.User) {
+		u.Phone = phone
+	})
+}
+
+func (svc *UserDevService) BindEmail(ctx context.Context, userId int, email string) error {
+	other, err := svc.repo.FindByEmail(ctx, domain.User{Email: email})
+	if err == nil && other.Id != userId {
+		return ErrEmailConflict
+	}
+	if err != nil && !errors.Is(err, ErrUserNotFind) {
+		return err
+	}
+	return svc.updateIdentity(ctx, userId, ErrEmailConflict, func(u *domain.User) {
+		//调用方已经用验证码确认过邮箱
+		u.Email = email
+		u.EmailVerified = true
+	})
+}
+
+func (svc *UserDevService) BindWechat(ctx context.Context, userId int, info domain.WechatInfo) error {
+	other, err := svc.repo.FindByWechat(ctx, info.OpenID)
+	if err == nil && other.Id != userId {
+		return ErrWechatConflict
+	}
+	if err != nil && !errors.Is(err, ErrUserNotFind) {
+		return err
+	}
+	return svc.updateIdentity(ctx, userId, ErrWechatConflict, func(u *domain.User) {
+		u.WechatInfo = info
+	})
+}
+
+func (svc *UserDevService) Unbind(ctx context.Context, userId int, identity Identity) error {
+	return svc.updateIdentity(ctx, userId, nil, func(u *domain.User) {
+		switch identity {
+		case IdentityPhone:
+			u.Phone = ""
+		case IdentityEmail:
+			u.Email = ""
+			u.EmailVerified = false
+		case IdentityWechat:
+			u.WechatInfo = domain.WechatInfo{}
+		}
+	})
+}
+
+// updateIdentity 并发绑定时靠唯一索引兜底 冲突时返回conflictErr
+func (svc *UserDevService) updateIdentity(ctx context.Context, userId int, conflictErr error,
+	update func(u *domain.User)) error {
+	u, err := svc.repo.FindById(ctx, domain.User{Id: userId})
+	if err != nil {
+		return err
+	}
+	update(&u)
+	if u.Phone == "" && u.Email == "" && u.WechatInfo.OpenID == "" {
+		return ErrLastLoginMethod
+	}
+	err = svc.repo.UpdateLoginIdentity(ctx, u)
+	if errors.Is(err, repository.ErrUserDuplicate) && conflictErr != nil {
+		return conflictErr
+	}
+	return err
+}
+
+func (svc *UserDevService) RequestDeletion(ctx context.Context, userId int) (time.Time, error) {
+	deleteAt := time.Now().Add(DeletionGracePeriod)
+	err := svc.repo.UpdateDeleteAt(ctx, userId, deleteAt)
+	return deleteAt, err
+}
+
+func (svc *UserDevService) PurgeDeleted(ctx context.Context, limit int) ([]int, error) {
+	now := time.Now()
+	ids, err := svc.repo.FindDeleteDue(ctx, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	purged := make([]int, 0, len(ids))
+	for _, id := range ids {
+		err = svc.repo.Purge(ctx, id, now)
+		switch {
+		case err == nil:
+			purged = append(purged, id)
+		case errors.Is(err, ErrUserNotFind):
+			//查出来之后用户登录撤销了注销
+		default:
+			return purged, err
+		}
+	}
+	return purged, nil
+}
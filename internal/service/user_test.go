@@ -2,15 +2,21 @@ package service
 
 import (
 	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 	"golang.org/x/crypto/bcrypt"
 	"testing"
+	"time"
 	"webook/internal/domain"
 	"webook/internal/repository"
 	repomocks "webook/internal/repository/mocks"
+	"webook/pkg/password"
 )
 
+// testHasher 和线上默认的bcrypt cost一致
+var testHasher = password.NewBcryptHasher(bcrypt.DefaultCost)
+
 func TestUserDevService_Login(t *testing.T) {
 	testCases := []struct {
 		name string
@@ -49,6 +55,49 @@ func TestUserDevService_Login(t *testing.T) {
 				Password: "$2a$10$e.u5gkPeXdL6s8tNpBcjSe1DPfHgZEL1jJ4kNoMuzxkVOzbeRRb9u2",
 			},
 		},
+		{
+			name: "用户不存在",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				userRepository := repomocks.NewMockUserRepository(ctrl)
+				userRepository.EXPECT().FindByEmail(gomock.Any(), gomock.Any()).
+					Return(domain.User{}, ErrUserNotFind)
+				return userRepository
+			},
+			inputUser: domain.User{
+				Email:    "12345@qq.com",
+				Password: "5123412312asd@",
+			},
+			wantErr: ErrInvalidUserOrPassword,
+		},
+		{
+			name: "bcrypt的cost太低 登录后升级",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				userRepository := repomocks.NewMockUserRepository(ctrl)
+				userRepository.EXPECT().FindByEmail(gomock.Any(), gomock.Any()).
+					Return(domain.User{
+						Id:       1,
+						Email:    "12345@qq.com",
+						Password: "$2a$04$OcCF.F4ZrCk10KY5go0xoeNma50PxNQ9yea6l4Q/W3HT1XXPP0oX.",
+					}, nil)
+				userRepository.EXPECT().Update(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, u domain.User) error {
+						assert.Equal(t, 1, u.Id)
+						assert.NoError(t, testHasher.Verify(u.Password, "5123412312asd@"))
+						assert.False(t, testHasher.NeedsRehash(u.Password))
+						return nil
+					})
+				return userRepository
+			},
+			inputUser: domain.User{
+				Email:    "12345@qq.com",
+				Password: "5123412312asd@",
+			},
+			wantUser: domain.User{
+				Id:       1,
+				Email:    "12345@qq.com",
+				Password: "$2a$04$OcCF.F4ZrCk10KY5go0xoeNma50PxNQ9yea6l4Q/W3HT1XXPP0oX.",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -56,7 +105,7 @@ func TestUserDevService_Login(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			svc := NewUserDevService(tc.mock(ctrl))
+			svc := NewUserDevService(tc.mock(ctrl), testHasher)
 			u, err := svc.Login(context.Background(), tc.inputUser)
 			assert.Equal(t, tc.wantErr, err)
 			assert.Equal(t, tc.wantUser, u)
@@ -65,6 +114,255 @@ func TestUserDevService_Login(t *testing.T) {
 	}
 }
 
+func TestUserDevService_ChangePassword(t *testing.T) {
+	//5123412312asd@
+	const hash = "$2a$10$e.u5gkPeXdL6s8tNpBcjSe1DPfHgZEL1jJ4kNoMuzxkVOzbeRRb9u2"
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		oldPassword string
+
+		wantErr error
+	}{
+		{
+			name: "修改成功",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				userRepository := repomocks.NewMockUserRepository(ctrl)
+				userRepository.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Password: hash}, nil)
+				userRepository.EXPECT().Update(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, u domain.User) error {
+						//按id更新 新密码要加密
+						assert.Equal(t, 1, u.Id)
+						assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(u.Password), []byte("new123456@")))
+						return nil
+					})
+				return userRepository
+			},
+			oldPassword: "5123412312asd@",
+		},
+		{
+			name: "原密码不对",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				userRepository := repomocks.NewMockUserRepository(ctrl)
+				userRepository.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Password: hash}, nil)
+				return userRepository
+			},
+			oldPassword: "wrong",
+			wantErr:     ErrPasswordMismatch,
+		},
+		{
+			name: "没有设置过密码",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				userRepository := repomocks.NewMockUserRepository(ctrl)
+				userRepository.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Phone: "186xxx"}, nil)
+				return userRepository
+			},
+			oldPassword: "",
+			wantErr:     ErrPasswordMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := NewUserDevService(tc.mock(ctrl), testHasher)
+			err := svc.ChangePassword(context.Background(), 1, tc.oldPassword, "new123456@")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestUserDevService_Bind(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+		bind func(svc UserService) error
+
+		wantErr error
+	}{
+		{
+			name: "绑定手机号",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), domain.User{Phone: "186xxx"}).
+					Return(domain.User{}, ErrUserNotFind)
+				repo.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "123@qq.com", EmailVerified: true}, nil)
+				repo.EXPECT().UpdateLoginIdentity(gomock.Any(), domain.User{
+					Id: 1, Email: "123@qq.com", EmailVerified: true, Phone: "186xxx",
+				}).Return(nil)
+				return repo
+			},
+			bind: func(svc UserService) error {
+				return svc.BindPhone(context.Background(), 1, "186xxx")
+			},
+		},
+		{
+			name: "手机号被其他账号占用",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), domain.User{Phone: "186xxx"}).
+					Return(domain.User{Id: 2, Phone: "186xxx"}, nil)
+				return repo
+			},
+			bind: func(svc UserService) error {
+				return svc.BindPhone(context.Background(), 1, "186xxx")
+			},
+			wantErr: ErrPhoneConflict,
+		},
+		{
+			name: "更换邮箱 验证码已经证明了邮箱归属",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), domain.User{Email: "456@qq.com"}).
+					Return(domain.User{}, ErrUserNotFind)
+				repo.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				repo.EXPECT().UpdateLoginIdentity(gomock.Any(), domain.User{
+					Id: 1, Email: "456@qq.com", EmailVerified: true,
+				}).Return(nil)
+				return repo
+			},
+			bind: func(svc UserService) error {
+				return svc.BindEmail(context.Background(), 1, "456@qq.com")
+			},
+		},
+		{
+			name: "并发绑定 唯一索引冲突",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), domain.User{Email: "123@qq.com"}).
+					Return(domain.User{}, ErrUserNotFind)
+				repo.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Phone: "186xxx"}, nil)
+				repo.EXPECT().UpdateLoginIdentity(gomock.Any(), gomock.Any()).Return(repository.ErrUserDuplicate)
+				return repo
+			},
+			bind: func(svc UserService) error {
+				return svc.BindEmail(context.Background(), 1, "123@qq.com")
+			},
+			wantErr: ErrEmailConflict,
+		},
+		{
+			name: "解绑最后一种登录方式",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Phone: "186xxx"}, nil)
+				return repo
+			},
+			bind: func(svc UserService) error {
+				return svc.Unbind(context.Background(), 1, IdentityPhone)
+			},
+			wantErr: ErrLastLoginMethod,
+		},
+		{
+			name: "解绑微信",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), domain.User{Id: 1}).Return(domain.User{
+					Id: 1, Phone: "186xxx", WechatInfo: domain.WechatInfo{OpenID: "open", UnionID: "union"},
+				}, nil)
+				repo.EXPECT().UpdateLoginIdentity(gomock.Any(), domain.User{Id: 1, Phone: "186xxx"}).Return(nil)
+				return repo
+			},
+			bind: func(svc UserService) error {
+				return svc.Unbind(context.Background(), 1, IdentityWechat)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := NewUserDevService(tc.mock(ctrl), testHasher)
+			err := tc.bind(svc)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestUserDevService_PurgeDeleted(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		wantIds []int
+		wantErr error
+	}{
+		{
+			name: "删除到期账号",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindDeleteDue(gomock.Any(), gomock.Any(), 100).Return([]int{1, 2}, nil)
+				repo.EXPECT().Purge(gomock.Any(), 1, gomock.Any()).Return(nil)
+				repo.EXPECT().Purge(gomock.Any(), 2, gomock.Any()).Return(nil)
+				return repo
+			},
+			wantIds: []int{1, 2},
+		},
+		{
+			name: "用户在删除前撤销了注销",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindDeleteDue(gomock.Any(), gomock.Any(), 100).Return([]int{1, 2}, nil)
+				repo.EXPECT().Purge(gomock.Any(), 1, gomock.Any()).Return(ErrUserNotFind)
+				repo.EXPECT().Purge(gomock.Any(), 2, gomock.Any()).Return(nil)
+				return repo
+			},
+			wantIds: []int{2},
+		},
+		{
+			name: "数据库出错",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindDeleteDue(gomock.Any(), gomock.Any(), 100).Return([]int{1, 2}, nil)
+				repo.EXPECT().Purge(gomock.Any(), 1, gomock.Any()).Return(errors.New("db错误"))
+				return repo
+			},
+			wantIds: []int{},
+			wantErr: errors.New("db错误"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := NewUserDevService(tc.mock(ctrl), testHasher)
+			ids, err := svc.PurgeDeleted(context.Background(), 100)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantIds, ids)
+		})
+	}
+}
+
+func TestUserDevService_FindOrCreate_Restore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := repomocks.NewMockUserRepository(ctrl)
+	deleteAt := time.Now().Add(time.Hour)
+	repo.EXPECT().FindByPhone(gomock.Any(), domain.User{Phone: "186xxx"}).
+		Return(domain.User{Id: 1, Phone: "186xxx", DeleteAt: deleteAt}, nil)
+	//冷静期内登录 撤销注销
+	repo.EXPECT().UpdateDeleteAt(gomock.Any(), 1, time.Time{}).Return(nil)
+
+	svc := NewUserDevService(repo, testHasher)
+	u, err := svc.FindOrCreate(context.Background(), domain.User{Phone: "186xxx"})
+	assert.NoError(t, err)
+	assert.True(t, u.DeleteAt.IsZero())
+}
+
 func TestEncrypted(t *testing.T) {
 	res, err := bcrypt.GenerateFromPassword([]byte("5123412312asd@"), bcrypt.DefaultCost)
 	if err == nil {
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"webook/internal/service"
+	"webook/internal/web/middleware"
+)
+
+// EmailVerifyHandler 验证邮箱
+type EmailVerifyHandler struct {
+	svc service.EmailVerifyService
+}
+
+func NewEmailVerifyHandler(svc service.EmailVerifyService) *EmailVerifyHandler {
+	return &EmailVerifyHandler{svc: svc}
+}
+
+func (h *EmailVerifyHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/users/email/verify")
+	//没验证时可能登录不了 所以按邮箱重新发送
+	g.POST("/send", middleware.Public(), h.Send)
+	g.POST("", middleware.Public(), h.Verify)
+}
+
+// Send 重新发送验证邮件
+func (h *EmailVerifyHandler) Send(ctx *gin.Context) {
+	type Req struct {
+		Email string `json:"email"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	err := h.svc.SendVerifyEmail(ctx, req.Email)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "发送成功",
+		})
+	case errors.Is(err, service.ErrVerifySendTooMany):
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "发送太频繁,请稍后再试",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// Verify 验证页面带着链接里的token提交
+func (h *EmailVerifyHandler) Verify(ctx *gin.Context) {
+	type Req struct {
+		Token string `json:"token"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	err := h.svc.Verify(ctx, req.Token)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "验证成功",
+		})
+	case errors.Is(err, service.ErrVerifyTokenInvalid):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "验证链接无效或已过期",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
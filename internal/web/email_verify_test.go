@@ -0,0 +1,89 @@
+package web
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	repomocks "webook/internal/repository/mocks"
+	"webook/internal/service"
+	"webook/internal/service/email"
+	emailmocks "webook/internal/service/email/mocks"
+	"webook/internal/service/email/templates"
+	pkgmocks "webook/pkg/mocks"
+)
+
+// 没注册 已经验证过 等待验证 三种邮箱的响应必须完全一样 否则可以用来探测哪些邮箱注册过
+func TestEmailVerifyHandler_Send(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, email.Service)
+	}{
+		{
+			name: "邮箱没有注册",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, email.Service) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), domain.User{Email: "123@qq.com"}).
+					Return(domain.User{}, repository.ErrUserNotFind)
+				return repo, emailmocks.NewMockService(ctrl)
+			},
+		},
+		{
+			name: "邮箱已经验证过",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, email.Service) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), domain.User{Email: "123@qq.com"}).
+					Return(domain.User{Id: 1, Email: "123@qq.com", EmailVerified: true}, nil)
+				return repo, emailmocks.NewMockService(ctrl)
+			},
+		},
+		{
+			name: "邮箱等待验证",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, email.Service) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), domain.User{Email: "123@qq.com"}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				emailSvc := emailmocks.NewMockService(ctrl)
+				emailSvc.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil)
+				return repo, emailSvc
+			},
+		},
+	}
+
+	tpls, err := templates.NewDefaultTemplates()
+	require.NoError(t, err)
+	bodies := make([]string, 0, len(testCases))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			limiter := pkgmocks.NewMockLimiter(ctrl)
+			limiter.EXPECT().Limit(gomock.Any(), "email_verify:123@qq.com").Return(false, nil)
+			repo, emailSvc := tc.mock(ctrl)
+			svc := service.NewEmailVerifyDevService(repo, nil, emailSvc, tpls, limiter, []byte("secret"), "", false)
+
+			server := gin.Default()
+			NewEmailVerifyHandler(svc).RegisterRoutes(server)
+			req := httptest.NewRequest(http.MethodPost, "/users/email/verify/send",
+				bytes.NewBuffer([]byte(`{"email":"123@qq.com"}`)))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.JSONEq(t, `{"code":"","msg":"发送成功","data":null}`, resp.Body.String())
+			bodies = append(bodies, resp.Body.String())
+		})
+	}
+	for _, body := range bodies {
+		assert.Equal(t, bodies[0], body)
+	}
+}
@@ -0,0 +1,128 @@
+package ijwt
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+var (
+	ErrDeviceMismatch  = errors.New("设备不匹配")
+	ErrDeviceIdMissing = errors.New("请求头里没有设备id")
+)
+
+// DeviceBinding 短token和设备的绑定策略
+// 签发时把设备指纹写进claims 校验时重新计算并比对
+type DeviceBinding interface {
+	// Fingerprint 算不出指纹时返回error 不签发token 校验时按设备不匹配处理
+	Fingerprint(ctx *gin.Context) (string, error)
+}
+
+// NoneBinding 不绑定设备
+type NoneBinding struct {
+}
+
+func NewNoneBinding() *NoneBinding {
+	return &NoneBinding{}
+}
+
+func (n *NoneBinding) Fingerprint(ctx *gin.Context) (string, error) {
+	return "", nil
+}
+
+// UserAgentBinding User-Agent必须完全一致 浏览器自动升级后需要重新登录
+type UserAgentBinding struct {
+}
+
+func NewUserAgentBinding() *UserAgentBinding {
+	return &UserAgentBinding{}
+}
+
+func (u *UserAgentBinding) Fingerprint(ctx *gin.Context) (string, error) {
+	return ctx.Request.UserAgent(), nil
+}
+
+// UAFamilyBinding 只比较浏览器和操作系统 withMajor为true时还要比较浏览器大版本
+type UAFamilyBinding struct {
+	withMajor bool
+}
+
+func NewUAFamilyBinding(withMajor bool) *UAFamilyBinding {
+	return &UAFamilyBinding{withMajor: withMajor}
+}
+
+func (u *UAFamilyBinding) Fingerprint(ctx *gin.Context) (string, error) {
+	ua := ctx.Request.UserAgent()
+	browser, version := uaBrowser(ua)
+	fp := browser + "/" + uaOS(ua)
+	if u.withMajor {
+		major, _, _ := strings.Cut(version, ".")
+		fp += "/" + major
+	}
+	return fp, nil
+}
+
+// DeviceIdBinding 客户端自己生成设备id 放在请求头里
+// 没带请求头的请求不能登录 否则不带请求头就能绕过绑定
+type DeviceIdBinding struct {
+	header string
+}
+
+func NewDeviceIdBinding(header string) *DeviceIdBinding {
+	return &DeviceIdBinding{header: header}
+}
+
+func (d *DeviceIdBinding) Fingerprint(ctx *gin.Context) (string, error) {
+	id := ctx.GetHeader(d.header)
+	if id == "" {
+		return "", ErrDeviceIdMissing
+	}
+	return id, nil
+}
+
+// uaBrowser 识别浏览器 顺序很重要 Edge和Opera的UA里也带了Chrome Chrome的UA里也带了Safari
+func uaBrowser(ua string) (string, string) {
+	products := []struct {
+		token string
+		name  string
+	}{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Version/", "Safari"},
+		{"MSIE ", "IE"},
+	}
+	for _, p := range products {
+		idx := strings.Index(ua, p.token)
+		if idx < 0 {
+			continue
+		}
+		version := ua[idx+len(p.token):]
+		if end := strings.IndexAny(version, " ;)"); end >= 0 {
+			version = version[:end]
+		}
+		return p.name, version
+	}
+	//不认识的客户端 例如okhttp/4.9.0 取第一个产品名
+	first, _, _ := strings.Cut(ua, " ")
+	name, version, _ := strings.Cut(first, "/")
+	return name, version
+}
+
+func uaOS(ua string) string {
+	switch {
+	case strings.Contains(ua, "Windows"):
+		return "Windows"
+	case strings.Contains(ua, "Android"):
+		return "Android"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		return "iOS"
+	case strings.Contains(ua, "Mac OS X"):
+		return "macOS"
+	case strings.Contains(ua, "Linux"):
+		return "Linux"
+	default:
+		return ""
+	}
+}
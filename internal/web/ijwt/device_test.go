@@ -0,0 +1,120 @@
+package ijwt
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUAFamilyBinding_Fingerprint(t *testing.T) {
+	const (
+		chrome117 = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/117.0.0.0 Safari/537.36"
+		chrome118 = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.5993.70 Safari/537.36"
+		edge118   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36 Edg/118.0.2088.46"
+		safariMac = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15"
+	)
+	testCases := []struct {
+		name      string
+		withMajor bool
+		issued    string
+		current   string
+
+		wantMatch bool
+	}{
+		{
+			name:      "浏览器自动升级",
+			issued:    chrome117,
+			current:   chrome118,
+			wantMatch: true,
+		},
+		{
+			name:      "比较大版本-浏览器升级",
+			withMajor: true,
+			issued:    chrome117,
+			current:   chrome118,
+			wantMatch: false,
+		},
+		{
+			name:      "比较大版本-小版本不同",
+			withMajor: true,
+			issued:    "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36",
+			current:   chrome118,
+			wantMatch: true,
+		},
+		{
+			name:    "换了浏览器",
+			issued:  chrome118,
+			current: edge118,
+		},
+		{
+			name:    "换了操作系统",
+			issued:  chrome118,
+			current: safariMac,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			binding := NewUAFamilyBinding(tc.withMajor)
+			fingerprint := func(ua string) string {
+				ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+				ctx.Request = httptest.NewRequest(http.MethodGet, "/users/profile", nil)
+				ctx.Request.Header.Set("User-Agent", ua)
+				fp, err := binding.Fingerprint(ctx)
+				require.NoError(t, err)
+				return fp
+			}
+			assert.Equal(t, tc.wantMatch, fingerprint(tc.issued) == fingerprint(tc.current))
+		})
+	}
+}
+
+func TestRedisJwt_CheckDevice(t *testing.T) {
+	testCases := []struct {
+		name     string
+		deviceId string
+		claims   *UserClaims
+
+		wantErr error
+	}{
+		{
+			name:     "设备id一致",
+			deviceId: "device-1",
+			claims:   &UserClaims{Device: "device-1"},
+		},
+		{
+			name:     "设备id不一致",
+			deviceId: "device-2",
+			claims:   &UserClaims{Device: "device-1"},
+			wantErr:  ErrDeviceMismatch,
+		},
+		{
+			name:    "不带请求头 token里也没有设备id",
+			claims:  &UserClaims{},
+			wantErr: ErrDeviceMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/users/profile", nil)
+			if tc.deviceId != "" {
+				ctx.Request.Header.Set("X-Device-Id", tc.deviceId)
+			}
+			r := NewRedisJwt(nil, nil, NewHeaderTransport(), NewDeviceIdBinding("X-Device-Id"), nil)
+			assert.Equal(t, tc.wantErr, r.CheckDevice(ctx, tc.claims))
+		})
+	}
+}
+
+func TestRedisJwt_SetLoginToken_DeviceIdMissing(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/users/login", nil)
+	//没有设备id 不能创建会话 cmd为nil 调用到redis会panic
+	r := NewRedisJwt(nil, nil, NewHeaderTransport(), NewDeviceIdBinding("X-Device-Id"), nil)
+	assert.Equal(t, ErrDeviceIdMissing, r.SetLoginToken(ctx, 1, LoginMethodEmail))
+}
@@ -0,0 +1,193 @@
+package ijwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"webook/config"
+)
+
+// token类型 放在header的typ里 防止短token被当成长token用
+const (
+	typAccessToken  = "at+jwt"
+	typRefreshToken = "rt+jwt"
+	typMFAToken     = "mfa+jwt"
+)
+
+var (
+	ErrKeyNotFind = errors.New("找不到对应kid的公钥")
+)
+
+// Key 一对签名密钥 只用于验签的老key没有私钥
+type Key struct {
+	Kid     string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// KeySet 用当前的key签名 用所有的key验签 以支持密钥轮换
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewKeySet signingKid对应的key必须带私钥
+func NewKeySet(signingKid string, keys ...*Key) (*KeySet, error) {
+	ks := &KeySet{
+		keys: make(map[string]*Key, len(keys)),
+	}
+	for _, key := range keys {
+		ks.keys[key.Kid] = key
+	}
+	signing, ok := ks.keys[signingKid]
+	if !ok || signing.Private == nil {
+		return nil, fmt.Errorf("签名密钥%s不存在或没有私钥", signingKid)
+	}
+	ks.signing = signing
+	return ks, nil
+}
+
+// LoadKeySet 从配置的PEM文件加载密钥
+func LoadKeySet(cfg config.JWTConfig) (*KeySet, error) {
+	keys := make([]*Key, 0, len(cfg.Keys))
+	for _, kc := range cfg.Keys {
+		key, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("加载密钥%s失败,%w", kc.Kid, err)
+		}
+		keys = append(keys, key)
+	}
+	return NewKeySet(cfg.SigningKid, keys...)
+}
+
+// NewEphemeralKeySet 临时生成一个Ed25519密钥 重启之后所有token失效 只用于开发环境
+func NewEphemeralKeySet() (*KeySet, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySet("dev", &Key{
+		Kid:     "dev",
+		Method:  jwt.SigningMethodEdDSA,
+		Private: priv,
+		Public:  pub,
+	})
+}
+
+func (ks *KeySet) sign(claims jwt.Claims, typ string) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	token.Header["kid"] = ks.signing.Kid
+	token.Header["typ"] = typ
+	return token.SignedString(ks.signing.Private)
+}
+
+func (ks *KeySet) parse(tokenStr string, claims jwt.Claims, typ string) error {
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		if t, _ := token.Header["typ"].(string); t != typ {
+			return nil, ErrTokenInvalid
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, ErrKeyNotFind
+		}
+		//防止算法混淆 alg必须和key匹配
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, ErrTokenInvalid
+		}
+		return key.Public, nil
+	})
+	if err != nil {
+		return err
+	}
+	if token == nil || !token.Valid {
+		return ErrTokenInvalid
+	}
+	return nil
+}
+
+// JWKS 公开所有验签公钥 RFC 7517
+func (ks *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		jwk := JWK{
+			Kid: key.Kid,
+			Alg: key.Method.Alg(),
+			Use: "sig",
+		}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	//RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	//Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+func loadKey(kc config.JWTKeyConfig) (*Key, error) {
+	key := &Key{Kid: kc.Kid}
+	if kc.PrivateKeyFile != "" {
+		data, err := os.ReadFile(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if priv, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			key.Method, key.Private, key.Public = jwt.SigningMethodRS256, priv, priv.Public()
+			return key, nil
+		}
+		priv, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, errors.New("私钥只支持RSA和Ed25519")
+		}
+		signer := priv.(crypto.Signer)
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, signer, signer.Public()
+		return key, nil
+	}
+	data, err := os.ReadFile(kc.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if pub, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		key.Method, key.Public = jwt.SigningMethodRS256, pub
+		return key, nil
+	}
+	pub, err := jwt.ParseEdPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, errors.New("公钥只支持RSA和Ed25519")
+	}
+	key.Method, key.Public = jwt.SigningMethodEdDSA, pub
+	return key, nil
+}
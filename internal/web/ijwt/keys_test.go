@@ -0,0 +1,84 @@
+package ijwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestKeySet_Parse(t *testing.T) {
+	oldPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	oldKey := &Key{Kid: "old", Method: jwt.SigningMethodRS256, Private: oldPriv, Public: oldPriv.Public()}
+	newKey := &Key{Kid: "new", Method: jwt.SigningMethodRS256, Private: newPriv, Public: newPriv.Public()}
+
+	//老key签出来的token
+	oldKs, err := NewKeySet("old", oldKey)
+	require.NoError(t, err)
+	oldToken, err := oldKs.sign(UserClaims{UserId: 1}, typAccessToken)
+	require.NoError(t, err)
+	//轮换之后 老key只用于验签
+	ks, err := NewKeySet("new", newKey, &Key{Kid: "old", Method: jwt.SigningMethodRS256, Public: oldPriv.Public()})
+	require.NoError(t, err)
+	refreshToken, err := ks.sign(RefreshClaims{Uid: 1}, typRefreshToken)
+	require.NoError(t, err)
+	//不认识的key签出来的token
+	otherKs, err := NewEphemeralKeySet()
+	require.NoError(t, err)
+	otherToken, err := otherKs.sign(UserClaims{UserId: 1}, typAccessToken)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		token string
+		typ   string
+
+		wantErr bool
+	}{
+		{
+			name:  "老key签的token依旧有效",
+			token: oldToken,
+			typ:   typAccessToken,
+		},
+		{
+			name:  "新key签的长token",
+			token: refreshToken,
+			typ:   typRefreshToken,
+		},
+		{
+			name:    "长token不能当短token用",
+			token:   refreshToken,
+			typ:     typAccessToken,
+			wantErr: true,
+		},
+		{
+			name:    "未知kid",
+			token:   otherToken,
+			typ:     typAccessToken,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ks.parse(tc.token, &UserClaims{}, tc.typ)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	ks, err := NewEphemeralKeySet()
+	require.NoError(t, err)
+	set := ks.JWKS()
+	require.Len(t, set.Keys, 1)
+	assert.Equal(t, "OKP", set.Keys[0].Kty)
+	assert.Equal(t, "EdDSA", set.Keys[0].Alg)
+	assert.Equal(t, "dev", set.Keys[0].Kid)
+	assert.NotEmpty(t, set.Keys[0].X)
+}
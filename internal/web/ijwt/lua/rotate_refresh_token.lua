@@ -0,0 +1,31 @@
+-- 会话在Redis上的key
+-- users:ssid:xx
+local key = KEYS[1]
+-- 用户的会话索引
+-- users:sessions:xx
+local userKey = KEYS[2]
+-- 客户端出示的长token id 为空说明是直接签发 不需要比对
+local expectedId = ARGV[1]
+-- 新的长token id
+local newId = ARGV[2]
+-- 刷新时间
+local utime = ARGV[3]
+-- 新的长token的有效期 ms
+local ttl = tonumber(ARGV[4])
+if redis.call("exists", key) == 0 then
+    -- 会话已经失效
+    return -1
+end
+local curId = redis.call("hget", key, "rtid")
+if curId == false then
+    curId = ""
+end
+if expectedId ~= "" and curId ~= expectedId then
+    -- 出示的是已经被轮换掉的长token 说明被重放了
+    return -2
+end
+redis.call("hset", key, "rtid", newId, "utime", utime)
+-- 会话要和新的长token活得一样久
+redis.call("pexpire", key, ttl)
+redis.call("pexpire", userKey, ttl)
+return 0
@@ -0,0 +1,309 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/web/ijwt/types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/web/ijwt/types.go -package=jwtmocks -destination=internal/web/ijwt/mocks/handler_gen.go
+//
+// Package jwtmocks is a generated GoMock package.
+package jwtmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	ijwt "webook/internal/web/ijwt"
+
+	gin "github.com/gin-gonic/gin"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockHandler is a mock of Handler interface.
+type MockHandler struct {
+	ctrl     *gomock.Controller
+	recorder *MockHandlerMockRecorder
+}
+
+// MockHandlerMockRecorder is the mock recorder for MockHandler.
+type MockHandlerMockRecorder struct {
+	mock *MockHandler
+}
+
+// NewMockHandler creates a new mock instance.
+func NewMockHandler(ctrl *gomock.Controller) *MockHandler {
+	mock := &MockHandler{ctrl: ctrl}
+	mock.recorder = &MockHandlerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHandler) EXPECT() *MockHandlerMockRecorder {
+	return m.recorder
+}
+
+// CheckDevice mocks base method.
+func (m *MockHandler) CheckDevice(ctx *gin.Context, claims *ijwt.UserClaims) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckDevice", ctx, claims)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckDevice indicates an expected call of CheckDevice.
+func (mr *MockHandlerMockRecorder) CheckDevice(ctx, claims any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckDevice", reflect.TypeOf((*MockHandler)(nil).CheckDevice), ctx, claims)
+}
+
+// CheckSession mocks base method.
+func (m *MockHandler) CheckSession(ctx *gin.Context, ssid string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckSession", ctx, ssid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckSession indicates an expected call of CheckSession.
+func (mr *MockHandlerMockRecorder) CheckSession(ctx, ssid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSession", reflect.TypeOf((*MockHandler)(nil).CheckSession), ctx, ssid)
+}
+
+// ClearToken mocks base method.
+func (m *MockHandler) ClearToken(ctx *gin.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearToken", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearToken indicates an expected call of ClearToken.
+func (mr *MockHandlerMockRecorder) ClearToken(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearToken", reflect.TypeOf((*MockHandler)(nil).ClearToken), ctx)
+}
+
+// ExtractRefreshToken mocks base method.
+func (m *MockHandler) ExtractRefreshToken(ctx *gin.Context) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExtractRefreshToken", ctx)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ExtractRefreshToken indicates an expected call of ExtractRefreshToken.
+func (mr *MockHandlerMockRecorder) ExtractRefreshToken(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractRefreshToken", reflect.TypeOf((*MockHandler)(nil).ExtractRefreshToken), ctx)
+}
+
+// ExtractToken mocks base method.
+func (m *MockHandler) ExtractToken(ctx *gin.Context) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExtractToken", ctx)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ExtractToken indicates an expected call of ExtractToken.
+func (mr *MockHandlerMockRecorder) ExtractToken(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractToken", reflect.TypeOf((*MockHandler)(nil).ExtractToken), ctx)
+}
+
+// IssueMFAToken mocks base method.
+func (m *MockHandler) IssueMFAToken(ctx *gin.Context, userId int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueMFAToken", ctx, userId)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueMFAToken indicates an expected call of IssueMFAToken.
+func (mr *MockHandlerMockRecorder) IssueMFAToken(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueMFAToken", reflect.TypeOf((*MockHandler)(nil).IssueMFAToken), ctx, userId)
+}
+
+// ListSessions mocks base method.
+func (m *MockHandler) ListSessions(ctx *gin.Context, userId int) ([]ijwt.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", ctx, userId)
+	ret0, _ := ret[0].([]ijwt.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockHandlerMockRecorder) ListSessions(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockHandler)(nil).ListSessions), ctx, userId)
+}
+
+// ParseMFAToken mocks base method.
+func (m *MockHandler) ParseMFAToken(ctx *gin.Context, tokenStr string) (*ijwt.MFAClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseMFAToken", ctx, tokenStr)
+	ret0, _ := ret[0].(*ijwt.MFAClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseMFAToken indicates an expected call of ParseMFAToken.
+func (mr *MockHandlerMockRecorder) ParseMFAToken(ctx, tokenStr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseMFAToken", reflect.TypeOf((*MockHandler)(nil).ParseMFAToken), ctx, tokenStr)
+}
+
+// ParseRefreshToken mocks base method.
+func (m *MockHandler) ParseRefreshToken(tokenStr string) (*ijwt.RefreshClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseRefreshToken", tokenStr)
+	ret0, _ := ret[0].(*ijwt.RefreshClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseRefreshToken indicates an expected call of ParseRefreshToken.
+func (mr *MockHandlerMockRecorder) ParseRefreshToken(tokenStr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseRefreshToken", reflect.TypeOf((*MockHandler)(nil).ParseRefreshToken), tokenStr)
+}
+
+// ParseToken mocks base method.
+func (m *MockHandler) ParseToken(tokenStr string) (*ijwt.UserClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseToken", tokenStr)
+	ret0, _ := ret[0].(*ijwt.UserClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseToken indicates an expected call of ParseToken.
+func (mr *MockHandlerMockRecorder) ParseToken(tokenStr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseToken", reflect.TypeOf((*MockHandler)(nil).ParseToken), tokenStr)
+}
+
+// RevokeAllSessions mocks base method.
+func (m *MockHandler) RevokeAllSessions(ctx *gin.Context, userId int, keepSsid string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAllSessions", ctx, userId, keepSsid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAllSessions indicates an expected call of RevokeAllSessions.
+func (mr *MockHandlerMockRecorder) RevokeAllSessions(ctx, userId, keepSsid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAllSessions", reflect.TypeOf((*MockHandler)(nil).RevokeAllSessions), ctx, userId, keepSsid)
+}
+
+// RevokeSession mocks base method.
+func (m *MockHandler) RevokeSession(ctx *gin.Context, userId int, ssid string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, userId, ssid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockHandlerMockRecorder) RevokeSession(ctx, userId, ssid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockHandler)(nil).RevokeSession), ctx, userId, ssid)
+}
+
+// RotateRefreshToken mocks base method.
+func (m *MockHandler) RotateRefreshToken(ctx *gin.Context, rc *ijwt.RefreshClaims) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateRefreshToken", ctx, rc)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateRefreshToken indicates an expected call of RotateRefreshToken.
+func (mr *MockHandlerMockRecorder) RotateRefreshToken(ctx, rc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockHandler)(nil).RotateRefreshToken), ctx, rc)
+}
+
+// SetJWTToken mocks base method.
+func (m *MockHandler) SetJWTToken(ctx *gin.Context, userId int, ssid string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetJWTToken", ctx, userId, ssid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetJWTToken indicates an expected call of SetJWTToken.
+func (mr *MockHandlerMockRecorder) SetJWTToken(ctx, userId, ssid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetJWTToken", reflect.TypeOf((*MockHandler)(nil).SetJWTToken), ctx, userId, ssid)
+}
+
+// SetLoginToken mocks base method.
+func (m *MockHandler) SetLoginToken(ctx *gin.Context, userId int, method string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLoginToken", ctx, userId, method)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetLoginToken indicates an expected call of SetLoginToken.
+func (mr *MockHandlerMockRecorder) SetLoginToken(ctx, userId, method any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLoginToken", reflect.TypeOf((*MockHandler)(nil).SetLoginToken), ctx, userId, method)
+}
+
+// SetRefreshToken mocks base method.
+func (m *MockHandler) SetRefreshToken(ctx *gin.Context, userId int, ssid string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRefreshToken", ctx, userId, ssid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRefreshToken indicates an expected call of SetRefreshToken.
+func (mr *MockHandlerMockRecorder) SetRefreshToken(ctx, userId, ssid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRefreshToken", reflect.TypeOf((*MockHandler)(nil).SetRefreshToken), ctx, userId, ssid)
+}
+
+// MockPermissionLoader is a mock of PermissionLoader interface.
+type MockPermissionLoader struct {
+	ctrl     *gomock.Controller
+	recorder *MockPermissionLoaderMockRecorder
+}
+
+// MockPermissionLoaderMockRecorder is the mock recorder for MockPermissionLoader.
+type MockPermissionLoaderMockRecorder struct {
+	mock *MockPermissionLoader
+}
+
+// NewMockPermissionLoader creates a new mock instance.
+func NewMockPermissionLoader(ctrl *gomock.Controller) *MockPermissionLoader {
+	mock := &MockPermissionLoader{ctrl: ctrl}
+	mock.recorder = &MockPermissionLoaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPermissionLoader) EXPECT() *MockPermissionLoaderMockRecorder {
+	return m.recorder
+}
+
+// UserPermissions mocks base method.
+func (m *MockPermissionLoader) UserPermissions(ctx context.Context, userId int) ([]string, []string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserPermissions", ctx, userId)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].([]string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// UserPermissions indicates an expected call of UserPermissions.
+func (mr *MockPermissionLoaderMockRecorder) UserPermissions(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserPermissions", reflect.TypeOf((*MockPermissionLoader)(nil).UserPermissions), ctx, userId)
+}
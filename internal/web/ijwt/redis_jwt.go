@@ -1,105 +1,432 @@
 package ijwt
 
 import (
+	"context"
+	_ "embed"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
-	"strings"
+	"strconv"
 	"time"
-	"webook/internal/web"
 )
 
+const (
+	//短token有效期
+	accessTokenExpiration = time.Hour
+	//长token有效期 会话的有效期与之对齐
+	refreshTokenExpiration = time.Hour * 24 * 7
+	//二次验证token有效期
+	mfaTokenExpiration = time.Minute * 5
+	//一个二次验证token最多能试几次验证码
+	mfaMaxAttempts = 5
+)
+
+var (
+	ErrSessionExpired = errors.New("会话已失效")
+	ErrTokenInvalid   = errors.New("token不合法")
+	ErrSessionNotFind = errors.New("会话不存在")
+	//已经被轮换掉的长token又被使用了
+	ErrRefreshTokenReused = errors.New("长token被重复使用")
+	ErrMFATooManyAttempts = errors.New("二次验证尝试次数过多")
+)
+
+//go:embed lua/rotate_refresh_token.lua
+var luaRotateRefreshToken string
+
 type RedisJwt struct {
-	cmd redis.Cmdable
+	cmd       redis.Cmdable
+	keys      *KeySet
+	transport Transport
+	binding   DeviceBinding
+	perms     PermissionLoader
 }
 
-func NewRedisJwt(cmd redis.Cmdable) *RedisJwt {
-	return &RedisJwt{cmd: cmd}
+func NewRedisJwt(cmd redis.Cmdable, keys *KeySet, transport Transport, binding DeviceBinding,
+	perms PermissionLoader) *RedisJwt {
+	return &RedisJwt{cmd: cmd, keys: keys, transport: transport, binding: binding, perms: perms}
 }
 
 func (r *RedisJwt) ExtractToken(ctx *gin.Context) string {
-	tokenHeader := ctx.GetHeader("Authorization")
-	if tokenHeader == "" {
-		return ""
-	}
-	tokens := strings.Split(tokenHeader, " ")
-	if len(tokens) != 2 {
-		return ""
-	}
-	return tokens[1]
+	return r.transport.ExtractAccessToken(ctx)
+}
+
+func (r *RedisJwt) ExtractRefreshToken(ctx *gin.Context) string {
+	return r.transport.ExtractRefreshToken(ctx)
 }
 
-func (r *RedisJwt) SetLoginToken(ctx *gin.Context, userId int) error {
+// SetLoginToken 登录成功 创建会话并下发长短token
+func (r *RedisJwt) SetLoginToken(ctx *gin.Context, userId int, method string) error {
+	//算不出设备指纹就不用创建会话了
+	if _, err := r.binding.Fingerprint(ctx); err != nil {
+		return err
+	}
 	ssid := uuid.New().String()
-	err := r.SetJWTToken(ctx, userId, ssid)
+	err := r.createSession(ctx, Session{
+		Ssid:        ssid,
+		UserId:      userId,
+		UserAgent:   ctx.Request.UserAgent(),
+		IP:          ctx.ClientIP(),
+		LoginMethod: method,
+	})
 	if err != nil {
 		return err
 	}
-	err = r.SetRefreshToken(ctx, userId, ssid)
+	err = r.SetJWTToken(ctx, userId, ssid)
 	if err != nil {
 		return err
 	}
-	return err
+	return r.SetRefreshToken(ctx, userId, ssid)
 }
 
+// ClearToken 退出登录 删除服务端会话
 func (r *RedisJwt) ClearToken(ctx *gin.Context) error {
-	ctx.Header("X-jwt-token", "")
-	ctx.Header("X-refresh-token", "")
+	r.transport.Clear(ctx)
 
-	claims := ctx.MustGet("claims").(*UserClaims)
-	return r.cmd.Set(ctx, fmt.Sprintf("user:ssid:%s", claims.Ssid), "", time.Hour*7*24).Err()
+	claims, ok := GetClaims(ctx)
+	if !ok {
+		return ErrTokenInvalid
+	}
+	return r.deleteSession(ctx, claims.UserId, claims.Ssid)
 }
 
+// CheckSession 会话存在即有效 不存在说明已退出或已过期
 func (r *RedisJwt) CheckSession(ctx *gin.Context, ssid string) error {
-	//TODO implement me
-	panic("implement me")
+	cnt, err := r.cmd.Exists(ctx, r.key(ssid)).Result()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return ErrSessionExpired
+	}
+	return nil
 }
 
-func (r *RedisJwt) SetJWTToken(ctx *gin.Context, userId int, ssid string) error {
-	claims := UserClaims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
-		},
-		Ssid:      ssid,
-		UserId:    userId,
-		UserAgent: ctx.Request.UserAgent(),
+// CheckDevice 请求的设备和签发短token时的设备是否一致
+func (r *RedisJwt) CheckDevice(ctx *gin.Context, claims *UserClaims) error {
+	fp, err := r.binding.Fingerprint(ctx)
+	if err != nil || fp != claims.Device {
+		return ErrDeviceMismatch
 	}
+	return nil
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
-	jwtToken, err := token.SignedString([]byte(web.JwtTokenKey))
+// RotateRefreshToken 用长token换一对新的长短token
+// 出示的长token已经被轮换过 说明长token泄露 整个会话直接下线
+// 先签好新token再轮换 签发失败时旧的长token还能用来重试
+func (r *RedisJwt) RotateRefreshToken(ctx *gin.Context, rc *RefreshClaims) error {
+	if rc.ID == "" {
+		return ErrTokenInvalid
+	}
+	accessToken, err := r.accessToken(ctx, rc.Uid, rc.Ssid)
+	if err != nil {
+		return err
+	}
+	rtid := uuid.New().String()
+	refreshToken, err := r.refreshToken(rc.Uid, rc.Ssid, rtid)
+	if err != nil {
+		return err
+	}
+	err = r.rotate(ctx, rc.Uid, rc.Ssid, rc.ID, rtid)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if er := r.deleteSession(ctx, rc.Uid, rc.Ssid); er != nil {
+			return er
+		}
+		return err
+	}
 	if err != nil {
 		return err
 	}
-	ctx.Header("X-jwt-token", jwtToken)
+	r.transport.SetAccessToken(ctx, accessToken, accessTokenExpiration)
+	r.transport.SetRefreshToken(ctx, refreshToken, refreshTokenExpiration)
 	return nil
 }
 
+// ListSessions 过期的会话会顺便从用户的会话索引中清理掉
+func (r *RedisJwt) ListSessions(ctx *gin.Context, userId int) ([]Session, error) {
+	ssids, err := r.cmd.SMembers(ctx, r.userKey(userId)).Result()
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, 0, len(ssids))
+	for _, ssid := range ssids {
+		sess, err := r.getSession(ctx, ssid)
+		if errors.Is(err, ErrSessionNotFind) {
+			if err = r.cmd.SRem(ctx, r.userKey(userId), ssid).Err(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+	}
+	return sessions, nil
+}
+
+// RevokeSession 只能下线属于自己的会话
+func (r *RedisJwt) RevokeSession(ctx *gin.Context, userId int, ssid string) error {
+	sess, err := r.getSession(ctx, ssid)
+	if err != nil {
+		return err
+	}
+	if sess.UserId != userId {
+		return ErrSessionNotFind
+	}
+	return r.deleteSession(ctx, userId, ssid)
+}
+
+func (r *RedisJwt) RevokeAllSessions(ctx *gin.Context, userId int, keepSsid string) error {
+	ssids, err := r.cmd.SMembers(ctx, r.userKey(userId)).Result()
+	if err != nil {
+		return err
+	}
+	for _, ssid := range ssids {
+		if ssid == keepSsid {
+			continue
+		}
+		err = r.deleteSession(ctx, userId, ssid)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PurgeSessions 账号被彻底删除后清掉所有会话和会话索引 不在请求里调用
+func (r *RedisJwt) PurgeSessions(ctx context.Context, userId int) error {
+	ssids, err := r.cmd.SMembers(ctx, r.userKey(userId)).Result()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(ssids)+1)
+	keys = append(keys, r.userKey(userId))
+	for _, ssid := range ssids {
+		keys = append(keys, r.key(ssid))
+	}
+	return r.cmd.Del(ctx, keys...).Err()
+}
+
+// SetJWTToken 每次签发短token都重新加载角色 权限变更最晚一个短token有效期后生效
+func (r *RedisJwt) SetJWTToken(ctx *gin.Context, userId int, ssid string) error {
+	token, err := r.accessToken(ctx, userId, ssid)
+	if err != nil {
+		return err
+	}
+	r.transport.SetAccessToken(ctx, token, accessTokenExpiration)
+	return nil
+}
+
+// SetRefreshToken 签发新的长token 并记为会话当前的长token
 func (r *RedisJwt) SetRefreshToken(ctx *gin.Context, userId int, ssid string) error {
+	rtid := uuid.New().String()
+	token, err := r.refreshToken(userId, ssid, rtid)
+	if err != nil {
+		return err
+	}
+	err = r.rotate(ctx, userId, ssid, "", rtid)
+	if err != nil {
+		return err
+	}
+	r.transport.SetRefreshToken(ctx, token, refreshTokenExpiration)
+	return nil
+}
+
+func (r *RedisJwt) accessToken(ctx *gin.Context, userId int, ssid string) (string, error) {
+	device, err := r.binding.Fingerprint(ctx)
+	if err != nil {
+		return "", err
+	}
+	roles, permissions, err := r.perms.UserPermissions(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+	claims := UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenExpiration)),
+		},
+		Ssid:        ssid,
+		UserId:      userId,
+		Device:      device,
+		Roles:       roles,
+		Permissions: permissions,
+	}
+	return r.keys.sign(claims, typAccessToken)
+}
+
+func (r *RedisJwt) refreshToken(userId int, ssid string, rtid string) (string, error) {
 	claims := RefreshClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * 7)),
+			ID:        rtid,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(refreshTokenExpiration)),
 		},
 		Ssid: ssid,
 		Uid:  userId,
 	}
+	return r.keys.sign(claims, typRefreshToken)
+}
+
+func (r *RedisJwt) ParseToken(tokenStr string) (*UserClaims, error) {
+	claims := &UserClaims{}
+	err := r.keys.parse(tokenStr, claims, typAccessToken)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (r *RedisJwt) ParseRefreshToken(tokenStr string) (*RefreshClaims, error) {
+	claims := &RefreshClaims{}
+	err := r.keys.parse(tokenStr, claims, typRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// IssueMFAToken 密码验证通过但还需要二次验证 签发一个短时间有效的凭证
+func (r *RedisJwt) IssueMFAToken(ctx *gin.Context, userId int) (string, error) {
+	claims := MFAClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(mfaTokenExpiration)),
+		},
+		Uid: userId,
+	}
+	return r.keys.sign(claims, typMFAToken)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
-	jwtToken, err := token.SignedString([]byte(web.RefreshTokenKey))
+// ParseMFAToken 每解析一次算一次尝试 防止在有效期内暴力破解验证码
+func (r *RedisJwt) ParseMFAToken(ctx *gin.Context, tokenStr string) (*MFAClaims, error) {
+	claims := &MFAClaims{}
+	err := r.keys.parse(tokenStr, claims, typMFAToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ID == "" {
+		return nil, ErrTokenInvalid
+	}
+	key := fmt.Sprintf("users:mfa:%s", claims.ID)
+	cnt, err := r.cmd.Incr(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if cnt == 1 {
+		r.cmd.Expire(ctx, key, mfaTokenExpiration)
+	}
+	if cnt > mfaMaxAttempts {
+		return nil, ErrMFATooManyAttempts
+	}
+	return claims, nil
+}
+
+// createSession 写入会话 同时记到用户的会话索引里
+func (r *RedisJwt) createSession(ctx *gin.Context, sess Session) error {
+	now := time.Now().UnixMilli()
+	key := r.key(sess.Ssid)
+	userKey := r.userKey(sess.UserId)
+	_, err := r.cmd.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key,
+			"uid", sess.UserId,
+			"user_agent", sess.UserAgent,
+			"ip", sess.IP,
+			"method", sess.LoginMethod,
+			"ctime", now,
+			"utime", now,
+		)
+		pipe.Expire(ctx, key, refreshTokenExpiration)
+		pipe.SAdd(ctx, userKey, sess.Ssid)
+		//索引比最新的会话活得久就可以
+		pipe.Expire(ctx, userKey, refreshTokenExpiration)
+		return nil
+	})
+	return err
+}
+
+// rotate 把会话当前的长token id换成newId expectedId为空时不比对
+// 会话和用户的会话索引都续期到新的长token过期
+func (r *RedisJwt) rotate(ctx *gin.Context, userId int, ssid string, expectedId string, newId string) error {
+	val, err := r.cmd.Eval(ctx, luaRotateRefreshToken, []string{r.key(ssid), r.userKey(userId)},
+		expectedId, newId, time.Now().UnixMilli(), refreshTokenExpiration.Milliseconds()).Int()
 	if err != nil {
 		return err
 	}
-	ctx.Header("X-refresh-token", jwtToken)
-	return nil
+	switch val {
+	case 0:
+		return nil
+	case -1:
+		return ErrSessionExpired
+	case -2:
+		return ErrRefreshTokenReused
+	default:
+		return errors.New("系统错误")
+	}
+}
+
+func (r *RedisJwt) getSession(ctx *gin.Context, ssid string) (Session, error) {
+	vals, err := r.cmd.HGetAll(ctx, r.key(ssid)).Result()
+	if err != nil {
+		return Session{}, err
+	}
+	if len(vals) == 0 {
+		return Session{}, ErrSessionNotFind
+	}
+	uid, _ := strconv.Atoi(vals["uid"])
+	ctime, _ := strconv.ParseInt(vals["ctime"], 10, 64)
+	utime, _ := strconv.ParseInt(vals["utime"], 10, 64)
+	return Session{
+		Ssid:        ssid,
+		UserId:      uid,
+		UserAgent:   vals["user_agent"],
+		IP:          vals["ip"],
+		LoginMethod: vals["method"],
+		Ctime:       ctime,
+		Utime:       utime,
+	}, nil
+}
+
+func (r *RedisJwt) deleteSession(ctx *gin.Context, userId int, ssid string) error {
+	_, err := r.cmd.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, r.key(ssid))
+		pipe.SRem(ctx, r.userKey(userId), ssid)
+		return nil
+	})
+	return err
+}
+
+func (r *RedisJwt) key(ssid string) string {
+	return fmt.Sprintf("users:ssid:%s", ssid)
+}
+
+// userKey 用户的会话索引 存放该用户所有的ssid
+func (r *RedisJwt) userKey(userId int) string {
+	return fmt.Sprintf("users:sessions:%d", userId)
 }
 
 type UserClaims struct {
 	jwt.RegisteredClaims
 	//自定义存入token的字段
-	UserId    int
-	Ssid      string
-	UserAgent string
+	UserId int
+	Ssid   string
+	//设备指纹 由DeviceBinding决定内容
+	Device      string
+	Roles       []string
+	Permissions []string
+}
+
+// PermissionAll 拥有所有权限
+const PermissionAll = "*"
+
+func (c *UserClaims) HasPermission(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission || p == PermissionAll {
+			return true
+		}
+	}
+	return false
 }
 
 type RefreshClaims struct {
@@ -107,3 +434,9 @@ type RefreshClaims struct {
 	Ssid string
 	Uid  int
 }
+
+// MFAClaims 等待二次验证的登录
+type MFAClaims struct {
+	jwt.RegisteredClaims
+	Uid int
+}
@@ -0,0 +1,284 @@
+package ijwt
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"webook/internal/repository/redismocks"
+	svcmocks "webook/internal/service/mocks"
+)
+
+func TestRedisJwt_CheckSession(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+
+		ssid string
+
+		wantErr error
+	}{
+		{
+			name: "会话有效",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewIntCmd(context.Background())
+				res.SetVal(1)
+				cmd.EXPECT().Exists(gomock.Any(), "users:ssid:abc").Return(res)
+				return cmd
+			},
+			ssid: "abc",
+		},
+		{
+			name: "会话已失效",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewIntCmd(context.Background())
+				res.SetVal(0)
+				cmd.EXPECT().Exists(gomock.Any(), "users:ssid:abc").Return(res)
+				return cmd
+			},
+			ssid:    "abc",
+			wantErr: ErrSessionExpired,
+		},
+		{
+			name: "redis出错",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewIntCmd(context.Background())
+				res.SetErr(errors.New("redis出错"))
+				cmd.EXPECT().Exists(gomock.Any(), "users:ssid:abc").Return(res)
+				return cmd
+			},
+			ssid:    "abc",
+			wantErr: errors.New("redis出错"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			r := NewRedisJwt(tc.mock(ctrl), nil, NewHeaderTransport(), NewUserAgentBinding(), nil)
+			err := r.CheckSession(ctx, tc.ssid)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestRedisJwt_RotateRefreshToken(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+
+		rc *RefreshClaims
+		//加载权限出错 短token签不出来
+		permsErr error
+
+		wantErr error
+	}{
+		{
+			name: "签发失败 不轮换",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				//旧的长token还是当前的 客户端可以重试
+				return redismocks.NewMockCmdable(ctrl)
+			},
+			rc: &RefreshClaims{
+				RegisteredClaims: jwt.RegisteredClaims{ID: "old"},
+				Ssid:             "abc",
+				Uid:              1,
+			},
+			permsErr: errors.New("数据库出错"),
+			wantErr:  errors.New("数据库出错"),
+		},
+		{
+			name: "长token被重复使用",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(-2))
+				cmd.EXPECT().Eval(gomock.Any(), luaRotateRefreshToken, []string{"users:ssid:abc", "users:sessions:1"},
+					"old", gomock.Any(), gomock.Any(), refreshTokenExpiration.Milliseconds()).Return(res)
+				//整个会话下线
+				cmd.EXPECT().TxPipelined(gomock.Any(), gomock.Any()).Return(nil, nil)
+				return cmd
+			},
+			rc: &RefreshClaims{
+				RegisteredClaims: jwt.RegisteredClaims{ID: "old"},
+				Ssid:             "abc",
+				Uid:              1,
+			},
+			wantErr: ErrRefreshTokenReused,
+		},
+		{
+			name: "会话已失效",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(-1))
+				cmd.EXPECT().Eval(gomock.Any(), luaRotateRefreshToken, []string{"users:ssid:abc", "users:sessions:1"},
+					"old", gomock.Any(), gomock.Any(), refreshTokenExpiration.Milliseconds()).Return(res)
+				return cmd
+			},
+			rc: &RefreshClaims{
+				RegisteredClaims: jwt.RegisteredClaims{ID: "old"},
+				Ssid:             "abc",
+				Uid:              1,
+			},
+			wantErr: ErrSessionExpired,
+		},
+		{
+			name: "轮换成功",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().Eval(gomock.Any(), luaRotateRefreshToken, []string{"users:ssid:abc", "users:sessions:1"},
+					"old", gomock.Any(), gomock.Any(), refreshTokenExpiration.Milliseconds()).Return(res)
+				return cmd
+			},
+			rc: &RefreshClaims{
+				RegisteredClaims: jwt.RegisteredClaims{ID: "old"},
+				Ssid:             "abc",
+				Uid:              1,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/users/refresh_token", nil)
+			keys, err := NewEphemeralKeySet()
+			require.NoError(t, err)
+			roleSvc := svcmocks.NewMockRoleService(ctrl)
+			roleSvc.EXPECT().UserPermissions(gomock.Any(), tc.rc.Uid).Return(nil, nil, tc.permsErr).AnyTimes()
+			r := NewRedisJwt(tc.mock(ctrl), keys, NewHeaderTransport(), NewUserAgentBinding(), roleSvc)
+			err = r.RotateRefreshToken(ctx, tc.rc)
+			assert.Equal(t, tc.wantErr, err)
+			if err == nil {
+				assert.NotEmpty(t, recorder.Header().Get("X-jwt-token"))
+				assert.NotEmpty(t, recorder.Header().Get("X-refresh-token"))
+			} else {
+				//没有轮换成功 不能下发新token
+				assert.Empty(t, recorder.Header().Get("X-refresh-token"))
+			}
+		})
+	}
+}
+
+func TestRedisJwt_ListSessions(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+
+		wantSessions []Session
+		wantErr      error
+	}{
+		{
+			name: "过期的会话被清理掉",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				members := redis.NewStringSliceCmd(context.Background())
+				members.SetVal([]string{"abc", "old"})
+				cmd.EXPECT().SMembers(gomock.Any(), "users:sessions:1").Return(members)
+				abc := redis.NewMapStringStringCmd(context.Background())
+				abc.SetVal(map[string]string{"uid": "1", "method": "email", "ctime": "100", "utime": "200"})
+				cmd.EXPECT().HGetAll(gomock.Any(), "users:ssid:abc").Return(abc)
+				old := redis.NewMapStringStringCmd(context.Background())
+				old.SetVal(map[string]string{})
+				cmd.EXPECT().HGetAll(gomock.Any(), "users:ssid:old").Return(old)
+				cmd.EXPECT().SRem(gomock.Any(), "users:sessions:1", "old").Return(redis.NewIntCmd(context.Background()))
+				return cmd
+			},
+			wantSessions: []Session{{Ssid: "abc", UserId: 1, LoginMethod: "email", Ctime: 100, Utime: 200}},
+		},
+		{
+			name: "清理过期会话出错",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				members := redis.NewStringSliceCmd(context.Background())
+				members.SetVal([]string{"old"})
+				cmd.EXPECT().SMembers(gomock.Any(), "users:sessions:1").Return(members)
+				old := redis.NewMapStringStringCmd(context.Background())
+				old.SetVal(map[string]string{})
+				cmd.EXPECT().HGetAll(gomock.Any(), "users:ssid:old").Return(old)
+				res := redis.NewIntCmd(context.Background())
+				res.SetErr(errors.New("redis出错"))
+				cmd.EXPECT().SRem(gomock.Any(), "users:sessions:1", "old").Return(res)
+				return cmd
+			},
+			wantErr: errors.New("redis出错"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			r := NewRedisJwt(tc.mock(ctrl), nil, NewHeaderTransport(), NewUserAgentBinding(), nil)
+			sessions, err := r.ListSessions(ctx, 1)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantSessions, sessions)
+		})
+	}
+}
+
+func TestRedisJwt_PurgeSessions(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+
+		wantErr error
+	}{
+		{
+			name: "删除所有会话和索引",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				members := redis.NewStringSliceCmd(context.Background())
+				members.SetVal([]string{"abc", "def"})
+				cmd.EXPECT().SMembers(gomock.Any(), "users:sessions:1").Return(members)
+				cmd.EXPECT().Del(gomock.Any(), "users:sessions:1", "users:ssid:abc", "users:ssid:def").
+					Return(redis.NewIntCmd(context.Background()))
+				return cmd
+			},
+		},
+		{
+			name: "查询会话索引出错",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				members := redis.NewStringSliceCmd(context.Background())
+				members.SetErr(errors.New("redis出错"))
+				cmd.EXPECT().SMembers(gomock.Any(), "users:sessions:1").Return(members)
+				return cmd
+			},
+			wantErr: errors.New("redis出错"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			r := NewRedisJwt(tc.mock(ctrl), nil, NewHeaderTransport(), NewUserAgentBinding(), nil)
+			err := r.PurgeSessions(context.Background(), 1)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
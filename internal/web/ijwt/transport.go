@@ -0,0 +1,222 @@
+package ijwt
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Transport token在客户端和服务端之间的传递方式
+type Transport interface {
+	// ExtractAccessToken 取出短token 取不到返回空字符串
+	ExtractAccessToken(ctx *gin.Context) string
+	// ExtractRefreshToken 取出长token 取不到返回空字符串
+	ExtractRefreshToken(ctx *gin.Context) string
+	SetAccessToken(ctx *gin.Context, token string, expiration time.Duration)
+	SetRefreshToken(ctx *gin.Context, token string, expiration time.Duration)
+	// Clear 让客户端丢弃token
+	Clear(ctx *gin.Context)
+}
+
+// HeaderTransport 通过Authorization请求头接收 通过响应头下发 给移动端用
+type HeaderTransport struct {
+}
+
+func NewHeaderTransport() *HeaderTransport {
+	return &HeaderTransport{}
+}
+
+func (h *HeaderTransport) ExtractAccessToken(ctx *gin.Context) string {
+	return h.extract(ctx)
+}
+
+// ExtractRefreshToken 刷新接口的Authorization里放的是长token
+func (h *HeaderTransport) ExtractRefreshToken(ctx *gin.Context) string {
+	return h.extract(ctx)
+}
+
+func (h *HeaderTransport) SetAccessToken(ctx *gin.Context, token string, expiration time.Duration) {
+	ctx.Header("X-jwt-token", token)
+}
+
+func (h *HeaderTransport) SetRefreshToken(ctx *gin.Context, token string, expiration time.Duration) {
+	ctx.Header("X-refresh-token", token)
+}
+
+func (h *HeaderTransport) Clear(ctx *gin.Context) {
+	ctx.Header("X-jwt-token", "")
+	ctx.Header("X-refresh-token", "")
+}
+
+func (h *HeaderTransport) extract(ctx *gin.Context) string {
+	tokenHeader := ctx.GetHeader("Authorization")
+	if tokenHeader == "" {
+		return ""
+	}
+	tokens := strings.Split(tokenHeader, " ")
+	if len(tokens) != 2 {
+		return ""
+	}
+	return tokens[1]
+}
+
+const (
+	accessTokenCookie  = "webook_at"
+	refreshTokenCookie = "webook_rt"
+	csrfCookie         = "webook_csrf"
+	csrfHeader         = "X-CSRF-Token"
+	//长token只在刷新接口上发送
+	refreshTokenPath = "/users/refresh_token"
+)
+
+// CookieTransport HttpOnly+SameSite的cookie 给web前端用
+// 用double submit防CSRF:非只读请求必须在请求头里带上和cookie一致的csrf token
+type CookieTransport struct {
+	domain   string
+	secure   bool
+	sameSite http.SameSite
+}
+
+func NewCookieTransport(domain string, secure bool) *CookieTransport {
+	return &CookieTransport{
+		domain:   domain,
+		secure:   secure,
+		sameSite: http.SameSiteLaxMode,
+	}
+}
+
+func (c *CookieTransport) ExtractAccessToken(ctx *gin.Context) string {
+	return c.extract(ctx, accessTokenCookie)
+}
+
+func (c *CookieTransport) ExtractRefreshToken(ctx *gin.Context) string {
+	return c.extract(ctx, refreshTokenCookie)
+}
+
+// SetAccessToken 每次下发短token时一起更换csrf token
+func (c *CookieTransport) SetAccessToken(ctx *gin.Context, token string, expiration time.Duration) {
+	c.setCookie(ctx, accessTokenCookie, token, "/", expiration, true)
+	c.setCookie(ctx, csrfCookie, c.newCSRFToken(), "/", refreshTokenExpiration, false)
+}
+
+func (c *CookieTransport) SetRefreshToken(ctx *gin.Context, token string, expiration time.Duration) {
+	c.setCookie(ctx, refreshTokenCookie, token, refreshTokenPath, expiration, true)
+}
+
+func (c *CookieTransport) Clear(ctx *gin.Context) {
+	c.setCookie(ctx, accessTokenCookie, "", "/", -1, true)
+	c.setCookie(ctx, refreshTokenCookie, "", refreshTokenPath, -1, true)
+	c.setCookie(ctx, csrfCookie, "", "/", -1, false)
+}
+
+func (c *CookieTransport) extract(ctx *gin.Context, name string) string {
+	token, err := ctx.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	if !c.checkCSRF(ctx) {
+		return ""
+	}
+	return token
+}
+
+func (c *CookieTransport) checkCSRF(ctx *gin.Context) bool {
+	switch ctx.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	expected, err := ctx.Cookie(csrfCookie)
+	if err != nil || expected == "" {
+		return false
+	}
+	actual := ctx.GetHeader(csrfHeader)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) == 1
+}
+
+func (c *CookieTransport) setCookie(ctx *gin.Context, name, value, path string,
+	expiration time.Duration, httpOnly bool) {
+	maxAge := int(expiration.Seconds())
+	if expiration < 0 {
+		maxAge = -1
+	}
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.domain,
+		MaxAge:   maxAge,
+		Secure:   c.secure,
+		HttpOnly: httpOnly,
+		SameSite: c.sameSite,
+	})
+}
+
+func (c *CookieTransport) newCSRFToken() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// transportKey 记录这次请求的token是从哪种方式取出来的
+const transportKey = "ijwt_transport"
+
+// CompositeTransport 同时支持多种方式 按顺序取token 下发时每种方式都下发
+type CompositeTransport struct {
+	transports []Transport
+}
+
+func NewCompositeTransport(transports ...Transport) *CompositeTransport {
+	return &CompositeTransport{transports: transports}
+}
+
+func (c *CompositeTransport) ExtractAccessToken(ctx *gin.Context) string {
+	for _, t := range c.transports {
+		if token := t.ExtractAccessToken(ctx); token != "" {
+			ctx.Set(transportKey, t)
+			return token
+		}
+	}
+	return ""
+}
+
+func (c *CompositeTransport) ExtractRefreshToken(ctx *gin.Context) string {
+	for _, t := range c.transports {
+		if token := t.ExtractRefreshToken(ctx); token != "" {
+			ctx.Set(transportKey, t)
+			return token
+		}
+	}
+	return ""
+}
+
+// SetAccessToken 已知客户端用的哪种方式就只用那种 否则(登录时)都下发
+func (c *CompositeTransport) SetAccessToken(ctx *gin.Context, token string, expiration time.Duration) {
+	for _, t := range c.used(ctx) {
+		t.SetAccessToken(ctx, token, expiration)
+	}
+}
+
+func (c *CompositeTransport) SetRefreshToken(ctx *gin.Context, token string, expiration time.Duration) {
+	for _, t := range c.used(ctx) {
+		t.SetRefreshToken(ctx, token, expiration)
+	}
+}
+
+func (c *CompositeTransport) Clear(ctx *gin.Context) {
+	for _, t := range c.used(ctx) {
+		t.Clear(ctx)
+	}
+}
+
+func (c *CompositeTransport) used(ctx *gin.Context) []Transport {
+	if val, ok := ctx.Get(transportKey); ok {
+		if t, ok := val.(Transport); ok {
+			return []Transport{t}
+		}
+	}
+	return c.transports
+}
@@ -0,0 +1,81 @@
+package ijwt
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCookieTransport_ExtractAccessToken(t *testing.T) {
+	testCases := []struct {
+		name string
+		req  func() *http.Request
+
+		wantToken string
+	}{
+		{
+			name: "GET请求不校验csrf",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/users/profile", nil)
+				req.AddCookie(&http.Cookie{Name: accessTokenCookie, Value: "token"})
+				return req
+			},
+			wantToken: "token",
+		},
+		{
+			name: "POST请求csrf一致",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/users/logout", nil)
+				req.AddCookie(&http.Cookie{Name: accessTokenCookie, Value: "token"})
+				req.AddCookie(&http.Cookie{Name: csrfCookie, Value: "csrf"})
+				req.Header.Set(csrfHeader, "csrf")
+				return req
+			},
+			wantToken: "token",
+		},
+		{
+			name: "POST请求没有带csrf请求头",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/users/logout", nil)
+				req.AddCookie(&http.Cookie{Name: accessTokenCookie, Value: "token"})
+				req.AddCookie(&http.Cookie{Name: csrfCookie, Value: "csrf"})
+				return req
+			},
+		},
+		{
+			name: "POST请求csrf不一致",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/users/logout", nil)
+				req.AddCookie(&http.Cookie{Name: accessTokenCookie, Value: "token"})
+				req.AddCookie(&http.Cookie{Name: csrfCookie, Value: "csrf"})
+				req.Header.Set(csrfHeader, "other")
+				return req
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = tc.req()
+			token := NewCookieTransport("", false).ExtractAccessToken(ctx)
+			assert.Equal(t, tc.wantToken, token)
+		})
+	}
+}
+
+func TestCompositeTransport_Clear(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	req := httptest.NewRequest(http.MethodGet, "/users/profile", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	ctx.Request = req
+
+	transport := NewCompositeTransport(NewHeaderTransport(), NewCookieTransport("", false))
+	assert.Equal(t, "token", transport.ExtractAccessToken(ctx))
+	//token是从请求头取的 只清理请求头
+	transport.Clear(ctx)
+	assert.Empty(t, recorder.Header().Values("Set-Cookie"))
+}
@@ -1,12 +1,82 @@
 package ijwt
 
-import "github.com/gin-gonic/gin"
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+)
+
+// 登录方式
+const (
+	LoginMethodEmail  = "email"
+	LoginMethodSMS    = "sms"
+	LoginMethodWechat = "wechat"
+)
 
 type Handler interface {
+	// ExtractToken 取出短token
 	ExtractToken(ctx *gin.Context) string
-	SetLoginToken(ctx *gin.Context, userId int) error
+	// ExtractRefreshToken 取出长token
+	ExtractRefreshToken(ctx *gin.Context) string
+	// SetLoginToken method为登录方式
+	SetLoginToken(ctx *gin.Context, userId int, method string) error
 	ClearToken(ctx *gin.Context) error
+	// CheckSession 校验ssid对应的会话是否还有效
 	CheckSession(ctx *gin.Context, ssid string) error
+	// CheckDevice 校验请求是否来自签发短token时的设备
+	CheckDevice(ctx *gin.Context, claims *UserClaims) error
+	// RotateRefreshToken 轮换长token 同时下发新的短token
+	RotateRefreshToken(ctx *gin.Context, rc *RefreshClaims) error
+	// ListSessions 列出用户所有有效的会话
+	ListSessions(ctx *gin.Context, userId int) ([]Session, error)
+	// RevokeSession 下线用户的某个会话
+	RevokeSession(ctx *gin.Context, userId int, ssid string) error
+	// RevokeAllSessions 下线用户所有会话 keepSsid不为空时保留该会话
+	RevokeAllSessions(ctx *gin.Context, userId int, keepSsid string) error
 	SetJWTToken(ctx *gin.Context, userId int, ssid string) error
 	SetRefreshToken(ctx *gin.Context, userId int, ssid string) error
+	// ParseToken 解析短token
+	ParseToken(tokenStr string) (*UserClaims, error)
+	// ParseRefreshToken 解析长token
+	ParseRefreshToken(tokenStr string) (*RefreshClaims, error)
+	// IssueMFAToken 签发等待二次验证的凭证 换取真正的token前必须通过二次验证
+	IssueMFAToken(ctx *gin.Context, userId int) (string, error)
+	// ParseMFAToken 解析二次验证凭证 并计入尝试次数
+	ParseMFAToken(ctx *gin.Context, tokenStr string) (*MFAClaims, error)
+}
+
+// PermissionLoader 签发短token时加载用户的角色和权限 实现方自己做缓存
+type PermissionLoader interface {
+	UserPermissions(ctx context.Context, userId int) (roles []string, permissions []string, err error)
+}
+
+// Session 服务端记录的登录会话
+type Session struct {
+	Ssid      string
+	UserId    int
+	UserAgent string
+	IP        string
+	//登录方式 email sms wechat
+	LoginMethod string
+	//登录时间 ms
+	Ctime int64
+	//最后一次刷新时间 ms
+	Utime int64
+}
+
+// claimsKey 登录校验通过后claims在gin.Context里的key
+const claimsKey = "claims"
+
+// SetClaims 登录校验通过后放入claims
+func SetClaims(ctx *gin.Context, claims *UserClaims) {
+	ctx.Set(claimsKey, claims)
+}
+
+// GetClaims 取出当前登录用户的claims 没有登录时ok为false
+func GetClaims(ctx *gin.Context) (*UserClaims, bool) {
+	val, ok := ctx.Get(claimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := val.(*UserClaims)
+	return claims, ok && claims != nil
 }
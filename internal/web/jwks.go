@@ -0,0 +1,26 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"webook/internal/web/ijwt"
+	"webook/internal/web/middleware"
+)
+
+// JWKSHandler 公开验签公钥 其他服务据此校验webook签发的token
+type JWKSHandler struct {
+	keys *ijwt.KeySet
+}
+
+func NewJWKSHandler(keys *ijwt.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+func (h *JWKSHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET("/.well-known/jwks.json", middleware.Public(), h.JWKS)
+}
+
+func (h *JWKSHandler) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, h.keys.JWKS())
+}
@@ -16,8 +16,8 @@ func (c *CrossMiddlewareBuilder) Build() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowOrigins: []string{"https://localhost"},
 		//传递信息跨域
-		ExposeHeaders:    []string{"X-ijwt-token", "X-refresh-token"},
-		AllowHeaders:     []string{"Authorization", "Content-Type"},
+		ExposeHeaders:    []string{"X-jwt-token", "X-refresh-token"},
+		AllowHeaders:     []string{"Authorization", "Content-Type", "X-CSRF-Token", "X-Device-Id"},
 		AllowCredentials: true,
 	})
 }
@@ -1,68 +1,159 @@
 package middleware
 
 import (
-	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/redis/go-redis/v9"
+	"log"
 	"net/http"
-	"webook/internal/web"
+	"reflect"
+	"runtime"
+	"strings"
 	"webook/internal/web/ijwt"
 )
 
 // LoginJWTMiddlewareBuilder JWT 登录校验
 type LoginJWTMiddlewareBuilder struct {
-	paths   []string
-	cmd     redis.Cmdable
+	rules   []ignoreRule
 	handler ijwt.Handler
+	//可选登录 校验失败也不拦截
+	optional bool
 }
 
-func NewLoginJWTMiddlewareBuilder(cmd redis.Cmdable, handler ijwt.Handler) *LoginJWTMiddlewareBuilder {
-	return &LoginJWTMiddlewareBuilder{cmd: cmd, handler: handler}
+// ignoreRule 不需要登录的路由
+type ignoreRule struct {
+	//为空表示所有方法
+	method string
+	//gin的路由 例如 /articles/:id
+	path string
+	//path当作前缀匹配
+	prefix bool
 }
 
+func (r ignoreRule) match(ctx *gin.Context) bool {
+	if r.method != "" && r.method != ctx.Request.Method {
+		return false
+	}
+	if r.prefix {
+		return strings.HasPrefix(ctx.Request.URL.Path, r.path)
+	}
+	//FullPath是命中的路由 没有命中任何路由时为空
+	return ctx.FullPath() == r.path || ctx.Request.URL.Path == r.path
+}
+
+func NewLoginJWTMiddlewareBuilder(handler ijwt.Handler) *LoginJWTMiddlewareBuilder {
+	return &LoginJWTMiddlewareBuilder{handler: handler}
+}
+
+// Ignore path为gin的路由 所有方法都不校验
 func (l *LoginJWTMiddlewareBuilder) Ignore(path string) *LoginJWTMiddlewareBuilder {
-	l.paths = append(l.paths, path)
+	l.rules = append(l.rules, ignoreRule{path: path})
+	return l
+}
+
+// IgnoreMethod 只有method的请求不校验
+func (l *LoginJWTMiddlewareBuilder) IgnoreMethod(method string, path string) *LoginJWTMiddlewareBuilder {
+	l.rules = append(l.rules, ignoreRule{method: method, path: path})
+	return l
+}
+
+// IgnorePrefix 以prefix开头的路径都不校验
+func (l *LoginJWTMiddlewareBuilder) IgnorePrefix(prefix string) *LoginJWTMiddlewareBuilder {
+	l.rules = append(l.rules, ignoreRule{path: prefix, prefix: true})
+	return l
+}
+
+// Optional 可选登录 带了有效token就放入claims 否则当作匿名用户 从不拦截
+func (l *LoginJWTMiddlewareBuilder) Optional() *LoginJWTMiddlewareBuilder {
+	l.optional = true
 	return l
 }
 
 func (l *LoginJWTMiddlewareBuilder) Build() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		for _, path := range l.paths {
-			if ctx.Request.URL.Path == path {
-				return
-			}
+		if l.ignored(ctx) {
+			return
 		}
+		optional := l.optional || hasHandler(ctx, optionalAuthName)
 
-		//jwt验证
-		tokenStr := l.handler.ExtractToken(ctx)
-
-		claims := &ijwt.UserClaims{}
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(web.JwtTokenKey), nil
-		})
-		if err != nil {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
+		claims, ok := l.authenticate(ctx)
+		if !ok {
+			if !optional {
+				ctx.AbortWithStatus(http.StatusUnauthorized)
+			}
 			return
 		}
+		ijwt.SetClaims(ctx, claims)
+	}
+}
+
+func (l *LoginJWTMiddlewareBuilder) authenticate(ctx *gin.Context) (*ijwt.UserClaims, bool) {
+	//jwt验证
+	tokenStr := l.handler.ExtractToken(ctx)
+	if tokenStr == "" {
 		//没登录
-		if token == nil || !token.Valid {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
-		//验证Useragent
-		if claims.UserAgent != ctx.Request.UserAgent() {
-			//严重的安全问题
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
-		cnt, err := l.cmd.Exists(ctx, fmt.Sprintf("users:uuid:%s", claims.Ssid)).Result()
-		if err != nil || cnt > 0 {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
+		return nil, false
+	}
+	claims, err := l.handler.ParseToken(tokenStr)
+	if err != nil {
+		return nil, false
+	}
+	//验证设备
+	err = l.handler.CheckDevice(ctx, claims)
+	if err != nil {
+		//严重的安全问题 token可能被盗用了
+		log.Printf("[security] 设备不匹配,uid:%d,ssid:%s,ip:%s,ua:%s,path:%s",
+			claims.UserId, claims.Ssid, ctx.ClientIP(), ctx.Request.UserAgent(), ctx.Request.URL.Path)
+		return nil, false
+	}
+	//会话已退出或者过期
+	err = l.handler.CheckSession(ctx, claims.Ssid)
+	if err != nil {
+		return nil, false
+	}
+	return claims, true
+}
 
+func (l *LoginJWTMiddlewareBuilder) ignored(ctx *gin.Context) bool {
+	for _, rule := range l.rules {
+		if rule.match(ctx) {
+			return true
 		}
+	}
+	//路由注册时用Public声明了不需要登录
+	return hasHandler(ctx, publicName)
+}
 
-		ctx.Set("claims", claims)
+func hasHandler(ctx *gin.Context, handlerName string) bool {
+	for _, name := range ctx.HandlerNames() {
+		if name == handlerName {
+			return true
+		}
 	}
+	return false
+}
+
+// Public 在注册路由时声明该路由不需要登录
+//
+//	server.POST("/users/signup", middleware.Public(), u.SignUp)
+func Public() gin.HandlerFunc {
+	return public
+}
+
+// OptionalAuth 在注册路由时声明该路由可选登录 登录了就能拿到claims
+//
+//	server.GET("/articles/:id", middleware.OptionalAuth(), a.Detail)
+func OptionalAuth() gin.HandlerFunc {
+	return optionalAuth
+}
+
+func public(ctx *gin.Context) {}
+
+func optionalAuth(ctx *gin.Context) {}
+
+var (
+	publicName       = handlerName(public)
+	optionalAuthName = handlerName(optionalAuth)
+)
+
+func handlerName(fn gin.HandlerFunc) string {
+	return runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
 }
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"webook/internal/web/ijwt"
+	jwtmocks "webook/internal/web/ijwt/mocks"
+)
+
+func TestLoginJWTMiddlewareBuilder_Ignore(t *testing.T) {
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+
+		wantCode int
+	}{
+		{
+			name:     "路由模式",
+			method:   http.MethodGet,
+			path:     "/articles/123",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "指定方法",
+			method:   http.MethodGet,
+			path:     "/users/123",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "指定方法-其他方法要登录",
+			method:   http.MethodDelete,
+			path:     "/users/123",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "前缀",
+			method:   http.MethodGet,
+			path:     "/static/js/app.js",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "注册路由时声明",
+			method:   http.MethodPost,
+			path:     "/users/login_sms",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "需要登录",
+			method:   http.MethodGet,
+			path:     "/users/profile",
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			handler := jwtmocks.NewMockHandler(ctrl)
+			handler.EXPECT().ExtractToken(gomock.Any()).Return("").AnyTimes()
+			handler.EXPECT().ParseToken("").Return(nil, ijwt.ErrTokenInvalid).AnyTimes()
+
+			server := gin.New()
+			server.Use(NewLoginJWTMiddlewareBuilder(handler).
+				Ignore("/articles/:id").
+				IgnoreMethod(http.MethodGet, "/users/:id").
+				IgnorePrefix("/static/").
+				Build())
+			ok := func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			}
+			server.GET("/articles/:id", ok)
+			server.GET("/users/:id", ok)
+			server.DELETE("/users/:id", ok)
+			server.GET("/static/*file", ok)
+			server.POST("/users/login_sms", Public(), ok)
+			server.GET("/users/profile", ok)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantCode, resp.Code)
+		})
+	}
+}
+
+func TestLoginJWTMiddlewareBuilder_Optional(t *testing.T) {
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) ijwt.Handler
+		path  string
+		token string
+
+		wantCode int
+		wantBody string
+	}{
+		{
+			name: "可选登录-匿名",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				handler := jwtmocks.NewMockHandler(ctrl)
+				handler.EXPECT().ExtractToken(gomock.Any()).Return("")
+				return handler
+			},
+			path:     "/articles/1",
+			wantCode: http.StatusOK,
+			wantBody: "anonymous",
+		},
+		{
+			name: "可选登录-token无效也不拦截",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				handler := jwtmocks.NewMockHandler(ctrl)
+				handler.EXPECT().ExtractToken(gomock.Any()).Return("bad")
+				handler.EXPECT().ParseToken("bad").Return(nil, ijwt.ErrTokenInvalid)
+				return handler
+			},
+			path:     "/articles/1",
+			wantCode: http.StatusOK,
+			wantBody: "anonymous",
+		},
+		{
+			name: "可选登录-已登录",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				handler := jwtmocks.NewMockHandler(ctrl)
+				handler.EXPECT().ExtractToken(gomock.Any()).Return("good")
+				handler.EXPECT().ParseToken("good").Return(&ijwt.UserClaims{UserId: 1, Ssid: "abc"}, nil)
+				handler.EXPECT().CheckDevice(gomock.Any(), gomock.Any()).Return(nil)
+				handler.EXPECT().CheckSession(gomock.Any(), "abc").Return(nil)
+				return handler
+			},
+			path:     "/articles/1",
+			wantCode: http.StatusOK,
+			wantBody: "user",
+		},
+		{
+			name: "必须登录",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				handler := jwtmocks.NewMockHandler(ctrl)
+				handler.EXPECT().ExtractToken(gomock.Any()).Return("")
+				return handler
+			},
+			path:     "/users/profile",
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := gin.New()
+			server.Use(NewLoginJWTMiddlewareBuilder(tc.mock(ctrl)).Build())
+			hdl := func(ctx *gin.Context) {
+				if _, ok := ijwt.GetClaims(ctx); ok {
+					ctx.String(http.StatusOK, "user")
+					return
+				}
+				ctx.String(http.StatusOK, "anonymous")
+			}
+			server.GET("/articles/:id", OptionalAuth(), hdl)
+			server.GET("/users/profile", hdl)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantCode, resp.Code)
+			assert.Equal(t, tc.wantBody, resp.Body.String())
+		})
+	}
+}
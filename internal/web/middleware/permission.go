@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"webook/config"
+	"webook/internal/web/ijwt"
+)
+
+// PermissionMiddlewareBuilder 权限校验 必须放在登录校验之后
+type PermissionMiddlewareBuilder struct {
+	permissions []string
+}
+
+// NewPermissionMiddlewareBuilder 需要同时拥有所有permissions
+func NewPermissionMiddlewareBuilder(permissions ...string) *PermissionMiddlewareBuilder {
+	return &PermissionMiddlewareBuilder{permissions: permissions}
+}
+
+func (p *PermissionMiddlewareBuilder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, ok := ijwt.GetClaims(ctx)
+		if !ok {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		for _, permission := range p.permissions {
+			if !claims.HasPermission(permission) {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, config.Result{
+					Code: "4",
+					Msg:  "没有权限",
+					Data: permission,
+				})
+				return
+			}
+		}
+	}
+}
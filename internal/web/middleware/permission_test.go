@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"webook/internal/web/ijwt"
+)
+
+func TestPermissionMiddlewareBuilder_Build(t *testing.T) {
+	testCases := []struct {
+		name   string
+		claims *ijwt.UserClaims
+
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "拥有权限",
+			claims:   &ijwt.UserClaims{Permissions: []string{"user:manage"}},
+			wantCode: http.StatusOK,
+			wantBody: "ok",
+		},
+		{
+			name:     "超级权限",
+			claims:   &ijwt.UserClaims{Permissions: []string{ijwt.PermissionAll}},
+			wantCode: http.StatusOK,
+			wantBody: "ok",
+		},
+		{
+			name:     "没有权限",
+			claims:   &ijwt.UserClaims{Permissions: []string{"article:read"}},
+			wantCode: http.StatusForbidden,
+			wantBody: `{"code":"4","msg":"没有权限","data":"user:manage"}`,
+		},
+		{
+			name:     "没有登录",
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				if tc.claims != nil {
+					ctx.Set("claims", tc.claims)
+				}
+			})
+			g := server.Group("/admin", NewPermissionMiddlewareBuilder("user:manage").Build())
+			g.GET("/users", func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, "ok")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.wantCode, resp.Code)
+			assert.Equal(t, tc.wantBody, resp.Body.String())
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package web
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"webook/internal/service"
+	"webook/internal/web/ijwt"
+	"webook/internal/web/middleware"
+	"webook/internal/web/validator"
+)
+
+// PasswordResetHandler 忘记密码
+type PasswordResetHandler struct {
+	svc     service.PasswordResetService
+	handler ijwt.Handler
+	policy  *validator.Policy
+}
+
+func NewPasswordResetHandler(svc service.PasswordResetService, jwtHandler ijwt.Handler,
+	policy *validator.Policy) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		svc:     svc,
+		handler: jwtHandler,
+		policy:  policy,
+	}
+}
+
+func (h *PasswordResetHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/users/password_reset")
+	g.POST("/sms/code/send", middleware.Public(), h.SendSMSCode)
+	g.POST("/sms", middleware.Public(), h.ResetBySMS)
+	g.POST("/email/send", middleware.Public(), h.SendEmailLink)
+	g.POST("/email", middleware.Public(), h.ResetByEmail)
+}
+
+// SendSMSCode 发送找回密码的验证码
+func (h *PasswordResetHandler) SendSMSCode(ctx *gin.Context) {
+	type Req struct {
+		Phone string `json:"phone"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	err := h.svc.SendSMSCode(ctx, req.Phone)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "发送成功",
+		})
+	case errors.Is(err, service.ErrCodeSendTooMany):
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "发送太频繁,请稍后再试",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// ResetBySMS 验证码正确后设置新密码 所有设备都需要重新登录
+func (h *PasswordResetHandler) ResetBySMS(ctx *gin.Context) {
+	type Req struct {
+		Phone    string `json:"phone"`
+		Code     string `json:"code"`
+		Password string `json:"password"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if !h.checkPassword(ctx, req.Password) {
+		return
+	}
+	uid, err := h.svc.ResetBySMS(ctx, req.Phone, req.Code, req.Password)
+	switch {
+	case err == nil:
+		h.resetDone(ctx, uid)
+	case errors.Is(err, service.ErrResetCodeInvalid):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "验证码错误",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// SendEmailLink 发送重置密码的邮件
+func (h *PasswordResetHandler) SendEmailLink(ctx *gin.Context) {
+	type Req struct {
+		Email string `json:"email"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	err := h.svc.SendEmailLink(ctx, req.Email)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "发送成功",
+		})
+	case errors.Is(err, service.ErrResetSendTooMany):
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "发送太频繁,请稍后再试",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// ResetByEmail 重置页面带着链接里的token提交新密码
+func (h *PasswordResetHandler) ResetByEmail(ctx *gin.Context) {
+	type Req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if !h.checkPassword(ctx, req.Password) {
+		return
+	}
+	uid, err := h.svc.ResetByEmail(ctx, req.Token, req.Password)
+	switch {
+	case err == nil:
+		h.resetDone(ctx, uid)
+	case errors.Is(err, service.ErrResetTokenInvalid):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "重置链接无效或已过期",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// checkPassword 这时候还不知道是哪个用户 不检查是否包含邮箱
+func (h *PasswordResetHandler) checkPassword(ctx *gin.Context, password string) bool {
+	if vs := h.policy.Password(password, ""); len(vs) > 0 {
+		violated(ctx, vs)
+		return false
+	}
+	return true
+}
+
+// resetDone 密码已经改了 之前登录的会话全部下线
+func (h *PasswordResetHandler) resetDone(ctx *gin.Context, uid int) {
+	err := h.handler.RevokeAllSessions(ctx, uid, "")
+	if err != nil {
+		//密码已经改成功了 只记录下来
+		log.Printf("重置密码后下线会话失败,uid:%d,err:%v", uid, err)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "密码已重置,请重新登录",
+	})
+}
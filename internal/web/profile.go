@@ -0,0 +1,57 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	//按字符数算
+	nicknameMaxLen = 32
+	aboutMeMaxLen  = 256
+	avatarMaxLen   = 1024
+)
+
+// checkProfileText 返回空字符串表示通过 否则是给用户看的错误信息 allowNewline只有简介是true
+func checkProfileText(text string, maxLen int, field string, allowNewline bool) string {
+	if utf8.RuneCountInString(text) > maxLen {
+		return fmt.Sprintf("%s不能超过%d个字", field, maxLen)
+	}
+	for _, r := range text {
+		if unicode.IsControl(r) && !(allowNewline && r == '\n') {
+			return fmt.Sprintf("%s包含非法字符", field)
+		}
+	}
+	return ""
+}
+
+func parseBirthday(s string) (time.Time, string) {
+	if s == "" {
+		return time.Time{}, ""
+	}
+	birthday, err := time.ParseInLocation(time.DateOnly, s, time.UTC)
+	if err != nil {
+		return time.Time{}, "生日格式错误"
+	}
+	if birthday.Year() < 1900 || birthday.After(time.Now()) {
+		return time.Time{}, "生日不合法"
+	}
+	return birthday, ""
+}
+
+func checkAvatar(s string) string {
+	if s == "" {
+		return ""
+	}
+	if len(s) > avatarMaxLen {
+		return "头像地址太长"
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "头像地址不合法"
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+	"webook/internal/service/sms/auth"
+	"webook/internal/service/sms/templates"
+	"webook/internal/web/middleware"
+)
+
+// SMSGatewayHandler 内部服务通过webook的服务商发送短信
+// 业务方的token由有sms:manage权限的用户签发 调用时放在X-SMS-Token请求头里
+type SMSGatewayHandler struct {
+	svc *auth.SMSService
+}
+
+func NewSMSGatewayHandler(svc *auth.SMSService) *SMSGatewayHandler {
+	return &SMSGatewayHandler{svc: svc}
+}
+
+func (h *SMSGatewayHandler) RegisterRoutes(server *gin.Engine) {
+	//不走用户登录 用业务方token鉴权
+	server.POST("/internal/sms/send", middleware.Public(), h.Send)
+	manage := middleware.NewPermissionMiddlewareBuilder("sms:manage").Build()
+	server.POST("/admin/sms/tokens", manage, h.IssueToken)
+	server.POST("/admin/sms/tokens/revoke", manage, h.RevokeToken)
+}
+
+func (h *SMSGatewayHandler) Send(ctx *gin.Context) {
+	type Req struct {
+		Args    []string `json:"args"`
+		Numbers []string `json:"numbers"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if len(req.Numbers) == 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "手机号不能为空",
+		})
+		return
+	}
+	if len(req.Numbers) > auth.MaxNumbers {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  fmt.Sprintf("一次最多发送%d个手机号", auth.MaxNumbers),
+		})
+		return
+	}
+	err := h.svc.Send(ctx, ctx.GetHeader("X-SMS-Token"), req.Args, req.Numbers...)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "发送成功",
+		})
+	case errors.Is(err, auth.ErrTokenInvalid), errors.Is(err, auth.ErrTplDenied):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "token不合法",
+		})
+	case errors.Is(err, auth.ErrQuotaExceeded):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "超过了发送配额,请稍后再试",
+		})
+	case errors.Is(err, templates.ErrTemplateNotFind), errors.Is(err, templates.ErrArgsMismatch):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// IssueToken 给业务方签发token 一个token只能用一个模板
+func (h *SMSGatewayHandler) IssueToken(ctx *gin.Context) {
+	type Req struct {
+		Caller  string `json:"caller"`
+		Biz     string `json:"biz"`
+		TTLDays int    `json:"ttl_days"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if req.Caller == "" || req.Biz == "" {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "业务方和模板不能为空",
+		})
+		return
+	}
+	token, err := h.svc.IssueToken(req.Caller, req.Biz, time.Duration(req.TTLDays)*time.Hour*24)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Data: gin.H{"token": token},
+		})
+	case errors.Is(err, auth.ErrTTLInvalid), errors.Is(err, auth.ErrTplDenied),
+		errors.Is(err, templates.ErrTemplateNotFind):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// RevokeToken 业务方的token泄露时吊销
+func (h *SMSGatewayHandler) RevokeToken(ctx *gin.Context) {
+	type Req struct {
+		Token string `json:"token"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	err := h.svc.Revoke(ctx, req.Token)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "吊销成功",
+		})
+	case errors.Is(err, auth.ErrTokenInvalid):
+		//过期的token本来就不能用了
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "token不合法或者已经过期",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"webook/internal/repository/redismocks"
+	"webook/internal/service/sms"
+	"webook/internal/service/sms/auth"
+	smsmocks "webook/internal/service/sms/mocks"
+	"webook/internal/service/sms/templates"
+	"webook/internal/web/ijwt"
+	pkgmocks "webook/pkg/mocks"
+	"webook/pkg/ratelimit"
+)
+
+func newTestSMSGateway(svc sms.Service, cmd redis.Cmdable, counter ratelimit.Counter) *auth.SMSService {
+	tpls := templates.NewRegistry()
+	tpls.Register("order_notify", "tencent", templates.Template{Id: "1000003", Params: []string{"order"}})
+	tpls.Register("login", "tencent", templates.Template{Id: "1000001", Params: []string{"code"}})
+	return auth.NewSMSService(svc, []byte("a3f0a1c7e9b24d6f8e5c2b1a0d9f7e6c"), cmd, counter, tpls,
+		[]string{"login"}, time.Hour*24, nil, 5)
+}
+
+func TestSMSGatewayHandler_Send(t *testing.T) {
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter)
+		token bool
+		body  string
+
+		wantBody string
+	}{
+		{
+			name: "发送成功",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Exists(gomock.Any(), gomock.Any()).Return(redis.NewIntResult(0, nil))
+				counter := pkgmocks.NewMockCounter(ctrl)
+				counter.EXPECT().IncrBy(gomock.Any(), "sms:gateway:order", 1).Return(1, nil)
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "order_notify", []string{"1001"}, "15212345678").Return(nil)
+				return svc, cmd, counter
+			},
+			token:    true,
+			body:     `{"args":["1001"],"numbers":["15212345678"]}`,
+			wantBody: `{"code":"","msg":"发送成功","data":null}`,
+		},
+		{
+			name: "手机号为空",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				return nil, nil, nil
+			},
+			token:    true,
+			body:     `{"args":["1001"],"numbers":[]}`,
+			wantBody: `{"code":"4","msg":"手机号不能为空","data":null}`,
+		},
+		{
+			name: "一次发送的手机号太多",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				return nil, nil, nil
+			},
+			token: true,
+			body: `{"args":["1001"],"numbers":["1","2","3","4","5","6","7","8","9","10",` +
+				`"11","12","13","14","15","16","17","18","19","20","21","22","23","24","25",` +
+				`"26","27","28","29","30","31","32","33","34","35","36","37","38","39","40",` +
+				`"41","42","43","44","45","46","47","48","49","50","51"]}`,
+			wantBody: `{"code":"4","msg":"一次最多发送50个手机号","data":null}`,
+		},
+		{
+			name: "没有token",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				return nil, nil, nil
+			},
+			body:     `{"args":["1001"],"numbers":["15212345678"]}`,
+			wantBody: `{"code":"4","msg":"token不合法","data":null}`,
+		},
+		{
+			name: "token已经吊销",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Exists(gomock.Any(), gomock.Any()).Return(redis.NewIntResult(1, nil))
+				return nil, cmd, nil
+			},
+			token:    true,
+			body:     `{"args":["1001"],"numbers":["15212345678"]}`,
+			wantBody: `{"code":"4","msg":"token不合法","data":null}`,
+		},
+		{
+			name: "超过配额",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Exists(gomock.Any(), gomock.Any()).Return(redis.NewIntResult(0, nil))
+				counter := pkgmocks.NewMockCounter(ctrl)
+				counter.EXPECT().IncrBy(gomock.Any(), "sms:gateway:order", 1).Return(6, nil)
+				return nil, cmd, counter
+			},
+			token:    true,
+			body:     `{"args":["1001"],"numbers":["15212345678"]}`,
+			wantBody: `{"code":"4","msg":"超过了发送配额,请稍后再试","data":null}`,
+		},
+		{
+			name: "查询吊销名单出错",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable, ratelimit.Counter) {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Exists(gomock.Any(), gomock.Any()).Return(redis.NewIntResult(0, errors.New("redis出错")))
+				return nil, cmd, nil
+			},
+			token:    true,
+			body:     `{"args":["1001"],"numbers":["15212345678"]}`,
+			wantBody: `{"code":"5","msg":"系统错误","data":null}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := newTestSMSGateway(tc.mock(ctrl))
+			server := gin.Default()
+			NewSMSGatewayHandler(svc).RegisterRoutes(server)
+			req := httptest.NewRequest(http.MethodPost, "/internal/sms/send", bytes.NewBuffer([]byte(tc.body)))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.token {
+				token, err := svc.IssueToken("order", "order_notify", time.Hour)
+				require.NoError(t, err)
+				req.Header.Set("X-SMS-Token", token)
+			}
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.JSONEq(t, tc.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestSMSGatewayHandler_IssueToken(t *testing.T) {
+	testCases := []struct {
+		name   string
+		claims *ijwt.UserClaims
+		body   string
+
+		wantCode int
+		//为空时表示签发成功
+		wantBody string
+	}{
+		{
+			name:     "签发成功",
+			claims:   &ijwt.UserClaims{UserId: 1, Permissions: []string{"sms:manage"}},
+			body:     `{"caller":"order","biz":"order_notify","ttl_days":1}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "没有登录",
+			body:     `{"caller":"order","biz":"order_notify","ttl_days":1}`,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "没有sms:manage权限",
+			claims:   &ijwt.UserClaims{UserId: 1, Permissions: []string{"article:read"}},
+			body:     `{"caller":"order","biz":"order_notify","ttl_days":1}`,
+			wantCode: http.StatusForbidden,
+			wantBody: `{"code":"4","msg":"没有权限","data":"sms:manage"}`,
+		},
+		{
+			name:     "不能签发永久token",
+			claims:   &ijwt.UserClaims{UserId: 1, Permissions: []string{"sms:manage"}},
+			body:     `{"caller":"order","biz":"order_notify","ttl_days":0}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":"4","msg":"token有效期不合法,最长24h0m0s","data":null}`,
+		},
+		{
+			name:     "验证码模板不能签发",
+			claims:   &ijwt.UserClaims{UserId: 1, Permissions: []string{"sms:manage"}},
+			body:     `{"caller":"order","biz":"login","ttl_days":1}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":"4","msg":"这个模板不能给业务方使用","data":null}`,
+		},
+		{
+			name:     "模板没有注册",
+			claims:   &ijwt.UserClaims{UserId: 1, Permissions: []string{"sms:manage"}},
+			body:     `{"caller":"order","biz":"unknown","ttl_days":1}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":"4","msg":"短信模板不存在,biz:unknown","data":null}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := newTestSMSGateway(nil, nil, nil)
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				if tc.claims != nil {
+					ijwt.SetClaims(ctx, tc.claims)
+				}
+			})
+			NewSMSGatewayHandler(svc).RegisterRoutes(server)
+			req := httptest.NewRequest(http.MethodPost, "/admin/sms/tokens", bytes.NewBuffer([]byte(tc.body)))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.wantCode, resp.Code)
+			if tc.wantBody != "" {
+				assert.JSONEq(t, tc.wantBody, resp.Body.String())
+				return
+			}
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+			var res Result
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &res))
+			assert.Equal(t, "", res.Code)
+			assert.NotEmpty(t, res.Data.(map[string]any)["token"])
+		})
+	}
+}
+
+func TestSMSGatewayHandler_RevokeToken(t *testing.T) {
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) redis.Cmdable
+		token func(t *testing.T, svc *auth.SMSService) string
+
+		wantBody string
+	}{
+		{
+			name: "吊销成功",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Set(gomock.Any(), gomock.Any(), "order", gomock.Any()).
+					Return(redis.NewStatusResult("OK", nil))
+				return cmd
+			},
+			token: func(t *testing.T, svc *auth.SMSService) string {
+				token, err := svc.IssueToken("order", "order_notify", time.Hour)
+				require.NoError(t, err)
+				return token
+			},
+			wantBody: `{"code":"","msg":"吊销成功","data":null}`,
+		},
+		{
+			name: "token不合法",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return nil
+			},
+			token: func(t *testing.T, svc *auth.SMSService) string {
+				return "order_notify"
+			},
+			wantBody: `{"code":"4","msg":"token不合法或者已经过期","data":null}`,
+		},
+		{
+			name: "redis出错",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Set(gomock.Any(), gomock.Any(), "order", gomock.Any()).
+					Return(redis.NewStatusResult("", errors.New("redis出错")))
+				return cmd
+			},
+			token: func(t *testing.T, svc *auth.SMSService) string {
+				token, err := svc.IssueToken("order", "order_notify", time.Hour)
+				require.NoError(t, err)
+				return token
+			},
+			wantBody: `{"code":"5","msg":"系统错误","data":null}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := newTestSMSGateway(nil, tc.mock(ctrl), nil)
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ijwt.SetClaims(ctx, &ijwt.UserClaims{UserId: 1, Permissions: []string{"sms:manage"}})
+			})
+			NewSMSGatewayHandler(svc).RegisterRoutes(server)
+			body, err := json.Marshal(map[string]string{"token": tc.token(t, svc)})
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, "/admin/sms/tokens/revoke", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.JSONEq(t, tc.wantBody, resp.Body.String())
+		})
+	}
+}
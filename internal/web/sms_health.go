@@ -0,0 +1,43 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"webook/internal/service/sms/failover"
+	"webook/internal/web/middleware"
+)
+
+// SMSHealthHandler 查看短信服务商的熔断状态 只对有sms:manage权限的用户开放
+type SMSHealthHandler struct {
+	svc *failover.Service
+}
+
+func NewSMSHealthHandler(svc *failover.Service) *SMSHealthHandler {
+	return &SMSHealthHandler{svc: svc}
+}
+
+func (h *SMSHealthHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET("/admin/sms/health", middleware.NewPermissionMiddlewareBuilder("sms:manage").Build(), h.Health)
+}
+
+func (h *SMSHealthHandler) Health(ctx *gin.Context) {
+	hs := h.svc.Health()
+	vos := make([]SMSProviderHealthVo, 0, len(hs))
+	for _, ph := range hs {
+		vo := SMSProviderHealthVo{
+			Name:         ph.Name,
+			State:        string(ph.State),
+			Samples:      ph.Samples,
+			ErrRate:      ph.ErrRate,
+			SlowRate:     ph.SlowRate,
+			AvgLatencyMs: ph.AvgLatency.Milliseconds(),
+		}
+		if !ph.OpenUntil.IsZero() {
+			vo.OpenUntil = ph.OpenUntil.UnixMilli()
+		}
+		vos = append(vos, vo)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: vos,
+	})
+}
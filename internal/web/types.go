@@ -0,0 +1,65 @@
+package web
+
+import "webook/config"
+
+type Result = config.Result
+
+// SessionVo 我的设备
+type SessionVo struct {
+	Ssid        string `json:"ssid"`
+	UserAgent   string `json:"user_agent"`
+	IP          string `json:"ip"`
+	LoginMethod string `json:"login_method"`
+	//登录时间 ms
+	LoginTime int64 `json:"login_time"`
+	//最后一次刷新token的时间 ms
+	LastSeen int64 `json:"last_seen"`
+	//是否为当前设备
+	Current bool `json:"current"`
+}
+
+// ProfileVo 个人资料
+type ProfileVo struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Phone         string `json:"phone"`
+	Nickname      string `json:"nickname"`
+	//2006-01-02 没有填写时为空
+	Birthday string `json:"birthday"`
+	AboutMe  string `json:"about_me"`
+	Avatar   string `json:"avatar"`
+}
+
+// UserExportVo 导出的个人数据 密码只说明有没有设置
+type UserExportVo struct {
+	UserId int `json:"user_id"`
+	//注册时间 ms
+	SignUpTime    int64       `json:"sign_up_time"`
+	Email         string      `json:"email"`
+	EmailVerified bool        `json:"email_verified"`
+	Phone         string      `json:"phone"`
+	WechatOpenID  string      `json:"wechat_open_id"`
+	WechatUnionID string      `json:"wechat_union_id"`
+	HasPassword   bool        `json:"has_password"`
+	Nickname      string      `json:"nickname"`
+	Birthday      string      `json:"birthday"`
+	AboutMe       string      `json:"about_me"`
+	Avatar        string      `json:"avatar"`
+	Roles         []string    `json:"roles"`
+	MFAEnabled    bool        `json:"mfa_enabled"`
+	Sessions      []SessionVo `json:"sessions"`
+	//导出时间 ms
+	ExportTime int64 `json:"export_time"`
+}
+
+type SMSProviderHealthVo struct {
+	Name string `json:"name"`
+	//closed open half_open
+	State        string  `json:"state"`
+	Samples      int     `json:"samples"`
+	ErrRate      float64 `json:"err_rate"`
+	SlowRate     float64 `json:"slow_rate"`
+	AvgLatencyMs int64   `json:"avg_latency_ms"`
+	//熔断状态下开始探测的时间 ms 其他状态为0
+	OpenUntil int64 `json:"open_until"`
+}
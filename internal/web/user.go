@@ -2,73 +2,84 @@ package web
 
 import (
 	"errors"
-	"fmt"
-	"github.com/dlclark/regexp2"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/redis/go-redis/v9"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 	"webook/internal/domain"
 	"webook/internal/repository/dao"
 	"webook/internal/service"
 	"webook/internal/web/ijwt"
+	"webook/internal/web/middleware"
+	"webook/internal/web/validator"
 )
 
 const (
-	biz = "login"
+	biz          = "login"
+	bindPhoneBiz = "bind_phone"
 )
 
 type UserHandler struct {
-	svc         service.UserService
-	codeSvc     service.CodeService
-	emailExp    *regexp2.Regexp
-	passwordExp *regexp2.Regexp
-	cmd         redis.Cmdable
-	handler     ijwt.Handler
-}
-
-func NewUserHandler(userServer service.UserService, codeSvc service.CodeService, cmd redis.Cmdable, jwtHandler ijwt.Handler) *UserHandler {
-	const (
-		//email regex
-		emailRegexPattern = `^[A-Za-z0-9\u4e00-\u9fa5]+@[a-zA-Z0-9_-]+(\.[a-zA-Z0-9_-]+)+$`
-		//password regex
-		passwordRegexPattern = `^(?=.*[A-Za-z])(?=.*\d)(?=.*[$@$!%*#?&])[A-Za-z\d$@$!%*#?&]{8,}$`
-	)
-	emailExp := regexp2.MustCompile(emailRegexPattern, regexp2.None)
-	passwordExp := regexp2.MustCompile(passwordRegexPattern, regexp2.None)
+	svc       service.UserService
+	codeSvc   service.CodeService
+	handler   ijwt.Handler
+	mfaSvc    service.MFAService
+	verifySvc service.EmailVerifyService
+	guardSvc  service.LoginGuardService
+	//邮箱和密码的校验规则
+	policy  *validator.Policy
+	roleSvc service.RoleService
+}
+
+func NewUserHandler(userServer service.UserService, codeSvc service.CodeService, jwtHandler ijwt.Handler,
+	mfaSvc service.MFAService, verifySvc service.EmailVerifyService, guardSvc service.LoginGuardService,
+	policy *validator.Policy, roleSvc service.RoleService) *UserHandler {
 	return &UserHandler{
-		svc:         userServer,
-		emailExp:    emailExp,
-		passwordExp: passwordExp,
-		codeSvc:     codeSvc,
-		cmd:         cmd,
-		handler:     jwtHandler,
+		svc:       userServer,
+		codeSvc:   codeSvc,
+		handler:   jwtHandler,
+		mfaSvc:    mfaSvc,
+		verifySvc: verifySvc,
+		guardSvc:  guardSvc,
+		policy:    policy,
+		roleSvc:   roleSvc,
 	}
 }
 
 // RegisterRouter 注册路由
 func (u *UserHandler) RegisterRouter(server *gin.Engine) {
 	userRouter := server.Group("/users")
-	userRouter.POST("/signup", u.SignUp)
-	userRouter.POST("/login", u.LoginJWT)
-	userRouter.PUT("/edit", u.Edit)
+	userRouter.POST("/signup", middleware.Public(), u.SignUp)
+	userRouter.POST("/login", middleware.Public(), u.LoginJWT)
+	userRouter.POST("/password/change", u.ChangePassword)
 	userRouter.GET("/profile", u.ProfileJWT)
-	userRouter.POST("/login_sms/code/send", u.SendLoginSMSCode)
-	userRouter.POST("/login_sms", u.LoginSMS)
+	userRouter.PATCH("/profile", u.EditProfile)
+	userRouter.POST("/login_sms/code/send", middleware.Public(), u.SendLoginSMSCode)
+	userRouter.POST("/login_sms", middleware.Public(), u.LoginSMS)
 	userRouter.POST("/logout", u.Logout)
-	userRouter.POST("/refresh_token", u.RefreshToken)
+	userRouter.POST("/refresh_token", middleware.Public(), u.RefreshToken)
+	userRouter.GET("/sessions", u.ListSessions)
+	userRouter.DELETE("/sessions/:ssid", u.RevokeSession)
+	userRouter.DELETE("/sessions", u.RevokeOtherSessions)
+	userRouter.POST("/login/mfa", middleware.Public(), u.LoginMFA)
+	userRouter.POST("/login/unlock/code/send", middleware.Public(), u.SendUnlockCode)
+	userRouter.POST("/login/unlock", middleware.Public(), u.UnlockLogin)
+	userRouter.POST("/mfa/totp/setup", u.SetupTOTP)
+	userRouter.POST("/mfa/totp/enable", u.EnableTOTP)
+	userRouter.POST("/mfa/totp/disable", u.DisableTOTP)
+	userRouter.POST("/bind/phone/code/send", u.SendBindPhoneCode)
+	userRouter.POST("/bind/phone", u.BindPhone)
+	userRouter.POST("/bind/email/code/send", u.SendBindEmailCode)
+	userRouter.POST("/bind/email", u.BindEmail)
+	userRouter.DELETE("/bind/:identity", u.Unbind)
+	userRouter.POST("/deletion", u.RequestDeletion)
+	userRouter.GET("/export", u.ExportData)
 }
 
 func (u *UserHandler) Logout(ctx *gin.Context) {
-	ctx.Header("X-jwt-token", "")
-	ctx.Header("X-refresh-token", "")
-	claims, ok := ctx.MustGet("claims").(*ijwt.UserClaims)
-	if !ok {
-		ctx.String(http.StatusOK, "系统错误")
-	}
-	err := u.cmd.Set(ctx, fmt.Sprintf("users:uuid:%s", claims.Ssid), "", time.Hour*7*24).Err()
+	err := u.handler.ClearToken(ctx)
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Msg: "退出登录失败",
@@ -116,12 +127,105 @@ func (u *UserHandler) Profile(ctx *gin.Context) {
 	ctx.String(http.StatusOK, "你看到了。。。")
 }
 
-// ProfileJWT 测试权限信息
+// ProfileJWT 当前登录用户的个人资料
 func (u *UserHandler) ProfileJWT(ctx *gin.Context) {
-	//可以断言必然有claims
-	claims := ctx.MustGet("claims").(*ijwt.UserClaims)
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	user, err := u.svc.Profile(ctx, domain.User{Id: claims.UserId})
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	vo := ProfileVo{
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Phone:         user.Phone,
+		Nickname:      user.Nickname,
+		AboutMe:       user.AboutMe,
+		Avatar:        user.Avatar,
+	}
+	if !user.Birthday.IsZero() {
+		vo.Birthday = user.Birthday.Format(time.DateOnly)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: vo,
+	})
+}
+
+// EditProfile 修改个人资料 没有传的字段保持不变 传空字符串表示清空
+func (u *UserHandler) EditProfile(ctx *gin.Context) {
+	type Req struct {
+		Nickname *string `json:"nickname"`
+		//2006-01-02
+		Birthday *string `json:"birthday"`
+		AboutMe  *string `json:"about_me"`
+		Avatar   *string `json:"avatar"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	user, err := u.svc.Profile(ctx, domain.User{Id: claims.UserId})
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+
+	var msg string
+	if req.Nickname != nil {
+		user.Nickname = strings.TrimSpace(*req.Nickname)
+		msg = checkProfileText(user.Nickname, nicknameMaxLen, "昵称", false)
+	}
+	if msg == "" && req.Birthday != nil {
+		user.Birthday, msg = parseBirthday(*req.Birthday)
+	}
+	if msg == "" && req.AboutMe != nil {
+		user.AboutMe = strings.TrimSpace(*req.AboutMe)
+		msg = checkProfileText(user.AboutMe, aboutMeMaxLen, "个人简介", true)
+	}
+	if msg == "" && req.Avatar != nil {
+		user.Avatar = strings.TrimSpace(*req.Avatar)
+		msg = checkAvatar(user.Avatar)
+	}
+	if msg != "" {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  msg,
+		})
+		return
+	}
 
-	ctx.String(http.StatusOK, fmt.Sprintf("%d看到了。。。", claims.UserId))
+	err = u.svc.UpdateProfile(ctx, domain.User{
+		Id:       claims.UserId,
+		Nickname: user.Nickname,
+		Birthday: user.Birthday,
+		AboutMe:  user.AboutMe,
+		Avatar:   user.Avatar,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "修改成功",
+	})
 }
 
 // SignUp 注册
@@ -138,31 +242,18 @@ func (u *UserHandler) SignUp(ctx *gin.Context) {
 		return
 	}
 
-	//邮箱正则验证
-	ok, err := u.emailExp.MatchString(req.Email)
-	println(req.Email)
-	if err != nil {
-		ctx.String(http.StatusOK, "系统错误")
-		return
-	}
-	if !ok {
-		ctx.String(http.StatusOK, "邮箱有误")
-		return
-	}
-
-	//密码正则验证
-	ok, err = u.passwordExp.MatchString(req.Password)
-	if err != nil {
-		ctx.String(http.StatusOK, "系统错误")
+	//校验邮箱和密码 返回所有不满足的规则
+	if vs := u.policy.Email(req.Email); len(vs) > 0 {
+		violated(ctx, vs)
 		return
 	}
-	if !ok {
-		ctx.String(http.StatusOK, "密码格式错误！")
+	if vs := u.policy.Password(req.Password, req.Email); len(vs) > 0 {
+		violated(ctx, vs)
 		return
 	}
 
 	//DAO层数据处理
-	err = u.svc.SignUp(ctx.Request.Context(), domain.User{
+	err := u.svc.SignUp(ctx.Request.Context(), domain.User{
 		Email:    req.Email,
 		Password: req.Password,
 	})
@@ -174,6 +265,11 @@ func (u *UserHandler) SignUp(ctx *gin.Context) {
 		ctx.String(http.StatusOK, "系统错误")
 		return
 	}
+	//发送失败不影响注册 用户可以重新发送
+	err = u.verifySvc.SendVerifyEmail(ctx, req.Email)
+	if err != nil {
+		log.Printf("发送验证邮件失败,email:%s,err:%v", req.Email, err)
+	}
 	ctx.String(http.StatusOK, "注册成功")
 }
 
@@ -195,7 +291,7 @@ func (u *UserHandler) Login(ctx *gin.Context) {
 		Password: req.Password,
 	})
 
-	if errors.Is(err, service.ErrUserNotFind) {
+	if errors.Is(err, service.ErrInvalidUserOrPassword) {
 		ctx.String(http.StatusOK, "用户名或密码错误！")
 		return
 	}
@@ -257,7 +353,7 @@ func (u *UserHandler) LoginSMS(ctx *gin.Context) {
 	if err != nil {
 		return
 	}
-	err = u.handler.SetLoginToken(ctx, user.Id)
+	err = u.handler.SetLoginToken(ctx, user.Id, ijwt.LoginMethodSMS)
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Code: "5",
@@ -286,12 +382,20 @@ func (u *UserHandler) LoginJWT(ctx *gin.Context) {
 		return
 	}
 
+	//失败次数太多 不再校验密码
+	if !u.checkLoginGuard(ctx, req.Email) {
+		return
+	}
+
 	result, err := u.svc.Login(ctx.Request.Context(), domain.User{
 		Email:    req.Email,
 		Password: req.Password,
 	})
 
-	if errors.Is(err, service.ErrUserNotFind) {
+	if errors.Is(err, service.ErrInvalidUserOrPassword) {
+		if er := u.guardSvc.Fail(ctx, req.Email, ctx.ClientIP()); er != nil {
+			log.Printf("记录登录失败次数失败,email:%s,err:%v", req.Email, er)
+		}
 		ctx.String(http.StatusOK, "用户名或密码错误！")
 		return
 	}
@@ -299,8 +403,41 @@ func (u *UserHandler) LoginJWT(ctx *gin.Context) {
 		ctx.String(http.StatusOK, "系统错误")
 		return
 	}
+	//邮箱没验证的用户能不能登录由配置决定
+	err = u.verifySvc.CheckLogin(result)
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "7",
+			Msg:  "邮箱还没有验证",
+		})
+		return
+	}
+
+	//开启了二次验证 先不下发token 拿着mfa_token和验证码去/users/login/mfa
+	enabled, err := u.mfaSvc.Enabled(ctx, result.Id)
+	if err != nil {
+		ctx.String(http.StatusOK, "系统错误")
+		return
+	}
+	if enabled {
+		mfaToken, err := u.handler.IssueMFAToken(ctx, result.Id)
+		if err != nil {
+			ctx.String(http.StatusOK, "系统错误")
+			return
+		}
+		//二次验证通过之后才清空失败次数 否则每次输对密码都能多猜几次验证码
+		ctx.JSON(http.StatusOK, Result{
+			Code: "6",
+			Msg:  "需要二次验证",
+			Data: gin.H{"mfa_token": mfaToken},
+		})
+		return
+	}
+	if er := u.guardSvc.Succeed(ctx, req.Email); er != nil {
+		log.Printf("清空登录失败次数失败,email:%s,err:%v", req.Email, er)
+	}
 
-	err = u.handler.SetLoginToken(ctx, result.Id)
+	err = u.handler.SetLoginToken(ctx, result.Id, ijwt.LoginMethodEmail)
 	if err != nil {
 		ctx.String(http.StatusOK, "系统错误")
 		return
@@ -309,6 +446,30 @@ func (u *UserHandler) LoginJWT(ctx *gin.Context) {
 	return
 }
 
+// checkLoginGuard 账号被锁定或者失败太频繁时直接响应 返回false
+func (u *UserHandler) checkLoginGuard(ctx *gin.Context, account string) bool {
+	wait, err := u.guardSvc.Check(ctx, account, ctx.ClientIP())
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, service.ErrAccountLocked):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "8",
+			Msg:  "密码错误次数太多,账号已被临时锁定",
+			Data: gin.H{"retry_after": int(wait.Seconds())},
+		})
+	case errors.Is(err, service.ErrLoginTooFrequent):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "登录失败次数太多,请稍后再试",
+			Data: gin.H{"retry_after": int(wait.Seconds())},
+		})
+	default:
+		ctx.String(http.StatusOK, "系统错误")
+	}
+	return false
+}
+
 // LogOut 登出
 func (u *UserHandler) LogOut(ctx *gin.Context) {
 	//设置session
@@ -323,68 +484,78 @@ func (u *UserHandler) LogOut(ctx *gin.Context) {
 	ctx.String(http.StatusOK, "退出登录成功")
 }
 
-// Edit 更新信息(Session版)
-func (u *UserHandler) Edit(ctx *gin.Context) {
-	type UpdateReq struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+// ChangePassword 登录状态下用原密码修改密码 其他设备都需要重新登录
+func (u *UserHandler) ChangePassword(ctx *gin.Context) {
+	type Req struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
 	}
-	var req UpdateReq
-	err := ctx.Bind(&req)
-	if err != nil {
-		ctx.String(http.StatusOK, "系统错误")
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
 		return
 	}
-	sess := sessions.Default(ctx)
-	email := sess.Get("LoginSess")
-	if email.(string) != req.Email {
-		ctx.String(http.StatusOK, "系统错误")
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
 
-	//密码正则验证
-	ok, err := u.passwordExp.MatchString(req.Password)
+	//新密码不能包含邮箱 需要先查出来
+	user, err := u.svc.Profile(ctx, domain.User{Id: claims.UserId})
 	if err != nil {
-		ctx.String(http.StatusOK, "系统错误")
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
 		return
 	}
-	if !ok {
-		ctx.String(http.StatusOK, "密码格式错误！")
+	if vs := u.policy.Password(req.NewPassword, user.Email); len(vs) > 0 {
+		violated(ctx, vs)
 		return
 	}
 
-	err = u.svc.EditUserPassword(ctx.Request.Context(), domain.User{
-		Email:    req.Email,
-		Password: req.Password,
-	})
+	err = u.svc.ChangePassword(ctx, claims.UserId, req.OldPassword, req.NewPassword)
+	if errors.Is(err, service.ErrPasswordMismatch) {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "原密码不对",
+		})
+		return
+	}
 	if err != nil {
-		ctx.String(http.StatusOK, "系统错误")
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
 		return
 	}
-
-	ctx.String(http.StatusOK, "修改成功！")
-	return
+	//保留当前会话
+	err = u.handler.RevokeAllSessions(ctx, claims.UserId, claims.Ssid)
+	if err != nil {
+		log.Printf("修改密码后下线其他会话失败,uid:%d,err:%v", claims.UserId, err)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "修改成功",
+	})
 }
 
 // RefreshToken 拿长token
 func (u *UserHandler) RefreshToken(ctx *gin.Context) {
 	//只有这个接口 拿出来的才是长token 其他地方都是短token
-	refreshToken := u.handler.ExtractToken(ctx)
-	var rc ijwt.RefreshClaims
-	token, err := jwt.ParseWithClaims(refreshToken, &rc, func(token *jwt.Token) (interface{}, error) {
-		return []byte(RefreshTokenKey), nil
-	})
-	if err != nil || !token.Valid {
+	refreshToken := u.handler.ExtractRefreshToken(ctx)
+	rc, err := u.handler.ParseRefreshToken(refreshToken)
+	if err != nil {
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
-	err = u.handler.SetJWTToken(ctx, rc.Uid, rc.Ssid)
-	if err != nil {
+	//每次刷新都会轮换长token 旧的长token再出现会被认为是泄露
+	err = u.handler.RotateRefreshToken(ctx, rc)
+	if errors.Is(err, ijwt.ErrRefreshTokenReused) {
+		log.Printf("长token被重复使用,会话%s已下线,uid:%d", rc.Ssid, rc.Uid)
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
-	cnt, err := u.cmd.Exists(ctx, fmt.Sprintf("users:uuid:%s", rc.Ssid)).Result()
-	if err != nil || cnt > 0 {
+	if err != nil {
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
@@ -393,3 +564,610 @@ func (u *UserHandler) RefreshToken(ctx *gin.Context) {
 		Msg:  "刷新成功",
 	})
 }
+
+// ListSessions 我的设备 列出当前用户所有登录中的会话
+func (u *UserHandler) ListSessions(ctx *gin.Context) {
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	sessions, err := u.handler.ListSessions(ctx, claims.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	vos := make([]SessionVo, 0, len(sessions))
+	for _, sess := range sessions {
+		vos = append(vos, SessionVo{
+			Ssid:        sess.Ssid,
+			UserAgent:   sess.UserAgent,
+			IP:          sess.IP,
+			LoginMethod: sess.LoginMethod,
+			LoginTime:   sess.Ctime,
+			LastSeen:    sess.Utime,
+			Current:     sess.Ssid == claims.Ssid,
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: vos,
+	})
+}
+
+// RevokeSession 下线某个设备
+func (u *UserHandler) RevokeSession(ctx *gin.Context) {
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err := u.handler.RevokeSession(ctx, claims.UserId, ctx.Param("ssid"))
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "下线成功",
+		})
+	case errors.Is(err, ijwt.ErrSessionNotFind):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "会话不存在",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// RevokeOtherSessions 下线除当前设备以外的所有设备
+func (u *UserHandler) RevokeOtherSessions(ctx *gin.Context) {
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err := u.handler.RevokeAllSessions(ctx, claims.UserId, claims.Ssid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "下线成功",
+	})
+}
+
+// LoginMFA 登录的第二步 用二次验证码换取真正的token
+func (u *UserHandler) LoginMFA(ctx *gin.Context) {
+	type Req struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	mc, err := u.handler.ParseMFAToken(ctx, req.MFAToken)
+	if err != nil {
+		//过期 伪造或者试太多次了 都需要重新输入密码
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	//验证码错误和密码错误算在同一个账号上 锁定之后也不能继续二次验证
+	user, err := u.svc.Profile(ctx, domain.User{Id: mc.Uid})
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if !u.checkLoginGuard(ctx, user.Email) {
+		return
+	}
+	err = u.mfaSvc.Verify(ctx, mc.Uid, req.Code)
+	switch {
+	case err == nil:
+		if er := u.guardSvc.Succeed(ctx, user.Email); er != nil {
+			log.Printf("清空登录失败次数失败,email:%s,err:%v", user.Email, er)
+		}
+	case errors.Is(err, service.ErrMFACodeInvalid):
+		if er := u.guardSvc.Fail(ctx, user.Email, ctx.ClientIP()); er != nil {
+			log.Printf("记录登录失败次数失败,email:%s,err:%v", user.Email, er)
+		}
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "验证码错误",
+		})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	err = u.handler.SetLoginToken(ctx, mc.Uid, ijwt.LoginMethodEmail)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "登录成功",
+	})
+}
+
+// SendUnlockCode 账号被锁定之后 发送解锁验证码到绑定的手机号或者邮箱
+func (u *UserHandler) SendUnlockCode(ctx *gin.Context) {
+	type Req struct {
+		Email string `json:"email"`
+		//sms或者email
+		Channel string `json:"channel"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	err := u.guardSvc.SendUnlockCode(ctx, req.Email, service.UnlockChannel(req.Channel))
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "发送成功",
+		})
+	case errors.Is(err, service.ErrCodeSendTooMany):
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "发送太频繁,请稍后再试",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// UnlockLogin 用验证码解除锁定 之后可以重新用密码登录
+func (u *UserHandler) UnlockLogin(ctx *gin.Context) {
+	type Req struct {
+		Email   string `json:"email"`
+		Channel string `json:"channel"`
+		Code    string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	err := u.guardSvc.Unlock(ctx, req.Email, service.UnlockChannel(req.Channel), req.Code)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "解锁成功",
+		})
+	case errors.Is(err, service.ErrUnlockCodeInvalid):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "验证码错误",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// SetupTOTP 绑定验证器App 返回密钥和二维码内容 还需要EnableTOTP确认
+func (u *UserHandler) SetupTOTP(ctx *gin.Context) {
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	user, err := u.svc.Profile(ctx, domain.User{Id: claims.UserId})
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	//验证器App里显示的账号名
+	account := user.Email
+	if account == "" {
+		account = user.Phone
+	}
+	secret, uri, err := u.mfaSvc.SetupTOTP(ctx, claims.UserId, account)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Data: gin.H{
+				"secret": secret,
+				"uri":    uri,
+			},
+		})
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "已经开启了二次验证",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// EnableTOTP 输入验证器App上的验证码确认绑定 恢复码只返回这一次
+func (u *UserHandler) EnableTOTP(ctx *gin.Context) {
+	type Req struct {
+		Code string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	codes, err := u.mfaSvc.EnableTOTP(ctx, claims.UserId, req.Code)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg:  "开启成功",
+			Data: gin.H{"recovery_codes": codes},
+		})
+	case errors.Is(err, service.ErrMFACodeInvalid):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "验证码错误",
+		})
+	case errors.Is(err, service.ErrMFANotEnabled):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "请先绑定验证器",
+		})
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "已经开启了二次验证",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// DisableTOTP 关闭二次验证 需要验证码或者恢复码
+func (u *UserHandler) DisableTOTP(ctx *gin.Context) {
+	type Req struct {
+		Code string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err := u.mfaSvc.DisableTOTP(ctx, claims.UserId, req.Code)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "已关闭二次验证",
+		})
+	case errors.Is(err, service.ErrMFACodeInvalid):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "验证码错误",
+		})
+	case errors.Is(err, service.ErrMFANotEnabled):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "没有开启二次验证",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// SendBindPhoneCode 发送绑定手机号的验证码
+func (u *UserHandler) SendBindPhoneCode(ctx *gin.Context) {
+	type Req struct {
+		Phone string `json:"phone"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	err := u.codeSvc.Send(ctx, bindPhoneBiz, req.Phone)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "发送成功",
+		})
+	case errors.Is(err, service.ErrCodeSendTooMany):
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "发送太频繁,请稍后再试",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// BindPhone 绑定或者更换手机号
+func (u *UserHandler) BindPhone(ctx *gin.Context) {
+	type Req struct {
+		Phone string `json:"phone"`
+		Code  string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	ok, err := u.codeSvc.Verify(ctx, bindPhoneBiz, req.Phone, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "验证码错误",
+		})
+		return
+	}
+	err = u.svc.BindPhone(ctx, claims.UserId, req.Phone)
+	u.bindResult(ctx, err)
+}
+
+// SendBindEmailCode 发送绑定邮箱的验证码到新邮箱
+func (u *UserHandler) SendBindEmailCode(ctx *gin.Context) {
+	type Req struct {
+		Email string `json:"email"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if vs := u.policy.Email(req.Email); len(vs) > 0 {
+		violated(ctx, vs)
+		return
+	}
+	err := u.verifySvc.SendBindCode(ctx, req.Email)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "发送成功",
+		})
+	case errors.Is(err, service.ErrCodeSendTooMany):
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "发送太频繁,请稍后再试",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// BindEmail 绑定或者更换邮箱 和手机号一样需要验证码 否则可以抢先占用别人的邮箱
+func (u *UserHandler) BindEmail(ctx *gin.Context) {
+	type Req struct {
+		Email string `json:"email"`
+		Code  string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	ok, err := u.verifySvc.VerifyBindCode(ctx, req.Email, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "验证码错误",
+		})
+		return
+	}
+	err = u.svc.BindEmail(ctx, claims.UserId, req.Email)
+	u.bindResult(ctx, err)
+}
+
+// Unbind 解绑手机号 邮箱或者微信
+func (u *UserHandler) Unbind(ctx *gin.Context) {
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	identity := service.Identity(ctx.Param("identity"))
+	switch identity {
+	case service.IdentityPhone, service.IdentityEmail, service.IdentityWechat:
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  "不支持的登录方式",
+		})
+		return
+	}
+	err := u.svc.Unbind(ctx, claims.UserId, identity)
+	u.bindResult(ctx, err)
+}
+
+// RequestDeletion 申请注销 所有设备下线 冷静期内重新登录可以撤销
+func (u *UserHandler) RequestDeletion(ctx *gin.Context) {
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	deleteAt, err := u.svc.RequestDeletion(ctx, claims.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	err = u.handler.RevokeAllSessions(ctx, claims.UserId, "")
+	if err != nil {
+		//注销已经申请成功了 会话最多在过期之后失效
+		log.Printf("注销时下线会话失败,uid:%d,err:%v", claims.UserId, err)
+	}
+	_ = u.handler.ClearToken(ctx)
+	ctx.JSON(http.StatusOK, Result{
+		Msg:  "已申请注销,冷静期内重新登录即可撤销",
+		Data: gin.H{"delete_at": deleteAt.UnixMilli()},
+	})
+}
+
+// ExportData 导出webook保存的当前用户的所有数据
+func (u *UserHandler) ExportData(ctx *gin.Context) {
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	user, err := u.svc.Profile(ctx, domain.User{Id: claims.UserId})
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	mfaEnabled, err := u.mfaSvc.Enabled(ctx, claims.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	sessions, err := u.handler.ListSessions(ctx, claims.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	//token里的角色可能已经过时了
+	roles, _, err := u.roleSvc.UserPermissions(ctx, claims.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+		return
+	}
+	vo := UserExportVo{
+		UserId:        user.Id,
+		SignUpTime:    user.Ctime.UnixMilli(),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Phone:         user.Phone,
+		WechatOpenID:  user.WechatInfo.OpenID,
+		WechatUnionID: user.WechatInfo.UnionID,
+		HasPassword:   user.Password != "",
+		Nickname:      user.Nickname,
+		AboutMe:       user.AboutMe,
+		Avatar:        user.Avatar,
+		Roles:         roles,
+		MFAEnabled:    mfaEnabled,
+		Sessions:      make([]SessionVo, 0, len(sessions)),
+		ExportTime:    time.Now().UnixMilli(),
+	}
+	if !user.Birthday.IsZero() {
+		vo.Birthday = user.Birthday.Format(time.DateOnly)
+	}
+	for _, sess := range sessions {
+		vo.Sessions = append(vo.Sessions, SessionVo{
+			Ssid:        sess.Ssid,
+			UserAgent:   sess.UserAgent,
+			IP:          sess.IP,
+			LoginMethod: sess.LoginMethod,
+			LoginTime:   sess.Ctime,
+			LastSeen:    sess.Utime,
+			Current:     sess.Ssid == claims.Ssid,
+		})
+	}
+	//作为附件下载
+	ctx.Header("Content-Disposition", "attachment; filename=webook-export.json")
+	ctx.JSON(http.StatusOK, Result{
+		Data: vo,
+	})
+}
+
+// violated 校验不通过 Msg为第一条 Data里是所有不满足的规则
+func violated(ctx *gin.Context, vs []validator.Violation) {
+	ctx.JSON(http.StatusOK, Result{
+		Code: "4",
+		Msg:  vs[0].Msg,
+		Data: gin.H{"violations": vs},
+	})
+}
+
+func (u *UserHandler) bindResult(ctx *gin.Context, err error) {
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "操作成功",
+		})
+	case errors.Is(err, service.ErrPhoneConflict),
+		errors.Is(err, service.ErrEmailConflict),
+		errors.Is(err, service.ErrWechatConflict),
+		errors.Is(err, service.ErrLastLoginMethod):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
@@ -3,25 +3,29 @@ package web
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 	"webook/internal/domain"
 	"webook/internal/repository/dao"
 	"webook/internal/service"
 	svcmocks "webook/internal/service/mocks"
+	"webook/internal/web/ijwt"
+	jwtmocks "webook/internal/web/ijwt/mocks"
+	"webook/internal/web/validator"
 )
 
 func TestUserHandler_SignUp(t *testing.T) {
 	testCases := []struct {
 		name    string
-		mock    func(ctrl *gomock.Controller) service.UserService
+		mock    func(ctrl *gomock.Controller) (service.UserService, service.EmailVerifyService)
 		reqBody string
 
 		wantCode int
@@ -30,13 +34,15 @@ func TestUserHandler_SignUp(t *testing.T) {
 		//正常流畅
 		{
 			name: "SignUp-success",
-			mock: func(ctrl *gomock.Controller) service.UserService {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailVerifyService) {
 				userService := svcmocks.NewMockUserService(ctrl)
 				userService.EXPECT().SignUp(gomock.Any(), domain.User{
 					Email:    "25946185542@qq.com",
 					Password: "1234qwe56asd@",
 				})
-				return userService
+				verifySvc := svcmocks.NewMockEmailVerifyService(ctrl)
+				verifySvc.EXPECT().SendVerifyEmail(gomock.Any(), "25946185542@qq.com").Return(nil)
+				return userService, verifySvc
 			},
 			reqBody: `
 {
@@ -50,9 +56,9 @@ func TestUserHandler_SignUp(t *testing.T) {
 		//错误参数
 		{
 			name: "SignUp-varError",
-			mock: func(ctrl *gomock.Controller) service.UserService {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailVerifyService) {
 				userService := svcmocks.NewMockUserService(ctrl)
-				return userService
+				return userService, nil
 			},
 			reqBody: `
 {
@@ -64,9 +70,9 @@ func TestUserHandler_SignUp(t *testing.T) {
 		//邮箱有误
 		{
 			name: "SignUp-emailError",
-			mock: func(ctrl *gomock.Controller) service.UserService {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailVerifyService) {
 				userService := svcmocks.NewMockUserService(ctrl)
-				return userService
+				return userService, nil
 			},
 			reqBody: `
 {
@@ -75,14 +81,14 @@ func TestUserHandler_SignUp(t *testing.T) {
 }
 `,
 			wantCode: http.StatusOK,
-			wantBody: "邮箱有误",
+			wantBody: `{"code":"4","msg":"邮箱格式错误","data":{"violations":[{"rule":"email_syntax","msg":"邮箱格式错误"}]}}`,
 		},
 		//密码有误
 		{
 			name: "Signup-passwordError",
-			mock: func(ctrl *gomock.Controller) service.UserService {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailVerifyService) {
 				userService := svcmocks.NewMockUserService(ctrl)
-				return userService
+				return userService, nil
 			},
 			reqBody: `
 {
@@ -91,18 +97,18 @@ func TestUserHandler_SignUp(t *testing.T) {
 }
 `,
 			wantCode: http.StatusOK,
-			wantBody: "密码格式错误！",
+			wantBody: `{"code":"4","msg":"密码至少8位","data":{"violations":[{"rule":"min_length","msg":"密码至少8位"},{"rule":"symbol","msg":"密码需要包含特殊符号"}]}}`,
 		},
 		//邮箱冲突
 		{
 			name: "Sign-emailDupError",
-			mock: func(ctrl *gomock.Controller) service.UserService {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailVerifyService) {
 				userService := svcmocks.NewMockUserService(ctrl)
 				userService.EXPECT().SignUp(gomock.Any(), domain.User{
 					Email:    "2594618554@qq.com",
 					Password: "1234qwe56asd@",
 				}).Return(dao.ErrUserDuplicateEmail)
-				return userService
+				return userService, nil
 			},
 			reqBody: `
 {
@@ -121,7 +127,8 @@ func TestUserHandler_SignUp(t *testing.T) {
 			defer ctrl.Finish()
 
 			server := gin.Default()
-			userHandler := NewUserHandler(tc.mock(ctrl), nil)
+			userSvc, verifySvc := tc.mock(ctrl)
+			userHandler := NewUserHandler(userSvc, nil, nil, nil, verifySvc, nil, validator.NewDefaultPolicy(), nil)
 			userHandler.RegisterRouter(server)
 
 			req := httptest.NewRequest(http.MethodPost, "/users/signup", bytes.NewBuffer([]byte(tc.reqBody)))
@@ -141,7 +148,7 @@ func TestUserHandler_LoginSMS(t *testing.T) {
 	testCases := []struct {
 		name string
 
-		mock func(ctrl *gomock.Controller) (service.UserService, service.CodeService)
+		mock func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler)
 
 		reqBody string
 
@@ -150,7 +157,7 @@ func TestUserHandler_LoginSMS(t *testing.T) {
 		//成功流程
 		{
 			name: "LoginSMS-success",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
 				codeSvc := svcmocks.NewMockCodeService(ctrl)
 				userSvc := svcmocks.NewMockUserService(ctrl)
 				codeSvc.EXPECT().Verify(gomock.Any(), "login", "8188181", "866108").
@@ -162,7 +169,9 @@ func TestUserHandler_LoginSMS(t *testing.T) {
 						Phone:    "8188181",
 						Password: "",
 					}, nil)
-				return userSvc, codeSvc
+				jwtHandler := jwtmocks.NewMockHandler(ctrl)
+				jwtHandler.EXPECT().SetLoginToken(gomock.Any(), 1, ijwt.LoginMethodSMS).Return(nil)
+				return userSvc, codeSvc, jwtHandler
 			},
 			reqBody: `{
 "phone":"8188181",
@@ -176,10 +185,10 @@ func TestUserHandler_LoginSMS(t *testing.T) {
 		//绑定不成功 系统错误
 		{
 			name: "LoginSMS-sysError",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
 				codeSvc := svcmocks.NewMockCodeService(ctrl)
 				userSvc := svcmocks.NewMockUserService(ctrl)
-				return userSvc, codeSvc
+				return userSvc, codeSvc, jwtmocks.NewMockHandler(ctrl)
 			},
 			reqBody: `{
 phone:"8188181,
@@ -193,12 +202,12 @@ phone:"8188181,
 		//验证不成功 系统错误
 		{
 			name: "LoginSMS-verError",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
 				codeSvc := svcmocks.NewMockCodeService(ctrl)
 				userSvc := svcmocks.NewMockUserService(ctrl)
 				codeSvc.EXPECT().Verify(gomock.Any(), "login", "8188181", "866108").
 					Return(true, service.ErrCodeSendTooMany)
-				return userSvc, codeSvc
+				return userSvc, codeSvc, jwtmocks.NewMockHandler(ctrl)
 			},
 			reqBody: `{
 "phone":"8188181",
@@ -213,12 +222,12 @@ phone:"8188181,
 		//验证不成功 验证失败
 		{
 			name: "LoginSMS-success",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
 				codeSvc := svcmocks.NewMockCodeService(ctrl)
 				userSvc := svcmocks.NewMockUserService(ctrl)
 				codeSvc.EXPECT().Verify(gomock.Any(), "login", "8188181", "866108").
 					Return(false, nil)
-				return userSvc, codeSvc
+				return userSvc, codeSvc, jwtmocks.NewMockHandler(ctrl)
 			},
 			reqBody: `{
 "phone":"8188181",
@@ -235,7 +244,8 @@ phone:"8188181,
 			ctrl := gomock.NewController(t)
 			ctrl.Finish()
 
-			userHandler := NewUserHandler(tc.mock(ctrl))
+			userSvc, codeSvc, jwtHdl := tc.mock(ctrl)
+			userHandler := NewUserHandler(userSvc, codeSvc, jwtHdl, nil, nil, nil, nil, nil)
 			server := gin.Default()
 			userHandler.RegisterRouter(server)
 			req := httptest.NewRequest(http.MethodPost, "/users/login_sms",
@@ -254,17 +264,809 @@ phone:"8188181,
 }
 
 func TestSetJwtToken(t *testing.T) {
-	claims := UserClaims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			//不好测
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	roleSvc := svcmocks.NewMockRoleService(ctrl)
+	roleSvc.EXPECT().UserPermissions(gomock.Any(), 1).Return([]string{"admin"}, []string{"user:manage"}, nil)
+
+	keys, err := ijwt.NewEphemeralKeySet()
+	require.NoError(t, err)
+	hdl := ijwt.NewRedisJwt(nil, keys, ijwt.NewHeaderTransport(), ijwt.NewUserAgentBinding(), roleSvc)
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/users/profile", nil)
+	err = hdl.SetJWTToken(ctx, 1, "ssid")
+	require.NoError(t, err)
+
+	jwtToken := recorder.Header().Get("X-jwt-token")
+	println(jwtToken)
+	claims, err := hdl.ParseToken(jwtToken)
+	require.NoError(t, err)
+	assert.Equal(t, 1, claims.UserId)
+	assert.True(t, claims.HasPermission("user:manage"))
+}
+
+func TestUserHandler_EditProfile(t *testing.T) {
+	birthday := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) service.UserService
+		reqBody string
+
+		wantResult Result
+	}{
+		{
+			name: "只改昵称 其他字段不变",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userService := svcmocks.NewMockUserService(ctrl)
+				userService.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).Return(domain.User{
+					Id:       1,
+					Nickname: "旧昵称",
+					Birthday: birthday,
+					AboutMe:  "你好",
+				}, nil)
+				userService.EXPECT().UpdateProfile(gomock.Any(), domain.User{
+					Id:       1,
+					Nickname: "新昵称",
+					Birthday: birthday,
+					AboutMe:  "你好",
+				}).Return(nil)
+				return userService
+			},
+			reqBody:    `{"nickname":" 新昵称 "}`,
+			wantResult: Result{Msg: "修改成功"},
+		},
+		{
+			name: "清空生日",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userService := svcmocks.NewMockUserService(ctrl)
+				userService.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Birthday: birthday}, nil)
+				userService.EXPECT().UpdateProfile(gomock.Any(), domain.User{Id: 1}).Return(nil)
+				return userService
+			},
+			reqBody:    `{"birthday":""}`,
+			wantResult: Result{Msg: "修改成功"},
+		},
+		{
+			name: "昵称太长",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userService := svcmocks.NewMockUserService(ctrl)
+				userService.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).Return(domain.User{Id: 1}, nil)
+				return userService
+			},
+			reqBody:    `{"nickname":"` + strings.Repeat("长", 33) + `"}`,
+			wantResult: Result{Code: "4", Msg: "昵称不能超过32个字"},
+		},
+		{
+			name: "昵称不能换行",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userService := svcmocks.NewMockUserService(ctrl)
+				userService.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).Return(domain.User{Id: 1}, nil)
+				return userService
+			},
+			reqBody:    `{"nickname":"新\n昵称"}`,
+			wantResult: Result{Code: "4", Msg: "昵称包含非法字符"},
+		},
+		{
+			name: "简介可以换行",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userService := svcmocks.NewMockUserService(ctrl)
+				userService.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).Return(domain.User{Id: 1}, nil)
+				userService.EXPECT().UpdateProfile(gomock.Any(), domain.User{Id: 1, AboutMe: "第一行\n第二行"}).
+					Return(nil)
+				return userService
+			},
+			reqBody:    `{"about_me":"第一行\n第二行"}`,
+			wantResult: Result{Msg: "修改成功"},
+		},
+		{
+			name: "生日格式错误",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userService := svcmocks.NewMockUserService(ctrl)
+				userService.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).Return(domain.User{Id: 1}, nil)
+				return userService
+			},
+			reqBody:    `{"birthday":"2000/01/02"}`,
+			wantResult: Result{Code: "4", Msg: "生日格式错误"},
+		},
+		{
+			name: "头像不是http地址",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userService := svcmocks.NewMockUserService(ctrl)
+				userService.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).Return(domain.User{Id: 1}, nil)
+				return userService
+			},
+			reqBody:    `{"avatar":"javascript:alert(1)"}`,
+			wantResult: Result{Code: "4", Msg: "头像地址不合法"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ijwt.SetClaims(ctx, &ijwt.UserClaims{UserId: 1})
+			})
+			userHandler := NewUserHandler(tc.mock(ctrl), nil, nil, nil, nil, nil, nil, nil)
+			userHandler.RegisterRouter(server)
+
+			req := httptest.NewRequest(http.MethodPatch, "/users/profile", bytes.NewBuffer([]byte(tc.reqBody)))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+			var result Result
+			err := json.Unmarshal(resp.Body.Bytes(), &result)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantResult, result)
+		})
+	}
+}
+
+func TestUserHandler_Sessions(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mock   func(ctrl *gomock.Controller) ijwt.Handler
+		method string
+		path   string
+
+		wantResult Result
+	}{
+		{
+			name: "列出会话 标记当前设备",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ListSessions(gomock.Any(), 1).Return([]ijwt.Session{
+					{Ssid: "cur", UserAgent: "chrome", IP: "127.0.0.1", LoginMethod: "email", Ctime: 100, Utime: 200},
+					{Ssid: "other", UserAgent: "iphone", LoginMethod: "sms", Ctime: 300, Utime: 400},
+				}, nil)
+				return hdl
+			},
+			method: http.MethodGet,
+			path:   "/users/sessions",
+			wantResult: Result{Data: []SessionVo{
+				{Ssid: "cur", UserAgent: "chrome", IP: "127.0.0.1", LoginMethod: "email", LoginTime: 100, LastSeen: 200, Current: true},
+				{Ssid: "other", UserAgent: "iphone", LoginMethod: "sms", LoginTime: 300, LastSeen: 400},
+			}},
+		},
+		{
+			name: "列出会话出错",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ListSessions(gomock.Any(), 1).Return(nil, errors.New("redis出错"))
+				return hdl
+			},
+			method:     http.MethodGet,
+			path:       "/users/sessions",
+			wantResult: Result{Code: "5", Msg: "系统错误"},
+		},
+		{
+			name: "下线某个设备",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeSession(gomock.Any(), 1, "other").Return(nil)
+				return hdl
+			},
+			method:     http.MethodDelete,
+			path:       "/users/sessions/other",
+			wantResult: Result{Msg: "下线成功"},
+		},
+		{
+			name: "下线别人的或者不存在的会话",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeSession(gomock.Any(), 1, "other").Return(ijwt.ErrSessionNotFind)
+				return hdl
+			},
+			method:     http.MethodDelete,
+			path:       "/users/sessions/other",
+			wantResult: Result{Code: "4", Msg: "会话不存在"},
+		},
+		{
+			name: "下线其他设备 保留当前设备",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeAllSessions(gomock.Any(), 1, "cur").Return(nil)
+				return hdl
+			},
+			method:     http.MethodDelete,
+			path:       "/users/sessions",
+			wantResult: Result{Msg: "下线成功"},
+		},
+		{
+			name: "下线其他设备出错",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeAllSessions(gomock.Any(), 1, "cur").Return(errors.New("redis出错"))
+				return hdl
+			},
+			method:     http.MethodDelete,
+			path:       "/users/sessions",
+			wantResult: Result{Code: "5", Msg: "系统错误"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ijwt.SetClaims(ctx, &ijwt.UserClaims{UserId: 1, Ssid: "cur"})
+			})
+			userHandler := NewUserHandler(nil, nil, tc.mock(ctrl), nil, nil, nil, nil, nil)
+			userHandler.RegisterRouter(server)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+			val, err := json.Marshal(tc.wantResult)
+			require.NoError(t, err)
+			assert.Equal(t, string(val), resp.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_LoginMFA(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.MFAService,
+			service.LoginGuardService, ijwt.Handler)
+		reqBody string
+
+		wantCode int
+		wantBody string
+	}{
+		{
+			name: "二次验证通过 签发token",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.MFAService,
+				service.LoginGuardService, ijwt.Handler) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ParseMFAToken(gomock.Any(), "mfa").Return(&ijwt.MFAClaims{Uid: 1}, nil)
+				hdl.EXPECT().SetLoginToken(gomock.Any(), 1, ijwt.LoginMethodEmail).Return(nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				guardSvc := svcmocks.NewMockLoginGuardService(ctrl)
+				guardSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				guardSvc.EXPECT().Succeed(gomock.Any(), "123@qq.com").Return(nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().Verify(gomock.Any(), 1, "123456").Return(nil)
+				return userSvc, mfaSvc, guardSvc, hdl
+			},
+			reqBody:  `{"mfa_token":"mfa","code":"123456"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":"","msg":"登录成功","data":null}`,
+		},
+		{
+			name: "二次验证凭证无效 重新输入密码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.MFAService,
+				service.LoginGuardService, ijwt.Handler) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ParseMFAToken(gomock.Any(), "mfa").Return(nil, ijwt.ErrTokenInvalid)
+				return nil, nil, nil, hdl
+			},
+			reqBody:  `{"mfa_token":"mfa","code":"123456"}`,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "二次验证码错误 算一次登录失败",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.MFAService,
+				service.LoginGuardService, ijwt.Handler) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ParseMFAToken(gomock.Any(), "mfa").Return(&ijwt.MFAClaims{Uid: 1}, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				guardSvc := svcmocks.NewMockLoginGuardService(ctrl)
+				guardSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				guardSvc.EXPECT().Fail(gomock.Any(), "123@qq.com", gomock.Any()).Return(nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().Verify(gomock.Any(), 1, "000000").Return(service.ErrMFACodeInvalid)
+				return userSvc, mfaSvc, guardSvc, hdl
+			},
+			reqBody:  `{"mfa_token":"mfa","code":"000000"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":"4","msg":"验证码错误","data":null}`,
+		},
+		{
+			name: "账号已经锁定 不校验验证码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.MFAService,
+				service.LoginGuardService, ijwt.Handler) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ParseMFAToken(gomock.Any(), "mfa").Return(&ijwt.MFAClaims{Uid: 1}, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				guardSvc := svcmocks.NewMockLoginGuardService(ctrl)
+				guardSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(time.Minute*30, service.ErrAccountLocked)
+				return userSvc, svcmocks.NewMockMFAService(ctrl), guardSvc, hdl
+			},
+			reqBody:  `{"mfa_token":"mfa","code":"123456"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":"8","msg":"密码错误次数太多,账号已被临时锁定","data":{"retry_after":1800}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := gin.Default()
+			userSvc, mfaSvc, guardSvc, hdl := tc.mock(ctrl)
+			userHandler := NewUserHandler(userSvc, nil, hdl, mfaSvc, nil, guardSvc, nil, nil)
+			userHandler.RegisterRouter(server)
+
+			req := httptest.NewRequest(http.MethodPost, "/users/login/mfa", bytes.NewBuffer([]byte(tc.reqBody)))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.wantCode, resp.Code)
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+			assert.JSONEq(t, tc.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_MFA(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (service.UserService, service.MFAService, ijwt.Handler)
+		path    string
+		reqBody string
+
+		wantCode   int
+		wantResult Result
+	}{
+		{
+			name: "绑定验证器 手机号用户用手机号当账号名",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.MFAService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Phone: "15212345678"}, nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().SetupTOTP(gomock.Any(), 1, "15212345678").Return("SECRET", "otpauth://totp/webook", nil)
+				return userSvc, mfaSvc, nil
+			},
+			path:       "/users/mfa/totp/setup",
+			wantCode:   http.StatusOK,
+			wantResult: Result{Data: map[string]any{"secret": "SECRET", "uri": "otpauth://totp/webook"}},
+		},
+		{
+			name: "已经开启了二次验证 不能重新绑定",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.MFAService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().SetupTOTP(gomock.Any(), 1, "123@qq.com").Return("", "", service.ErrMFAAlreadyEnabled)
+				return userSvc, mfaSvc, nil
+			},
+			path:       "/users/mfa/totp/setup",
+			wantCode:   http.StatusOK,
+			wantResult: Result{Code: "4", Msg: "已经开启了二次验证"},
+		},
+		{
+			name: "开启二次验证 返回恢复码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.MFAService, ijwt.Handler) {
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().EnableTOTP(gomock.Any(), 1, "123456").Return([]string{"r1", "r2"}, nil)
+				return nil, mfaSvc, nil
+			},
+			path:     "/users/mfa/totp/enable",
+			reqBody:  `{"code":"123456"}`,
+			wantCode: http.StatusOK,
+			wantResult: Result{
+				Msg:  "开启成功",
+				Data: map[string]any{"recovery_codes": []any{"r1", "r2"}},
+			},
+		},
+		{
+			name: "没有绑定就开启",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.MFAService, ijwt.Handler) {
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().EnableTOTP(gomock.Any(), 1, "123456").Return(nil, service.ErrMFANotEnabled)
+				return nil, mfaSvc, nil
+			},
+			path:       "/users/mfa/totp/enable",
+			reqBody:    `{"code":"123456"}`,
+			wantCode:   http.StatusOK,
+			wantResult: Result{Code: "4", Msg: "请先绑定验证器"},
+		},
+		{
+			name: "开启时验证码错误",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.MFAService, ijwt.Handler) {
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().EnableTOTP(gomock.Any(), 1, "000000").Return(nil, service.ErrMFACodeInvalid)
+				return nil, mfaSvc, nil
+			},
+			path:       "/users/mfa/totp/enable",
+			reqBody:    `{"code":"000000"}`,
+			wantCode:   http.StatusOK,
+			wantResult: Result{Code: "4", Msg: "验证码错误"},
+		},
+		{
+			name: "关闭二次验证",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.MFAService, ijwt.Handler) {
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().DisableTOTP(gomock.Any(), 1, "r1").Return(nil)
+				return nil, mfaSvc, nil
+			},
+			path:       "/users/mfa/totp/disable",
+			reqBody:    `{"code":"r1"}`,
+			wantCode:   http.StatusOK,
+			wantResult: Result{Msg: "已关闭二次验证"},
+		},
+		{
+			name: "没有开启就关闭",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.MFAService, ijwt.Handler) {
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().DisableTOTP(gomock.Any(), 1, "123456").Return(service.ErrMFANotEnabled)
+				return nil, mfaSvc, nil
+			},
+			path:       "/users/mfa/totp/disable",
+			reqBody:    `{"code":"123456"}`,
+			wantCode:   http.StatusOK,
+			wantResult: Result{Code: "4", Msg: "没有开启二次验证"},
+		},
+		{
+			name: "关闭时验证码错误",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.MFAService, ijwt.Handler) {
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().DisableTOTP(gomock.Any(), 1, "000000").Return(service.ErrMFACodeInvalid)
+				return nil, mfaSvc, nil
+			},
+			path:       "/users/mfa/totp/disable",
+			reqBody:    `{"code":"000000"}`,
+			wantCode:   http.StatusOK,
+			wantResult: Result{Code: "4", Msg: "验证码错误"},
 		},
-		UserId:    1,
-		UserAgent: "",
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
-	jwtToken, err := token.SignedString([]byte(JwtTokenKey))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ijwt.SetClaims(ctx, &ijwt.UserClaims{UserId: 1})
+			})
+			userSvc, mfaSvc, hdl := tc.mock(ctrl)
+			userHandler := NewUserHandler(userSvc, nil, hdl, mfaSvc, nil, nil, nil, nil)
+			userHandler.RegisterRouter(server)
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, bytes.NewBuffer([]byte(tc.reqBody)))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.wantCode, resp.Code)
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+			var result Result
+			err := json.Unmarshal(resp.Body.Bytes(), &result)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantResult, result)
+		})
+	}
+}
+
+func TestUserHandler_ChangePassword(t *testing.T) {
+	policy := validator.NewDefaultPolicy()
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler)
+		reqBody string
+
+		wantCode string
+		wantMsg  string
+	}{
+		{
+			name: "修改成功 下线其他会话",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				userSvc.EXPECT().ChangePassword(gomock.Any(), 1, "hello#world123", "new#world456").Return(nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				//只保留当前会话
+				hdl.EXPECT().RevokeAllSessions(gomock.Any(), 1, "cur").Return(nil)
+				return userSvc, hdl
+			},
+			reqBody: `{"old_password":"hello#world123","new_password":"new#world456"}`,
+			wantMsg: "修改成功",
+		},
+		{
+			name: "下线其他会话失败 密码已经改了",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				userSvc.EXPECT().ChangePassword(gomock.Any(), 1, "hello#world123", "new#world456").Return(nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeAllSessions(gomock.Any(), 1, "cur").Return(errors.New("redis出错"))
+				return userSvc, hdl
+			},
+			reqBody: `{"old_password":"hello#world123","new_password":"new#world456"}`,
+			wantMsg: "修改成功",
+		},
+		{
+			name: "原密码不对 不下线会话",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				userSvc.EXPECT().ChangePassword(gomock.Any(), 1, "wrong#world123", "new#world456").
+					Return(service.ErrPasswordMismatch)
+				return userSvc, jwtmocks.NewMockHandler(ctrl)
+			},
+			reqBody:  `{"old_password":"wrong#world123","new_password":"new#world456"}`,
+			wantCode: "4",
+			wantMsg:  "原密码不对",
+		},
+		{
+			name: "新密码不符合策略",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				return userSvc, jwtmocks.NewMockHandler(ctrl)
+			},
+			reqBody:  `{"old_password":"hello#world123","new_password":"abc"}`,
+			wantCode: "4",
+			wantMsg:  policy.Password("abc", "123@qq.com")[0].Msg,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ijwt.SetClaims(ctx, &ijwt.UserClaims{UserId: 1, Ssid: "cur"})
+			})
+			userSvc, hdl := tc.mock(ctrl)
+			userHandler := NewUserHandler(userSvc, nil, hdl, nil, nil, nil, policy, nil)
+			userHandler.RegisterRouter(server)
+
+			req := httptest.NewRequest(http.MethodPost, "/users/password/change", bytes.NewBuffer([]byte(tc.reqBody)))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+			var result Result
+			err := json.Unmarshal(resp.Body.Bytes(), &result)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantCode, result.Code)
+			assert.Equal(t, tc.wantMsg, result.Msg)
+		})
+	}
+}
+
+func TestUserHandler_BindEmail(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (service.UserService, service.EmailVerifyService)
+		reqBody string
+
+		wantResult Result
+	}{
+		{
+			name: "验证码正确 绑定成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailVerifyService) {
+				verifySvc := svcmocks.NewMockEmailVerifyService(ctrl)
+				verifySvc.EXPECT().VerifyBindCode(gomock.Any(), "123@qq.com", "123456").Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().BindEmail(gomock.Any(), 1, "123@qq.com").Return(nil)
+				return userSvc, verifySvc
+			},
+			reqBody:    `{"email":"123@qq.com","code":"123456"}`,
+			wantResult: Result{Msg: "操作成功"},
+		},
+		{
+			name: "没有验证码 不能绑定",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailVerifyService) {
+				verifySvc := svcmocks.NewMockEmailVerifyService(ctrl)
+				verifySvc.EXPECT().VerifyBindCode(gomock.Any(), "123@qq.com", "").Return(false, nil)
+				return svcmocks.NewMockUserService(ctrl), verifySvc
+			},
+			reqBody:    `{"email":"123@qq.com"}`,
+			wantResult: Result{Code: "4", Msg: "验证码错误"},
+		},
+		{
+			name: "邮箱被其他账号占用",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailVerifyService) {
+				verifySvc := svcmocks.NewMockEmailVerifyService(ctrl)
+				verifySvc.EXPECT().VerifyBindCode(gomock.Any(), "123@qq.com", "123456").Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().BindEmail(gomock.Any(), 1, "123@qq.com").Return(service.ErrEmailConflict)
+				return userSvc, verifySvc
+			},
+			reqBody:    `{"email":"123@qq.com","code":"123456"}`,
+			wantResult: Result{Code: "4", Msg: service.ErrEmailConflict.Error()},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ijwt.SetClaims(ctx, &ijwt.UserClaims{UserId: 1})
+			})
+			userSvc, verifySvc := tc.mock(ctrl)
+			userHandler := NewUserHandler(userSvc, nil, nil, nil, verifySvc, nil, validator.NewDefaultPolicy(), nil)
+			userHandler.RegisterRouter(server)
+
+			req := httptest.NewRequest(http.MethodPost, "/users/bind/email", bytes.NewBuffer([]byte(tc.reqBody)))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+			var result Result
+			err := json.Unmarshal(resp.Body.Bytes(), &result)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantResult, result)
+		})
+	}
+}
+
+func TestUserHandler_ExportData(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userSvc := svcmocks.NewMockUserService(ctrl)
+	userSvc.EXPECT().Profile(gomock.Any(), domain.User{Id: 1}).
+		Return(domain.User{Id: 1, Email: "123@qq.com", Ctime: time.UnixMilli(100)}, nil)
+	mfaSvc := svcmocks.NewMockMFAService(ctrl)
+	mfaSvc.EXPECT().Enabled(gomock.Any(), 1).Return(false, nil)
+	hdl := jwtmocks.NewMockHandler(ctrl)
+	hdl.EXPECT().ListSessions(gomock.Any(), 1).Return(nil, nil)
+	roleSvc := svcmocks.NewMockRoleService(ctrl)
+	//签发token之后被撤销了admin角色
+	roleSvc.EXPECT().UserPermissions(gomock.Any(), 1).Return([]string{"user"}, []string{"article:read"}, nil)
+
+	server := gin.Default()
+	server.Use(func(ctx *gin.Context) {
+		ijwt.SetClaims(ctx, &ijwt.UserClaims{UserId: 1, Roles: []string{"admin"}})
+	})
+	userHandler := NewUserHandler(userSvc, nil, hdl, mfaSvc, nil, nil, nil, roleSvc)
+	userHandler.RegisterRouter(server)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/export", nil)
+	resp := httptest.NewRecorder()
+	server.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var result struct {
+		Data UserExportVo `json:"data"`
+	}
+	err := json.Unmarshal(resp.Body.Bytes(), &result)
 	require.NoError(t, err)
-	println(jwtToken)
+	assert.Equal(t, []string{"user"}, result.Data.Roles)
+	assert.Equal(t, "123@qq.com", result.Data.Email)
+}
+
+func TestUserHandler_LoginJWT_Guard(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.LoginGuardService)
+		//密码正确之后用到的
+		mfaMock func(ctrl *gomock.Controller) (service.EmailVerifyService, service.MFAService, ijwt.Handler)
+
+		wantBody string
+	}{
+		{
+			name: "账号被锁定 不校验密码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginGuardService) {
+				guardSvc := svcmocks.NewMockLoginGuardService(ctrl)
+				guardSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(time.Minute*30, service.ErrAccountLocked)
+				return svcmocks.NewMockUserService(ctrl), guardSvc
+			},
+			wantBody: `{"code":"8","msg":"密码错误次数太多,账号已被临时锁定","data":{"retry_after":1800}}`,
+		},
+		{
+			name: "还没到下次可以尝试的时间",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginGuardService) {
+				guardSvc := svcmocks.NewMockLoginGuardService(ctrl)
+				guardSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(time.Second*4, service.ErrLoginTooFrequent)
+				return svcmocks.NewMockUserService(ctrl), guardSvc
+			},
+			wantBody: `{"code":"4","msg":"登录失败次数太多,请稍后再试","data":{"retry_after":4}}`,
+		},
+		{
+			name: "密码错误 记录失败次数",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginGuardService) {
+				guardSvc := svcmocks.NewMockLoginGuardService(ctrl)
+				guardSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				guardSvc.EXPECT().Fail(gomock.Any(), "123@qq.com", gomock.Any()).Return(nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Login(gomock.Any(), domain.User{Email: "123@qq.com", Password: "hello#world123"}).
+					Return(domain.User{}, service.ErrInvalidUserOrPassword)
+				return userSvc, guardSvc
+			},
+			wantBody: "用户名或密码错误！",
+		},
+		{
+			name: "密码正确 二次验证通过之前不清空失败次数",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginGuardService) {
+				guardSvc := svcmocks.NewMockLoginGuardService(ctrl)
+				guardSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Login(gomock.Any(), domain.User{Email: "123@qq.com", Password: "hello#world123"}).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				return userSvc, guardSvc
+			},
+			mfaMock: func(ctrl *gomock.Controller) (service.EmailVerifyService, service.MFAService, ijwt.Handler) {
+				verifySvc := svcmocks.NewMockEmailVerifyService(ctrl)
+				verifySvc.EXPECT().CheckLogin(domain.User{Id: 1, Email: "123@qq.com"}).Return(nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().Enabled(gomock.Any(), 1).Return(true, nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().IssueMFAToken(gomock.Any(), 1).Return("mfa", nil)
+				return verifySvc, mfaSvc, hdl
+			},
+			wantBody: `{"code":"6","msg":"需要二次验证","data":{"mfa_token":"mfa"}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			userSvc, guardSvc := tc.mock(ctrl)
+			var (
+				verifySvc service.EmailVerifyService
+				mfaSvc    service.MFAService
+				hdl       ijwt.Handler
+			)
+			if tc.mfaMock != nil {
+				verifySvc, mfaSvc, hdl = tc.mfaMock(ctrl)
+			}
+			userHandler := NewUserHandler(userSvc, nil, hdl, mfaSvc, verifySvc, guardSvc, nil, nil)
+			server := gin.Default()
+			userHandler.RegisterRouter(server)
+
+			req := httptest.NewRequest(http.MethodPost, "/users/login",
+				bytes.NewBuffer([]byte(`{"email":"123@qq.com","password":"hello#world123"}`)))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, tc.wantBody, resp.Body.String())
+		})
+	}
 }
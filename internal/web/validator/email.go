@@ -0,0 +1,106 @@
+package validator
+
+import (
+	"strings"
+)
+
+const (
+	emailMaxLen      = 254
+	emailLocalMaxLen = 64
+	domainLabelMax   = 63
+)
+
+type EmailPolicy struct {
+	disposable map[string]struct{}
+}
+
+// NewEmailPolicy disposable为一次性邮箱的域名 为空表示不拦截
+func NewEmailPolicy(disposable []string) *EmailPolicy {
+	return &EmailPolicy{disposable: toSet(disposable)}
+}
+
+// Validate 只接受常见的 local@domain 形式 不支持带引号的local和IP地址的域名
+func (p *EmailPolicy) Validate(email string) []Violation {
+	if email == "" {
+		return []Violation{{Rule: RuleRequired, Msg: "请输入邮箱"}}
+	}
+	if !validEmail(email) {
+		return []Violation{{Rule: RuleEmailSyntax, Msg: "邮箱格式错误"}}
+	}
+	_, domain, _ := strings.Cut(strings.ToLower(email), "@")
+	if p.isDisposable(domain) {
+		return []Violation{{Rule: RuleDisposable, Msg: "不支持一次性邮箱"}}
+	}
+	return nil
+}
+
+// isDisposable 子域名也算 例如 a.mailinator.com
+func (p *EmailPolicy) isDisposable(domain string) bool {
+	for domain != "" {
+		if _, ok := p.disposable[domain]; ok {
+			return true
+		}
+		_, domain, _ = strings.Cut(domain, ".")
+	}
+	return false
+}
+
+func validEmail(email string) bool {
+	if len(email) > emailMaxLen {
+		return false
+	}
+	idx := strings.LastIndexByte(email, '@')
+	if idx < 0 {
+		return false
+	}
+	return validLocal(email[:idx]) && validDomain(email[idx+1:])
+}
+
+// validLocal RFC 5322 的 dot-atom 允许+号 例如 a+webook@gmail.com
+func validLocal(local string) bool {
+	if local == "" || len(local) > emailLocalMaxLen {
+		return false
+	}
+	for _, atom := range strings.Split(local, ".") {
+		//点不能在开头结尾 也不能连续
+		if atom == "" {
+			return false
+		}
+		for i := 0; i < len(atom); i++ {
+			if !isAtext(atom[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isAtext(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-/=?^_`{|}~", c) >= 0
+}
+
+// validDomain 至少两级 国际化域名需要先转成punycode
+func validDomain(domain string) bool {
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > domainLabelMax || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	//顶级域名不能全是数字 排除IP地址
+	tld := labels[len(labels)-1]
+	return len(tld) >= 2 && strings.TrimLeft(tld, "0123456789") != ""
+}
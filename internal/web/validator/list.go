@@ -0,0 +1,66 @@
+package validator
+
+import (
+	"bufio"
+	"embed"
+	"io"
+	"os"
+	"strings"
+)
+
+//go:embed data/*.txt
+var data embed.FS
+
+// DefaultCommonPasswords 内置的弱密码列表
+func DefaultCommonPasswords() []string {
+	return mustReadEmbed("data/common_passwords.txt")
+}
+
+// DefaultDisposableDomains 内置的一次性邮箱域名
+func DefaultDisposableDomains() []string {
+	return mustReadEmbed("data/disposable_domains.txt")
+}
+
+// LoadList 从本地文件加载列表 一行一个 忽略空行和#开头的注释
+func LoadList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readList(f)
+}
+
+func mustReadEmbed(name string) []string {
+	f, err := data.Open(name)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	list, err := readList(f)
+	if err != nil {
+		panic(err)
+	}
+	return list
+}
+
+func readList(r io.Reader) ([]string, error) {
+	var list []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, strings.ToLower(line))
+	}
+	return list, scanner.Err()
+}
+
+func toSet(list []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(list))
+	for _, item := range list {
+		set[strings.ToLower(item)] = struct{}{}
+	}
+	return set
+}
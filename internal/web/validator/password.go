@@ -0,0 +1,104 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordRules 长度按字符数算
+type PasswordRules struct {
+	MinLength int
+	//bcrypt只用前72个字节 太长也没有意义
+	MaxLength     int
+	RequireLetter bool
+	RequireDigit  bool
+	//字母和数字以外的可见字符
+	RequireSymbol bool
+	//同时包含大小写字母
+	RequireMixedCase bool
+	//不能包含邮箱或者邮箱@前面的部分
+	DisallowEmail bool
+}
+
+// DefaultPasswordRules 和原来的正则保持一致 但是不再限制可以用哪些符号
+var DefaultPasswordRules = PasswordRules{
+	MinLength:     8,
+	MaxLength:     64,
+	RequireLetter: true,
+	RequireDigit:  true,
+	RequireSymbol: true,
+	DisallowEmail: true,
+}
+
+type PasswordPolicy struct {
+	rules  PasswordRules
+	common map[string]struct{}
+}
+
+// NewPasswordPolicy common为弱密码列表 比较时忽略大小写
+func NewPasswordPolicy(rules PasswordRules, common []string) *PasswordPolicy {
+	return &PasswordPolicy{rules: rules, common: toSet(common)}
+}
+
+// Validate email为空时不检查是否包含邮箱 返回所有不满足的规则
+func (p *PasswordPolicy) Validate(password string, email string) []Violation {
+	if password == "" {
+		return []Violation{{Rule: RuleRequired, Msg: "请输入密码"}}
+	}
+	var vs []Violation
+	length := utf8.RuneCountInString(password)
+	if length < p.rules.MinLength {
+		vs = append(vs, Violation{Rule: RuleMinLength, Msg: fmt.Sprintf("密码至少%d位", p.rules.MinLength)})
+	}
+	if p.rules.MaxLength > 0 && length > p.rules.MaxLength {
+		vs = append(vs, Violation{Rule: RuleMaxLength, Msg: fmt.Sprintf("密码不能超过%d位", p.rules.MaxLength)})
+	}
+	var letter, lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			//中文这种没有大小写的也算字母
+			letter = true
+			lower = lower || unicode.IsLower(r)
+			upper = upper || unicode.IsUpper(r)
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPrint(r) && !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.rules.RequireLetter && !letter {
+		vs = append(vs, Violation{Rule: RuleLetter, Msg: "密码需要包含字母"})
+	}
+	if p.rules.RequireMixedCase && !(lower && upper) {
+		vs = append(vs, Violation{Rule: RuleMixedCase, Msg: "密码需要同时包含大写和小写字母"})
+	}
+	if p.rules.RequireDigit && !digit {
+		vs = append(vs, Violation{Rule: RuleDigit, Msg: "密码需要包含数字"})
+	}
+	if p.rules.RequireSymbol && !symbol {
+		vs = append(vs, Violation{Rule: RuleSymbol, Msg: "密码需要包含特殊符号"})
+	}
+	lowered := strings.ToLower(password)
+	if _, ok := p.common[lowered]; ok {
+		vs = append(vs, Violation{Rule: RuleCommon, Msg: "密码太常见,很容易被猜到"})
+	}
+	if p.rules.DisallowEmail && p.containsEmail(lowered, strings.ToLower(email)) {
+		vs = append(vs, Violation{Rule: RuleContainsEmail, Msg: "密码不能包含邮箱"})
+	}
+	return vs
+}
+
+func (p *PasswordPolicy) containsEmail(password string, email string) bool {
+	if email == "" {
+		return false
+	}
+	if strings.Contains(password, email) {
+		return true
+	}
+	local, _, _ := strings.Cut(email, "@")
+	//太短的用户名很容易误伤
+	return utf8.RuneCountInString(local) >= 3 && strings.Contains(password, local)
+}
@@ -0,0 +1,26 @@
+package validator
+
+// Policy 注册 改密码 绑定邮箱时共用的校验策略
+type Policy struct {
+	password *PasswordPolicy
+	email    *EmailPolicy
+}
+
+func NewPolicy(password *PasswordPolicy, email *EmailPolicy) *Policy {
+	return &Policy{password: password, email: email}
+}
+
+// NewDefaultPolicy 默认规则和内置列表
+func NewDefaultPolicy() *Policy {
+	return NewPolicy(NewPasswordPolicy(DefaultPasswordRules, DefaultCommonPasswords()),
+		NewEmailPolicy(DefaultDisposableDomains()))
+}
+
+// Password email为空时不检查密码是否包含邮箱
+func (p *Policy) Password(password string, email string) []Violation {
+	return p.password.Validate(password, email)
+}
+
+func (p *Policy) Email(email string) []Violation {
+	return p.email.Validate(email)
+}
@@ -0,0 +1,110 @@
+package validator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEmailPolicy_Validate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		email string
+
+		wantRule string
+	}{
+		{name: "普通邮箱", email: "2594618554@qq.com"},
+		{name: "加号别名", email: "a.b+webook@gmail.com"},
+		{name: "多级域名", email: "tom@mail.example.com.cn"},
+		{name: "punycode域名", email: "tom@xn--fiqs8s.xn--fiqs8s"},
+		{name: "为空", email: "", wantRule: RuleRequired},
+		{name: "没有@", email: "2594618554qq.com", wantRule: RuleEmailSyntax},
+		{name: "中文用户名", email: "张三@qq.com", wantRule: RuleEmailSyntax},
+		{name: "连续的点", email: "a..b@qq.com", wantRule: RuleEmailSyntax},
+		{name: "点开头", email: ".ab@qq.com", wantRule: RuleEmailSyntax},
+		{name: "只有一级域名", email: "ab@localhost", wantRule: RuleEmailSyntax},
+		{name: "IP地址", email: "ab@127.0.0.1", wantRule: RuleEmailSyntax},
+		{name: "域名以-开头", email: "ab@-qq.com", wantRule: RuleEmailSyntax},
+		{name: "带显示名", email: "Tom <tom@qq.com>", wantRule: RuleEmailSyntax},
+		{name: "一次性邮箱", email: "tom@Mailinator.com", wantRule: RuleDisposable},
+		{name: "一次性邮箱的子域名", email: "tom@a.yopmail.com", wantRule: RuleDisposable},
+	}
+
+	p := NewEmailPolicy(DefaultDisposableDomains())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vs := p.Validate(tc.email)
+			if tc.wantRule == "" {
+				assert.Empty(t, vs)
+				return
+			}
+			if assert.Len(t, vs, 1) {
+				assert.Equal(t, tc.wantRule, vs[0].Rule)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rules    PasswordRules
+		password string
+		email    string
+
+		wantRules []string
+	}{
+		{
+			name:     "满足默认规则",
+			rules:    DefaultPasswordRules,
+			password: "1234qwe56asd@",
+			email:    "2594618554@qq.com",
+		},
+		{
+			name:     "符号不再限制范围",
+			rules:    DefaultPasswordRules,
+			password: "hello-world_42",
+		},
+		{
+			name:      "太短并且缺少符号",
+			rules:     DefaultPasswordRules,
+			password:  "1234q",
+			wantRules: []string{RuleMinLength, RuleSymbol},
+		},
+		{
+			name:      "常见密码 忽略大小写",
+			rules:     DefaultPasswordRules,
+			password:  "P@ssw0rd1",
+			wantRules: []string{RuleCommon},
+		},
+		{
+			name:      "包含邮箱用户名",
+			rules:     DefaultPasswordRules,
+			password:  "tommy#2024",
+			email:     "Tommy@qq.com",
+			wantRules: []string{RuleContainsEmail},
+		},
+		{
+			name:      "要求大小写",
+			rules:     PasswordRules{MinLength: 8, RequireMixedCase: true},
+			password:  "abcdefgh",
+			wantRules: []string{RuleMixedCase},
+		},
+		{
+			name:      "为空",
+			rules:     DefaultPasswordRules,
+			password:  "",
+			wantRules: []string{RuleRequired},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewPasswordPolicy(tc.rules, DefaultCommonPasswords())
+			var rules []string
+			for _, v := range p.Validate(tc.password, tc.email) {
+				rules = append(rules, v.Rule)
+			}
+			assert.Equal(t, tc.wantRules, rules)
+		})
+	}
+}
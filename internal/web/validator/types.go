@@ -0,0 +1,22 @@
+package validator
+
+// Violation 不满足的一条规则 Rule给前端做判断 Msg直接给用户看
+type Violation struct {
+	Rule string `json:"rule"`
+	Msg  string `json:"msg"`
+}
+
+// 规则名
+const (
+	RuleRequired      = "required"
+	RuleMinLength     = "min_length"
+	RuleMaxLength     = "max_length"
+	RuleLetter        = "letter"
+	RuleDigit         = "digit"
+	RuleSymbol        = "symbol"
+	RuleMixedCase     = "mixed_case"
+	RuleCommon        = "common"
+	RuleContainsEmail = "contains_email"
+	RuleEmailSyntax   = "email_syntax"
+	RuleDisposable    = "disposable"
+)
@@ -1,15 +1,18 @@
 package web
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	uuid "github.com/lithammer/shortuuid/v4"
 	"net/http"
 	"time"
+	"webook/internal/domain"
 	"webook/internal/service"
 	"webook/internal/service/oauth2/wechat"
 	"webook/internal/web/ijwt"
+	"webook/internal/web/middleware"
 )
 
 type OAuthWechatHandler struct {
@@ -19,22 +22,39 @@ type OAuthWechatHandler struct {
 	stateKey []byte
 }
 
-func NewOAuthWechatHandler(svc wechat.Service, userSvc service.UserService) *OAuthWechatHandler {
+func NewOAuthWechatHandler(svc wechat.Service, userSvc service.UserService, jwtHandler ijwt.Handler) *OAuthWechatHandler {
 	return &OAuthWechatHandler{
 		svc:      svc,
 		userSvc:  userSvc,
+		Handler:  jwtHandler,
 		stateKey: []byte("tbkykLFqpai8IwdLt9N20HfAsFZoK1uA"),
 	}
 }
 
 func (o *OAuthWechatHandler) RegisterRoutes(server *gin.Engine) {
 	g := server.Group("/oath2/wechat")
-	g.GET("/authurl", o.AuthURL)
-	g.Any("/callback", o.Callback)
+	g.GET("/authurl", middleware.Public(), o.AuthURL)
+	//已登录的用户绑定微信
+	g.GET("/bind/authurl", o.BindAuthURL)
+	g.Any("/callback", middleware.Public(), o.Callback)
 
 }
 
 func (o *OAuthWechatHandler) AuthURL(ctx *gin.Context) {
+	o.authURL(ctx, 0)
+}
+
+// BindAuthURL 扫码后回调时绑定到当前用户 而不是登录
+func (o *OAuthWechatHandler) BindAuthURL(ctx *gin.Context) {
+	claims, ok := ijwt.GetClaims(ctx)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	o.authURL(ctx, claims.UserId)
+}
+
+func (o *OAuthWechatHandler) authURL(ctx *gin.Context, bindUid int) {
 	state := uuid.New()
 	url, err := o.svc.AuthURL(ctx, state)
 	if err != nil {
@@ -42,14 +62,16 @@ func (o *OAuthWechatHandler) AuthURL(ctx *gin.Context) {
 			Code: "5",
 			Msg:  "构造扫码登录失败",
 		})
+		return
 	}
-	err = o.setStateCookie(ctx, state)
+	err = o.setStateCookie(ctx, state, bindUid)
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Code: "5",
 			Msg:  "系统异常",
 			Data: nil,
 		})
+		return
 	}
 	ctx.JSON(http.StatusOK, Result{
 		Data: url,
@@ -57,9 +79,10 @@ func (o *OAuthWechatHandler) AuthURL(ctx *gin.Context) {
 	return
 }
 
-func (o *OAuthWechatHandler) setStateCookie(ctx *gin.Context, state string) error {
+func (o *OAuthWechatHandler) setStateCookie(ctx *gin.Context, state string, bindUid int) error {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS512, StateClaims{
-		State: state,
+		State:   state,
+		BindUid: bindUid,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute * 3)),
 		},
@@ -75,7 +98,7 @@ func (o *OAuthWechatHandler) setStateCookie(ctx *gin.Context, state string) erro
 func (o *OAuthWechatHandler) Callback(ctx *gin.Context) {
 	//验证code
 	code := ctx.Query("code")
-	err := o.verifyState(ctx)
+	sc, err := o.verifyState(ctx)
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Code: "5",
@@ -101,9 +124,21 @@ func (o *OAuthWechatHandler) Callback(ctx *gin.Context) {
 		})
 		return
 	}
+	if sc.BindUid > 0 {
+		o.bind(ctx, sc.BindUid, info)
+		return
+	}
 	u, err := o.userSvc.FindOrCreateByWechat(ctx, info)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+			Data: nil,
+		})
+		return
+	}
 
-	err = o.SetLoginToken(ctx, u.Id)
+	err = o.SetLoginToken(ctx, u.Id, ijwt.LoginMethodWechat)
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Code: "5",
@@ -120,27 +155,49 @@ func (o *OAuthWechatHandler) Callback(ctx *gin.Context) {
 	})
 }
 
-func (o *OAuthWechatHandler) verifyState(ctx *gin.Context) error {
+func (o *OAuthWechatHandler) bind(ctx *gin.Context, uid int, info domain.WechatInfo) {
+	err := o.userSvc.BindWechat(ctx, uid, info)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "绑定成功",
+		})
+	case errors.Is(err, service.ErrWechatConflict):
+		ctx.JSON(http.StatusOK, Result{
+			Code: "4",
+			Msg:  err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: "5",
+			Msg:  "系统错误",
+		})
+	}
+}
+
+func (o *OAuthWechatHandler) verifyState(ctx *gin.Context) (StateClaims, error) {
 	state := ctx.Query("state")
 	cookie, err := ctx.Cookie("ijwt-state")
 	if err != nil {
-		return fmt.Errorf("拿不到state的cookie,%w", err)
+		return StateClaims{}, fmt.Errorf("拿不到state的cookie,%w", err)
 	}
 	var sc StateClaims
 	token, err := jwt.ParseWithClaims(cookie, &sc, func(token *jwt.Token) (interface{}, error) {
 		return o.stateKey, nil
 	})
 	if err != nil || !token.Valid {
-		return fmt.Errorf("token已经过期,%w", err)
+		return StateClaims{}, fmt.Errorf("token已经过期,%w", err)
 
 	}
 	if sc.State != state {
-		return fmt.Errorf("state不相等,%w", err)
+		return StateClaims{}, errors.New("state不相等")
 	}
-	return err
+	return sc, nil
 }
 
 type StateClaims struct {
 	State string
+	//不为0表示是绑定微信 而不是登录
+	BindUid int
 	jwt.RegisteredClaims
 }
@@ -0,0 +1,47 @@
+package ioc
+
+import (
+	"github.com/redis/go-redis/v9"
+	"os"
+	"time"
+	"webook/config"
+	"webook/internal/service/email"
+	"webook/internal/service/email/failover"
+	"webook/internal/service/email/memory"
+	emailratelimit "webook/internal/service/email/ratelimit"
+	"webook/internal/service/email/smtp"
+	"webook/internal/service/email/templates"
+	"webook/pkg/ratelimit"
+)
+
+func InitEmailService(cmd redis.Cmdable) email.Service {
+	cfg := config.Config.Email
+	if len(cfg.SMTP) == 0 {
+		return memory.NewService()
+	}
+	svcs := make([]email.Service, 0, len(cfg.SMTP))
+	for _, sc := range cfg.SMTP {
+		svc, err := smtp.NewService(sc.Host, sc.Port, sc.Username, os.Getenv(sc.PasswordEnv), sc.From)
+		if err != nil {
+			panic(err)
+		}
+		svcs = append(svcs, svc)
+	}
+	var svc email.Service = svcs[0]
+	if len(svcs) > 1 {
+		svc = failover.NewService(svcs)
+	}
+	rate := cfg.RatePerMinute
+	if rate <= 0 {
+		rate = 100
+	}
+	return emailratelimit.NewService(svc, ratelimit.NewRedisSlidingWindowLimiter(cmd, time.Minute, rate))
+}
+
+func InitEmailTemplates() *templates.Templates {
+	tpls, err := templates.NewDefaultTemplates()
+	if err != nil {
+		panic(err)
+	}
+	return tpls
+}
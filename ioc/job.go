@@ -0,0 +1,12 @@
+package ioc
+
+import (
+	"time"
+	"webook/internal/job"
+	"webook/internal/service"
+)
+
+func InitPurgeUserJob(svc service.UserService, sessions job.SessionRevoker) *job.PurgeUserJob {
+	//冷静期按天算 每小时扫一次足够了
+	return job.NewPurgeUserJob(svc, sessions, time.Hour, 100)
+}
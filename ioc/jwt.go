@@ -0,0 +1,52 @@
+package ioc
+
+import (
+	"log"
+	"webook/config"
+	"webook/internal/web/ijwt"
+)
+
+func InitJWTKeys() *ijwt.KeySet {
+	if len(config.Config.JWT.Keys) == 0 {
+		//开发环境没有配置密钥 重启后token全部失效
+		log.Println("没有配置JWT密钥,使用临时生成的密钥")
+		keys, err := ijwt.NewEphemeralKeySet()
+		if err != nil {
+			panic(err)
+		}
+		return keys
+	}
+	keys, err := ijwt.LoadKeySet(config.Config.JWT)
+	if err != nil {
+		panic(err)
+	}
+	return keys
+}
+
+func InitJWTTransport() ijwt.Transport {
+	cfg := config.Config.JWT
+	switch cfg.Transport {
+	case "cookie":
+		return ijwt.NewCookieTransport(cfg.Cookie.Domain, cfg.Cookie.Secure)
+	case "both":
+		return ijwt.NewCompositeTransport(ijwt.NewHeaderTransport(),
+			ijwt.NewCookieTransport(cfg.Cookie.Domain, cfg.Cookie.Secure))
+	default:
+		return ijwt.NewHeaderTransport()
+	}
+}
+
+func InitDeviceBinding() ijwt.DeviceBinding {
+	switch config.Config.JWT.DeviceBinding {
+	case "none":
+		return ijwt.NewNoneBinding()
+	case "ua_family":
+		return ijwt.NewUAFamilyBinding(false)
+	case "ua_major":
+		return ijwt.NewUAFamilyBinding(true)
+	case "device_id":
+		return ijwt.NewDeviceIdBinding("X-Device-Id")
+	default:
+		return ijwt.NewUserAgentBinding()
+	}
+}
@@ -1,11 +1,126 @@
 package ioc
 
 import (
+	"crypto/rand"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	tencentsms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"webook/config"
+	"webook/internal/repository"
 	"webook/internal/service/sms"
+	"webook/internal/service/sms/aliyun"
+	"webook/internal/service/sms/async"
+	"webook/internal/service/sms/auth"
+	"webook/internal/service/sms/failover"
 	"webook/internal/service/sms/memory"
+	"webook/internal/service/sms/templates"
+	"webook/internal/service/sms/tencent"
+	"webook/pkg/ratelimit"
 )
 
-func InitSMSService() sms.Service {
-	//服务选择
-	return memory.NewService()
+func InitSMSTemplates() *templates.Registry {
+	reg := templates.NewRegistry()
+	for biz, tpls := range config.Config.SMS.Templates {
+		for provider, tc := range tpls {
+			reg.Register(biz, provider, templates.Template{
+				Id:       tc.Id,
+				SignName: tc.SignName,
+				Params:   tc.Params,
+			})
+		}
+	}
+	return reg
+}
+
+// InitSMSFailoverService 按顺序配置服务商 前面的优先
+func InitSMSFailoverService(tpls *templates.Registry) *failover.Service {
+	cfgs := config.Config.SMS.Providers
+	if len(cfgs) == 0 {
+		return failover.NewService([]failover.Provider{
+			{Name: "memory", Svc: memory.NewService()},
+		})
+	}
+	providers := make([]failover.Provider, 0, len(cfgs))
+	for _, pc := range cfgs {
+		name := pc.Name
+		if name == "" {
+			name = pc.Type
+		}
+		providers = append(providers, failover.Provider{Name: name, Svc: initSMSProvider(name, pc, tpls)})
+	}
+	return failover.NewService(providers)
+}
+
+// otpBizes 通过CodeService发送验证码的业务
+var otpBizes = []string{"login", "bind_phone", "reset_pwd", "unlock_login"}
+
+func InitAsyncSMSService(svc *failover.Service, repo repository.AsyncSMSRepository) *async.Service {
+	//服务商出问题时转异步 由后台worker重试 验证码不进队列
+	return async.NewService(svc, repo, async.WithSyncOnly(otpBizes...))
+}
+
+func InitSMSService(svc *async.Service, tpls *templates.Registry) sms.Service {
+	if len(config.Config.SMS.Providers) == 0 {
+		//内存实现不需要模板
+		return svc
+	}
+	return templates.NewService(svc, tpls)
+}
+
+func initSMSProvider(name string, pc config.SMSProviderConfig, tpls *templates.Registry) sms.Service {
+	id, secret := os.Getenv(pc.AccessKeyIdEnv), os.Getenv(pc.AccessKeySecretEnv)
+	switch pc.Type {
+	case "tencent":
+		cpf := profile.NewClientProfile()
+		if pc.Endpoint != "" {
+			if strings.HasPrefix(pc.Endpoint, "http://") {
+				cpf.HttpProfile.Scheme = "HTTP"
+			}
+			cpf.HttpProfile.Endpoint = strings.TrimPrefix(strings.TrimPrefix(pc.Endpoint, "http://"), "https://")
+		}
+		client, err := tencentsms.NewClient(common.NewCredential(id, secret), pc.Region, cpf)
+		if err != nil {
+			panic(err)
+		}
+		return tencent.NewService(name, pc.AppId, pc.SignName, client, tpls)
+	case "aliyun":
+		var opts []aliyun.Option
+		if pc.Endpoint != "" {
+			opts = append(opts, aliyun.WithEndpoint(strings.TrimSuffix(pc.Endpoint, "/")+"/"))
+		}
+		return aliyun.NewService(name, id, secret, pc.SignName, tpls, opts...)
+	default:
+		panic(fmt.Sprintf("不支持的短信服务商%s", pc.Type))
+	}
+}
+
+func InitSMSGatewayService(svc sms.Service, cmd redis.Cmdable, tpls *templates.Registry) *auth.SMSService {
+	cfg := config.Config.SMS.Gateway
+	key := []byte(os.Getenv(cfg.KeyEnv))
+	if len(key) == 0 {
+		//重启后业务方的token全部失效
+		log.Println("没有配置短信网关密钥,使用临时生成的密钥")
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+	}
+	quota := cfg.DefaultQuota
+	if quota <= 0 {
+		quota = 60
+	}
+	maxTTLDays := cfg.MaxTTLDays
+	if maxTTLDays <= 0 {
+		maxTTLDays = 90
+	}
+	counter := ratelimit.NewRedisSlidingWindowCounter(cmd, time.Minute)
+	//验证码只能由webook自己发
+	return auth.NewSMSService(svc, key, cmd, counter, tpls, otpBizes,
+		time.Duration(maxTTLDays)*time.Hour*24, cfg.Quotas, quota)
 }
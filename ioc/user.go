@@ -0,0 +1,97 @@
+package ioc
+
+import (
+	"crypto/rand"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"os"
+	"sync"
+	"time"
+	"webook/config"
+	"webook/internal/repository"
+	"webook/internal/service"
+	"webook/internal/service/email"
+	"webook/internal/service/email/templates"
+	"webook/pkg/password"
+	"webook/pkg/ratelimit"
+)
+
+var (
+	linkSecretOnce sync.Once
+	linkSecretVal  []byte
+)
+
+// linkSecret 签名邮件链接的密钥 重置密码和验证邮箱共用 靠用途区分
+func linkSecret() []byte {
+	linkSecretOnce.Do(func() {
+		linkSecretVal = []byte(os.Getenv("WEBOOK_LINK_SECRET"))
+		if len(linkSecretVal) == 0 {
+			//以前只有重置密码链接 已经部署的环境还在用旧的环境变量
+			linkSecretVal = []byte(os.Getenv("WEBOOK_RESET_SECRET"))
+		}
+		if len(linkSecretVal) > 0 {
+			return
+		}
+		//开发环境 重启后之前发出去的链接全部失效
+		log.Println("没有找到环境变量 WEBOOK_LINK_SECRET 和 WEBOOK_RESET_SECRET,使用临时生成的密钥")
+		linkSecretVal = make([]byte, 32)
+		if _, err := rand.Read(linkSecretVal); err != nil {
+			panic(err)
+		}
+	})
+	return linkSecretVal
+}
+
+func InitPasswordResetService(repo repository.UserRepository, userSvc service.UserService,
+	codeSvc service.CodeService, emailSvc email.Service, tpls *templates.Templates,
+	cmd redis.Cmdable) *service.PasswordResetDevService {
+	//同一个邮箱一分钟只能发一次 一小时最多5次
+	cooldown := ratelimit.NewRedisSlidingWindowLimiter(cmd, time.Minute, 1)
+	limiter := ratelimit.NewRedisSlidingWindowLimiter(cmd, time.Hour, 5)
+	return service.NewPasswordResetDevService(repo, userSvc, codeSvc, emailSvc, tpls, cooldown, limiter,
+		linkSecret(), config.Config.PasswordReset.LinkURL)
+}
+
+func InitEmailVerifyService(repo repository.UserRepository, codeRepo repository.CodeRepository,
+	emailSvc email.Service, tpls *templates.Templates, cmd redis.Cmdable) *service.EmailVerifyDevService {
+	cfg := config.Config.EmailVerify
+	//同一个邮箱一分钟只能发一次
+	limiter := ratelimit.NewRedisSlidingWindowLimiter(cmd, time.Minute, 1)
+	return service.NewEmailVerifyDevService(repo, codeRepo, emailSvc, tpls, limiter,
+		linkSecret(), cfg.LinkURL, cfg.AllowUnverifiedLogin)
+}
+
+func InitLoginGuardService(repo repository.UserRepository, lockRepo repository.LoginLockRepository,
+	codeSvc service.CodeService, codeRepo repository.CodeRepository, emailSvc email.Service,
+	tpls *templates.Templates, cmd redis.Cmdable) *service.LoginGuardDevService {
+	counter := ratelimit.NewRedisSlidingWindowCounter(cmd, service.LoginFailWindow)
+	return service.NewLoginGuardDevService(repo, lockRepo, counter, codeSvc, codeRepo, emailSvc, tpls)
+}
+
+func InitPasswordHasher() password.Hasher {
+	cfg := config.Config.Password
+	bcryptCost := cfg.BcryptCost
+	if bcryptCost == 0 {
+		bcryptCost = bcrypt.DefaultCost
+	}
+	bcryptHasher := password.NewBcryptHasher(bcryptCost)
+	//每个参数单独判断 没配置的用默认值 Time或者Threads为0时argon2会panic
+	params := password.DefaultArgon2idParams
+	if cfg.Argon2id.Memory > 0 {
+		params.Memory = cfg.Argon2id.Memory
+	}
+	if cfg.Argon2id.Time > 0 {
+		params.Time = cfg.Argon2id.Time
+	}
+	if cfg.Argon2id.Threads > 0 {
+		params.Threads = cfg.Argon2id.Threads
+	}
+	argon2idHasher := password.NewArgon2idHasher(params)
+	switch cfg.Algorithm {
+	case "bcrypt":
+		return password.NewPolicy(bcryptHasher, argon2idHasher)
+	default:
+		return password.NewPolicy(argon2idHasher, bcryptHasher)
+	}
+}
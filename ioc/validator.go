@@ -0,0 +1,42 @@
+package ioc
+
+import (
+	"webook/config"
+	"webook/internal/web/validator"
+)
+
+func InitValidationPolicy() *validator.Policy {
+	cfg := config.Config.Validation
+	rules := validator.DefaultPasswordRules
+	if pc := cfg.Password; pc.MinLength > 0 {
+		rules = validator.PasswordRules{
+			MinLength:        pc.MinLength,
+			MaxLength:        pc.MaxLength,
+			RequireLetter:    pc.RequireLetter,
+			RequireDigit:     pc.RequireDigit,
+			RequireSymbol:    pc.RequireSymbol,
+			RequireMixedCase: pc.RequireMixedCase,
+			DisallowEmail:    pc.DisallowEmail,
+		}
+	}
+	common := validator.DefaultCommonPasswords()
+	if cfg.Password.CommonPasswordsFile != "" {
+		var err error
+		common, err = validator.LoadList(cfg.Password.CommonPasswordsFile)
+		if err != nil {
+			panic(err)
+		}
+	}
+	var disposable []string
+	if !cfg.Email.AllowDisposable {
+		disposable = validator.DefaultDisposableDomains()
+		if cfg.Email.DisposableDomainsFile != "" {
+			var err error
+			disposable, err = validator.LoadList(cfg.Email.DisposableDomainsFile)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+	return validator.NewPolicy(validator.NewPasswordPolicy(rules, common), validator.NewEmailPolicy(disposable))
+}
@@ -4,33 +4,33 @@ import (
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/memstore"
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
 	"webook/internal/web"
 	"webook/internal/web/ijwt"
 	"webook/internal/web/middleware"
 )
 
-func InitGin(middlewares []gin.HandlerFunc, hdl *web.UserHandler, oauth2WechatHandler *web.OAuthWechatHandler) *gin.Engine {
+func InitGin(middlewares []gin.HandlerFunc, hdl *web.UserHandler, oauth2WechatHandler *web.OAuthWechatHandler,
+	jwksHandler *web.JWKSHandler, passwordResetHandler *web.PasswordResetHandler,
+	emailVerifyHandler *web.EmailVerifyHandler, smsHealthHandler *web.SMSHealthHandler,
+	smsGatewayHandler *web.SMSGatewayHandler) *gin.Engine {
 	engine := gin.Default()
 	//初始化中间件
 	engine.Use(middlewares...)
 	hdl.RegisterRouter(engine)
 	oauth2WechatHandler.RegisterRoutes(engine)
+	jwksHandler.RegisterRoutes(engine)
+	passwordResetHandler.RegisterRoutes(engine)
+	emailVerifyHandler.RegisterRoutes(engine)
+	smsHealthHandler.RegisterRoutes(engine)
+	smsGatewayHandler.RegisterRoutes(engine)
 	return engine
 }
 
-func InitMiddlewares(redisClient redis.Cmdable, handler ijwt.Handler) []gin.HandlerFunc {
+func InitMiddlewares(handler ijwt.Handler) []gin.HandlerFunc {
 	return []gin.HandlerFunc{
 		middleware.NewCrossMiddlewareBuilder().Build(),
-		middleware.NewLoginJWTMiddlewareBuilder(redisClient, handler).
-			Ignore("/users/login").
-			Ignore("/users/login_sms/code/send").
-			Ignore("/users/lo gin_sms").
-			Ignore("/oath2/wechat/authurl").
-			Ignore("/oath2/wechat/callback").
-			Ignore("/users/signup").
-			Ignore("/users/refresh_token").
-			Build(),
+		//不需要登录的路由在注册时用middleware.Public()声明
+		middleware.NewLoginJWTMiddlewareBuilder(handler).Build(),
 		sessions.Sessions("mySessions",
 			memstore.NewStore(
 				[]byte("tbkykLFqpai8IwdLt9N20HfAsFZoK1uA"),
@@ -0,0 +1,108 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+// Argon2idParams RFC 9106
+type Argon2idParams struct {
+	// Memory KiB
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// DefaultArgon2idParams OWASP推荐的参数之一 64MiB内存 3轮
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:  64 * 1024,
+	Time:    3,
+	Threads: 2,
+	SaltLen: 16,
+	KeyLen:  32,
+}
+
+// Argon2idHasher 编码格式和参考实现一致
+// $argon2id$v=19$m=65536,t=3,p=2$salt$hash salt和hash为不带padding的base64
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{params: params}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLen)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+	p := h.params
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(encoded string, password string) error {
+	p, salt, key, err := h.decode(encoded)
+	if err != nil {
+		return err
+	}
+	actual := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, actual) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+func (h *Argon2idHasher) Supports(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2idPrefix)
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	p, _, key, err := h.decode(encoded)
+	if err != nil {
+		return true
+	}
+	return p.Memory < h.params.Memory || p.Time < h.params.Time ||
+		p.Threads < h.params.Threads || uint32(len(key)) < h.params.KeyLen
+}
+
+func (h *Argon2idHasher) decode(encoded string) (Argon2idParams, []byte, []byte, error) {
+	var p Argon2idParams
+	//"" argon2id v=19 m=..,t=..,p=.. salt hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, ErrUnsupported
+	}
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return p, nil, nil, ErrUnsupported
+	}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads)
+	if err != nil {
+		return p, nil, nil, ErrUnsupported
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrUnsupported
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrUnsupported
+	}
+	p.SaltLen, p.KeyLen = uint32(len(salt)), uint32(len(key))
+	return p, salt, key, nil
+}
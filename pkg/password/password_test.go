@@ -0,0 +1,66 @@
+package password
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// 测试用小参数 不然太慢
+var testArgon2idParams = Argon2idParams{
+	Memory:  1024,
+	Time:    1,
+	Threads: 1,
+	SaltLen: 16,
+	KeyLen:  32,
+}
+
+func TestArgon2idHasher(t *testing.T) {
+	h := NewArgon2idHasher(testArgon2idParams)
+	encoded, err := h.Hash("hello#world123")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	assert.True(t, h.Supports(encoded))
+
+	assert.NoError(t, h.Verify(encoded, "hello#world123"))
+	assert.Equal(t, ErrMismatch, h.Verify(encoded, "hello#world124"))
+	assert.Equal(t, ErrUnsupported, h.Verify("$argon2id$v=19$bad", "hello#world123"))
+	assert.False(t, h.NeedsRehash(encoded))
+
+	//参数调高之后 老的哈希需要升级
+	stronger := testArgon2idParams
+	stronger.Time = 2
+	assert.True(t, NewArgon2idHasher(stronger).NeedsRehash(encoded))
+	assert.NoError(t, NewArgon2idHasher(stronger).Verify(encoded, "hello#world123"))
+}
+
+func TestBcryptHasher(t *testing.T) {
+	h := NewBcryptHasher(4)
+	encoded, err := h.Hash("hello#world123")
+	require.NoError(t, err)
+	assert.True(t, h.Supports(encoded))
+	assert.NoError(t, h.Verify(encoded, "hello#world123"))
+	assert.Equal(t, ErrMismatch, h.Verify(encoded, "hello#world124"))
+	assert.False(t, h.NeedsRehash(encoded))
+	assert.True(t, NewBcryptHasher(5).NeedsRehash(encoded))
+}
+
+func TestPolicy(t *testing.T) {
+	bcryptHasher := NewBcryptHasher(4)
+	legacy, err := bcryptHasher.Hash("hello#world123")
+	require.NoError(t, err)
+
+	p := NewPolicy(NewArgon2idHasher(testArgon2idParams), bcryptHasher)
+	//老的bcrypt哈希可以验证 但是需要升级
+	assert.NoError(t, p.Verify(legacy, "hello#world123"))
+	assert.True(t, p.NeedsRehash(legacy))
+
+	encoded, err := p.Hash("hello#world123")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, argon2idPrefix))
+	assert.NoError(t, p.Verify(encoded, "hello#world123"))
+	assert.False(t, p.NeedsRehash(encoded))
+
+	assert.Equal(t, ErrUnsupported, p.Verify("plain", "plain"))
+}
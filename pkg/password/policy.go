@@ -0,0 +1,47 @@
+package password
+
+// Policy 用current生成新哈希 老算法生成的哈希也能验证 验证通过后应该升级
+type Policy struct {
+	current Hasher
+	legacy  []Hasher
+}
+
+func NewPolicy(current Hasher, legacy ...Hasher) *Policy {
+	return &Policy{current: current, legacy: legacy}
+}
+
+func (p *Policy) Hash(password string) (string, error) {
+	return p.current.Hash(password)
+}
+
+func (p *Policy) Verify(encoded string, password string) error {
+	h := p.find(encoded)
+	if h == nil {
+		return ErrUnsupported
+	}
+	return h.Verify(encoded, password)
+}
+
+func (p *Policy) Supports(encoded string) bool {
+	return p.find(encoded) != nil
+}
+
+// NeedsRehash 不是当前算法生成的都需要升级
+func (p *Policy) NeedsRehash(encoded string) bool {
+	if !p.current.Supports(encoded) {
+		return true
+	}
+	return p.current.NeedsRehash(encoded)
+}
+
+func (p *Policy) find(encoded string) Hasher {
+	if p.current.Supports(encoded) {
+		return p.current
+	}
+	for _, h := range p.legacy {
+		if h.Supports(encoded) {
+			return h
+		}
+	}
+	return nil
+}
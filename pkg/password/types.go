@@ -0,0 +1,21 @@
+package password
+
+import "errors"
+
+var (
+	ErrMismatch = errors.New("密码不匹配")
+	// ErrUnsupported 不认识的哈希格式
+	ErrUnsupported = errors.New("不支持的哈希格式")
+)
+
+// Hasher 密码哈希 编码后的结果里带上算法和参数 以后调整参数或者换算法 老的哈希依旧能验证
+type Hasher interface {
+	// Hash 返回编码后的哈希
+	Hash(password string) (string, error)
+	// Verify 密码不对返回ErrMismatch
+	Verify(encoded string, password string) error
+	// Supports encoded是不是这种算法生成的
+	Supports(encoded string) bool
+	// NeedsRehash encoded的参数比当前配置弱 应该在验证通过后重新哈希
+	NeedsRehash(encoded string) bool
+}
@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"math/rand"
+	"time"
+)
+
+//go:embed slide_window_counter.lua
+var slideWindowCounter string
+
+type RedisSlidingWindowCounter struct {
+	cmd redis.Cmdable
+
+	//窗口大小
+	interval time.Duration
+}
+
+func NewRedisSlidingWindowCounter(cmd redis.Cmdable, interval time.Duration) *RedisSlidingWindowCounter {
+	return &RedisSlidingWindowCounter{cmd: cmd, interval: interval}
+}
+
+func (r *RedisSlidingWindowCounter) Incr(ctx context.Context, key string) (int, error) {
+	return r.eval(ctx, key, 1)
+}
+
+func (r *RedisSlidingWindowCounter) IncrBy(ctx context.Context, key string, n int) (int, error) {
+	return r.eval(ctx, key, n)
+}
+
+func (r *RedisSlidingWindowCounter) Count(ctx context.Context, key string) (int, error) {
+	return r.eval(ctx, key, 0)
+}
+
+func (r *RedisSlidingWindowCounter) Reset(ctx context.Context, key string) error {
+	return r.cmd.Del(ctx, key).Err()
+}
+
+func (r *RedisSlidingWindowCounter) eval(ctx context.Context, key string, incr int) (int, error) {
+	now := time.Now().UnixMilli()
+	member := fmt.Sprintf("%d-%d", now, rand.Int63())
+	return r.cmd.Eval(ctx, slideWindowCounter, []string{key},
+		r.interval.Milliseconds(), now, member, incr).Int()
+}
@@ -0,0 +1,18 @@
+-- 只计数不限流 返回窗口内的次数
+local key = KEYS[1]
+-- 窗口大小
+local window = tonumber(ARGV[1])
+local now = tonumber(ARGV[2])
+-- 同一毫秒内可能有多次 member不能重复
+local member = ARGV[3]
+-- 记几次 为0时只查询不计数
+local incr = tonumber(ARGV[4])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+if incr > 0 then
+    for i = 1, incr do
+        redis.call('ZADD', key, now, member .. '-' .. i)
+    end
+    redis.call('PEXPIRE', key, window)
+end
+return redis.call('ZCARD', key)
@@ -8,3 +8,14 @@ type Limiter interface {
 	//err 限流器本身有没有错误
 	Limit(ctx context.Context, key string) (bool, error)
 }
+
+// Counter 滑动窗口计数 只记录次数不做判断 由调用方决定怎么处理
+type Counter interface {
+	// Incr 记一次 返回窗口内的次数
+	Incr(ctx context.Context, key string) (int, error)
+	// IncrBy 记n次 返回窗口内的次数
+	IncrBy(ctx context.Context, key string, n int) (int, error)
+	// Count 窗口内的次数
+	Count(ctx context.Context, key string) (int, error)
+	Reset(ctx context.Context, key string) error
+}
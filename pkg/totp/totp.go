@@ -0,0 +1,87 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RFC 6238 默认参数 绝大多数验证器App只支持这一组
+const (
+	period = 30
+	digits = 6
+	//密钥长度 RFC 4226 推荐160位
+	secretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret 生成base32编码的密钥
+func GenerateSecret() (string, error) {
+	buf := make([]byte, secretSize)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(buf), nil
+}
+
+// Counter t所在的时间步
+func Counter(t time.Time) int64 {
+	return t.Unix() / period
+}
+
+// Code 计算某个时间步的验证码
+func Code(secret string, counter int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	//动态截断 RFC 4226 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	val := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", digits, val%1000000), nil
+}
+
+// Validate 校验验证码 允许前后skew个时间步的时钟误差
+// 返回匹配上的时间步 调用方应该记住它 拒绝重复使用同一个时间步
+func Validate(secret string, code string, t time.Time, skew int64) (int64, bool) {
+	if len(code) != digits {
+		return 0, false
+	}
+	now := Counter(t)
+	for c := now - skew; c <= now+skew; c++ {
+		expected, err := Code(secret, c)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// URI 生成给验证器App扫码的otpauth地址
+func URI(issuer string, account string, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(digits))
+	params.Set("period", fmt.Sprint(period))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
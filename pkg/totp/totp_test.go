@@ -0,0 +1,45 @@
+package totp
+
+import (
+	"encoding/base32"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录B的SHA1测试向量 取后6位
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).
+		EncodeToString([]byte("12345678901234567890"))
+	testCases := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+	for _, tc := range testCases {
+		code, err := Code(secret, Counter(time.Unix(tc.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Now()
+	code, err := Code(secret, Counter(now)-1)
+	require.NoError(t, err)
+
+	counter, ok := Validate(secret, code, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, Counter(now)-1, counter)
+
+	_, ok = Validate(secret, code, now, 0)
+	assert.False(t, ok)
+}